@@ -0,0 +1,71 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// Result of the migration of one app.
+type AppResult struct {
+	App    string
+	Result Result
+}
+
+// Migrate schema for each app in the monorepo layout, i.e., each subdirectory of BaseDir is treated as a separate app
+// named after the directory (schema/<app>/vX.Y.Z.sql).
+//
+// MigrateConfig.App is ignored, apps are migrated in the order of their names, and the migration stops at the first
+// app that failed.
+//
+// e.g.,
+//
+//	//go:embed schema
+//	var schemaFs embed.FS
+//
+//	res, err := MigrateApps(conn, PrintLogger{}, MigrateConfig{Fs: schemaFs, BaseDir: "schema"})
+func MigrateApps(db *gorm.DB, log Logger, c MigrateConfig) ([]AppResult, error) {
+	if c.Fs == nil {
+		return nil, errors.New("fs is nil")
+	}
+
+	entries, err := c.Fs.ReadDir(c.BaseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %v folders, %w", c.BaseDir, err)
+	}
+	apps := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			apps = append(apps, e.Name())
+		}
+	}
+	sort.Strings(apps)
+
+	// the dedicated connection is shared by the apps
+	db, closeConn, err := c.connect(db)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+	c.DSN, c.Connect = "", nil
+
+	results := make([]AppResult, 0, len(apps))
+	for _, app := range apps {
+		ac := c
+		ac.App = app
+		ac.BaseDir = path.Join(c.BaseDir, app)
+
+		if log != nil {
+			log.Infof("Migrating schema for app '%v'", app)
+		}
+		res, err := Run(db, log, ac)
+		results = append(results, AppResult{App: app, Result: res})
+		if err != nil {
+			return results, fmt.Errorf("failed to migrate schema for app %v, %w", app, err)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,54 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrateApps(t *testing.T) {
+	mfs := fstest.MapFS{
+		"schema/billing/v0.0.1.sql": {Data: []byte("CREATE TABLE invoice (id INT);")},
+		"schema/auth/v0.0.1.sql":    {Data: []byte("CREATE TABLE t_user (id INT);")},
+		"schema/catalog/v0.0.1.sql": {Data: []byte("CREATE TABLE product (id INT);")},
+		"schema/readme.md":          {Data: []byte("")},
+	}
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	res, err := MigrateApps(f.open(t), PrintLogger{}, MigrateConfig{App: "ignored", Fs: mfs, BaseDir: "schema"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps := []string{"auth", "billing", "catalog"}
+	if len(res) != len(apps) {
+		t.Fatalf("incorrect results, %+v", res)
+	}
+	recorded := f.executed(`^INSERT INTO schema_script_sql`)
+	if len(recorded) != len(apps) {
+		t.Fatalf("incorrect statements, %+v", recorded)
+	}
+	for i, app := range apps {
+		if res[i].App != app || len(res[i].Result.Scripts) != 1 || res[i].Result.Scripts[0].Script != "v0.0.1.sql" {
+			t.Errorf("[%d] should be %v, %+v", i, app, res[i])
+		}
+		if recorded[i].Args[0] != app {
+			t.Errorf("[%d] statement should be recorded for %v, %+v", i, app, recorded[i])
+		}
+	}
+
+	f = &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.on(`^CREATE TABLE invoice`, func(args []driver.Value) (fakeRows, error) { return fakeRows{}, errors.New("access denied") })
+	res, err = MigrateApps(f.open(t), PrintLogger{}, MigrateConfig{Fs: mfs, BaseDir: "schema"})
+	if err == nil || !strings.Contains(err.Error(), "app billing") {
+		t.Fatalf("should fail at billing, %v", err)
+	}
+	if len(res) != 2 || res[1].App != "billing" {
+		t.Fatalf("incorrect results, %+v", res)
+	}
+	if q := f.executed(`^CREATE TABLE product`); len(q) > 0 {
+		t.Fatal("apps after the failed one should not be migrated")
+	}
+}
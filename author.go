@@ -0,0 +1,58 @@
+package svc
+
+import (
+	"database/sql"
+	"strings"
+)
+
+const (
+	headerAuthor = "author"
+	headerTicket = "ticket"
+)
+
+// Parse the '-- author: <name>' and '-- ticket: <id>' header comments of the script.
+//
+// Only the comments before the first statement are parsed, the header comments are not removed from the script, so
+// the statements recorded in schema_script_sql are not changed. Multiple authors (or tickets) declared in separate
+// lines are joined with ', '.
+func parseHeader(content string) (author string, ticket string) {
+	var authors, tickets []string
+	for _, l := range strings.Split(content, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		if !strings.HasPrefix(l, "--") {
+			break
+		}
+		key, val, ok := strings.Cut(strings.TrimSpace(strings.TrimPrefix(l, "--")), ":")
+		val = strings.TrimSpace(val)
+		if !ok || val == "" {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case headerAuthor:
+			authors = append(authors, val)
+		case headerTicket:
+			tickets = append(tickets, val)
+		}
+	}
+	return strings.Join(authors, ", "), strings.Join(tickets, ", ")
+}
+
+// Record the author and the ticket of the script in the latest schema_version record of the script.
+func saveAuthorship(meta *stmtCache, app string, sf schemaFile) error {
+	if sf.Author == "" && sf.Ticket == "" {
+		return nil
+	}
+	// the script may have failed before schema_version is saved, e.g., a failed check
+	var id sql.NullInt64
+	found, err := meta.queryRow(`SELECT MAX(id) FROM schema_version WHERE app = ? AND script = ? AND kind = ?`+meta.forUpdate(),
+		[]any{meta.app(app), sf.Name, sf.kind()}, &id)
+	if err != nil || !found || !id.Valid {
+		return err
+	}
+	_, err = meta.exec(`UPDATE schema_version SET author = ?, ticket = ? WHERE id = ?`,
+		truncateRemark(sf.Author), truncateRemark(sf.Ticket), id.Int64)
+	return err
+}
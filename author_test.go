@@ -0,0 +1,51 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseHeader(t *testing.T) {
+	author, ticket := parseHeader(`
+-- Author: Alice
+-- author: Bob
+-- ticket: PROJ-123
+-- svc:online-alter
+ALTER TABLE t ADD COLUMN remark VARCHAR(255) NOT NULL DEFAULT '';
+
+-- ticket: PROJ-456
+UPDATE t SET remark = 'x';
+`)
+	if author != "Alice, Bob" {
+		t.Fatalf("incorrect author, %v", author)
+	}
+	if ticket != "PROJ-123" {
+		t.Fatalf("incorrect ticket, %v", ticket)
+	}
+
+	if author, ticket := parseHeader("-- author:\nSELECT 1;"); author != "" || ticket != "" {
+		t.Fatalf("should be empty, %v, %v", author, ticket)
+	}
+}
+
+func TestSaveAuthorshipPrimaryReads(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT MAX\(id\) FROM schema_version`, []string{"id"}, []driver.Value{int64(3)})
+	_, err := Run(f.open(t), PrintLogger{}, MigrateConfig{
+		App:          "test",
+		Fs:           fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("-- author: Alice\nCREATE TABLE t (id INT);")}},
+		BaseDir:      "schema",
+		PrimaryReads: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^SELECT MAX\(id\) FROM schema_version WHERE .* FOR UPDATE$`); len(q) != 1 {
+		t.Fatalf("record of the script should be read from the primary, %+v", f.executed(`^SELECT MAX\(id\) FROM schema_version`))
+	}
+	if q := f.executed(`^UPDATE schema_version SET author = \?`); len(q) != 1 || q[0].Args[0] != "Alice" || q[0].Args[2] != int64(3) {
+		t.Fatalf("author should be saved, %+v", q)
+	}
+}
@@ -0,0 +1,55 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// Name of the baseline script in BaseDir.
+	baselineFile = "baseline.sql"
+)
+
+// Read baseline.sql in baseDir, returns false if it's absent.
+//
+// The baseline script must declare the version it bootstraps using '-- svc:baseline <version>', the returned
+// schemaFile is named after the version.
+func readBaseline(fsys ReadFS, baseDir string, s server) (schemaFile, bool, error) {
+	p := path.Join(baseDir, baselineFile)
+	buf, err := fsys.ReadFile(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return schemaFile{}, false, nil
+		}
+		return schemaFile{}, false, fmt.Errorf("failed to read %v, %w", p, err)
+	}
+	sf, err := parseScript(string(buf), s)
+	if err != nil {
+		return schemaFile{}, false, fmt.Errorf("failed to parse %v, %w", p, err)
+	}
+	if sf.Baseline == "" {
+		return schemaFile{}, false, fmt.Errorf("missing '-- %v%v <version>' in %v", s.ns.directivePrefix(), directiveBaseline, p)
+	}
+	sf.Name = sf.Baseline
+	sf.Path = p
+	return sf, true, nil
+}
+
+// Check if the schema doesn't contain any table other than svc's own tables.
+func isEmptySchema(db *gorm.DB) (bool, error) {
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return false, fmt.Errorf("failed to list tables, %w", err)
+	}
+	for _, t := range tables {
+		if !isMetaTable(strings.ToLower(t)) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,38 @@
+package svc
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadBaseline(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schema/baseline.sql": {Data: []byte("-- svc:baseline v1.0.0\nCREATE TABLE t (id INT);\nCREATE TABLE r (id INT);")},
+		"schema/v1.0.1.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN name VARCHAR(10);")},
+	}
+	sf, ok, err := readBaseline(fsys, "schema", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || sf.Name != "v1.0.0" || len(sf.SQLs) != 2 {
+		t.Fatalf("incorrect baseline, %+v", sf)
+	}
+
+	// baseline.sql is not a versioned script
+	files, err := discoverFiles(fsys, "schema", false, func(string) bool { return false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "v1.0.1.sql" {
+		t.Fatalf("incorrect files, %+v", files)
+	}
+
+	if _, ok, err := readBaseline(fsys, "other", server{}); ok || err != nil {
+		t.Fatalf("should be absent, %v, %v", ok, err)
+	}
+
+	fsys["schema/baseline.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE t (id INT);")}
+	if _, _, err := readBaseline(fsys, "schema", server{}); err == nil {
+		t.Fatal("should fail without version")
+	}
+}
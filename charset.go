@@ -0,0 +1,57 @@
+package svc
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Names of the tables created by the statements, e.g., 't' in 'CREATE TABLE t (...)'.
+func createdTables(sqls []string) []string {
+	tables := []string{}
+	for _, sql := range sqls {
+		_, rest := splitLeadingComments(sql)
+		if m := undoCreateTableRegex.FindStringSubmatch(strings.TrimSpace(rest)); m != nil {
+			tables = append(tables, m[1])
+		}
+	}
+	return tables
+}
+
+// Check that the tables created by the script use the required charset and collation.
+func checkTableCharset(db *gorm.DB, c MigrateConfig, database string, sqls []string) error {
+	var violations []string
+	for _, t := range createdTables(sqls) {
+		schema, name := database, t
+		if s, n, ok := strings.Cut(t, "."); ok {
+			schema, name = s, n
+		}
+		schema, name = strings.Trim(schema, "`"), strings.Trim(name, "`")
+
+		var opts struct {
+			TableCollation   string
+			CharacterSetName string
+		}
+		q := db.Raw(`SELECT t.table_collation, c.character_set_name
+			FROM information_schema.tables t
+			JOIN information_schema.collation_character_set_applicability c ON c.collation_name = t.table_collation
+			WHERE t.table_schema = COALESCE(NULLIF(?, ''), DATABASE()) AND t.table_name = ?`, schema, name).Scan(&opts)
+		if q.Error != nil {
+			return fmt.Errorf("failed to query table options of %v, %w", t, q.Error)
+		}
+		if q.RowsAffected < 1 {
+			continue // e.g., TEMPORARY table, or dropped in the same script
+		}
+		if c.RequiredCharset != "" && !strings.EqualFold(opts.CharacterSetName, c.RequiredCharset) {
+			violations = append(violations, fmt.Sprintf("%v uses charset %v, required: %v", t, opts.CharacterSetName, c.RequiredCharset))
+		}
+		if c.RequiredCollation != "" && !strings.EqualFold(opts.TableCollation, c.RequiredCollation) {
+			violations = append(violations, fmt.Sprintf("%v uses collation %v, required: %v", t, opts.TableCollation, c.RequiredCollation))
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("table charset check failed, %v", strings.Join(violations, "; "))
+	}
+	return nil
+}
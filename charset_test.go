@@ -0,0 +1,15 @@
+package svc
+
+import "testing"
+
+func TestCreatedTables(t *testing.T) {
+	tables := createdTables([]string{
+		"CREATE TABLE t (id INT)",
+		"-- archive\nCREATE TABLE IF NOT EXISTS `archive`.`t_archive` (id INT)",
+		"ALTER TABLE t ADD COLUMN name VARCHAR(10)",
+		"CREATE INDEX idx_name ON t (name)",
+	})
+	if len(tables) != 2 || tables[0] != "t" || tables[1] != "`archive`.`t_archive`" {
+		t.Fatalf("incorrect tables, %v", tables)
+	}
+}
@@ -0,0 +1,115 @@
+package svc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+)
+
+var (
+	checkQueryRegex = regexp.MustCompile(`(?is)^(?:SELECT|WITH)\s`)
+)
+
+// Precondition declared in script using '-- svc:check <query>'.
+//
+// The check passes if the query returns no rows, i.e., the query selects the rows violating the precondition, e.g.,
+//
+//	-- svc:check SELECT id FROM t WHERE remark IS NULL LIMIT 1
+//	ALTER TABLE t MODIFY COLUMN remark VARCHAR(255) NOT NULL;
+//
+// The checks are run before any statement of the script is executed, and they are also run by Validate. Only
+// SELECT (or WITH ... SELECT) queries are accepted, the checks must not change anything, they are run in a read-only
+// transaction that is always rolled back, e.g., a data-modifying WITH or SELECT ... INTO OUTFILE is rejected by the
+// server.
+type check struct {
+	Query string
+}
+
+// Failure of the check of the script, nothing of the script is executed.
+type checkError struct {
+	err error
+}
+
+func (e *checkError) Error() string {
+	return e.err.Error()
+}
+
+func (e *checkError) Unwrap() error {
+	return e.err
+}
+
+func isCheckFailure(err error) bool {
+	var ce *checkError
+	return errors.As(err, &ce)
+}
+
+func parseCheck(arg string) (check, error) {
+	if arg == "" {
+		return check{}, fmt.Errorf("missing query in '%v'", directiveCheck)
+	}
+	if !checkQueryRegex.MatchString(arg) {
+		return check{}, fmt.Errorf("malformed check '%v', only SELECT query is allowed", arg)
+	}
+	return check{Query: arg}, nil
+}
+
+func runCheck(db *gorm.DB, ck check) error {
+	// already in a transaction, e.g., the caller's one, it can't be nested
+	if _, ok := db.Statement.ConnPool.(*sql.Tx); !ok {
+		tx := db.Begin(&sql.TxOptions{ReadOnly: true})
+		if tx.Error != nil {
+			return fmt.Errorf("failed to begin read-only transaction for check '%v', %w", ck.Query, tx.Error)
+		}
+		defer tx.Rollback()
+		db = tx
+	}
+	var rows []map[string]any
+	if err := db.Raw(ck.Query).Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to execute check '%v', %w", ck.Query, err)
+	}
+	if len(rows) > 0 {
+		return fmt.Errorf("check failed, '%v' returned %d rows, e.g., %v", ck.Query, len(rows), rows[0])
+	}
+	return nil
+}
+
+// Run the check of the script in the script's database.
+func runScriptCheck(db *gorm.DB, log Logger, c MigrateConfig, d Dialect, sf schemaFile, ck check) error {
+	database := resolvePlaceholders(sf.Database, c.Placeholders)
+	ck.Query = resolvePlaceholders(ck.Query, c.Placeholders)
+	if err := withDatabase(db, d, database, func(conn *gorm.DB) error { return runCheck(conn, ck) }); err != nil {
+		return &checkError{err: err}
+	}
+	log.Infof("'%v' - check passed: '%v'", sf.Name, ck.Query)
+	return nil
+}
+
+// Run the checks ('-- svc:check <query>') of the pending versioned scripts (see Status), nothing is executed.
+//
+// It's mainly used in CI or before a deployment, to find out whether the data satisfies the preconditions of the
+// pending scripts. All checks are run, the failed ones are joined in the returned error. Checks referencing
+// objects created by the earlier pending scripts fail, since these scripts are not executed yet.
+func Validate(db *gorm.DB, log Logger, c MigrateConfig) error {
+	pending, srv, err := pendingScripts(db, c)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	checks := 0
+	for _, sf := range pending {
+		for _, ck := range sf.Checks {
+			checks++
+			if err := runScriptCheck(db, log, c, srv.dialect, sf, ck); err != nil {
+				errs = append(errs, fmt.Errorf("%v, %w", sf.Name, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	log.Infof("Validated %d pending scripts of %v, %d checks passed", len(pending), c.App, checks)
+	return nil
+}
@@ -0,0 +1,104 @@
+package svc
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Clock used by svc to tell the time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Clock backed by time.Now().
+type SystemClock struct {
+}
+
+func (sc SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Clock backed by the database server, e.g., UTC_TIMESTAMP(3) for MySQL, so the timestamps of svc's own tables
+// are consistent across the instances regardless of their clock skew. It falls back to time.Now() if the query fails.
+func DatabaseClock(db *gorm.DB) Clock {
+	return databaseClock{db: db}
+}
+
+type databaseClock struct {
+	db *gorm.DB
+}
+
+func (dc databaseClock) Now() time.Time {
+	query := `SELECT UTC_TIMESTAMP(3)`
+	if dialectOf(dc.db, nil).Name() == DialectOracle {
+		query = `SELECT SYS_EXTRACT_UTC(SYSTIMESTAMP) FROM DUAL`
+	}
+	var t utcTime
+	if err := dc.db.Raw(query).Row().Scan(&t); err != nil {
+		return time.Now()
+	}
+	return t.Time
+}
+
+func clockOrDefault(c Clock) Clock {
+	if c == nil {
+		return SystemClock{}
+	}
+	return c
+}
+
+const (
+	datetimeLayout = "2006-01-02 15:04:05.999999999"
+
+	// layout of the timestamps bound to svc's queries, the columns are DATETIME(3)
+	bindTimeLayout = "2006-01-02 15:04:05.000"
+)
+
+// Value of the timestamp bound to svc's queries, svc's own timestamps are stored in UTC. The MySQL driver converts
+// time.Time to its loc (e.g., loc=Local) before sending, so the UTC time is formatted and sent as is instead.
+func timeArg(d Dialect, t time.Time) any {
+	t = t.UTC()
+	if d != nil && d.Name() == DialectOracle {
+		return t
+	}
+	return t.Format(bindTimeLayout)
+}
+
+// time.Time in UTC, it's scanned from DATETIME columns regardless of the driver's parseTime setting.
+type utcTime struct {
+	time.Time
+}
+
+// Data type of the field for gorm, otherwise the embedded time.Time is parsed as a relation when scanned into
+// structs.
+func (t utcTime) GormDataType() string {
+	return "time"
+}
+
+func (t *utcTime) Scan(v any) error {
+	switch v := v.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		// DATETIME values don't carry time zone, the driver interprets them in its own location (UTC by default)
+		t.Time = time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	default:
+		return fmt.Errorf("unsupported time value %T", v)
+	}
+	return nil
+}
+
+func (t *utcTime) parse(s string) error {
+	p, err := time.ParseInLocation(datetimeLayout, s, time.UTC)
+	if err != nil {
+		return fmt.Errorf("failed to parse time %v, %w", s, err)
+	}
+	t.Time = p
+	return nil
+}
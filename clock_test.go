@@ -0,0 +1,96 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestUTCTimeScan(t *testing.T) {
+	want := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	local := time.Date(2024, 3, 1, 8, 30, 0, 0, time.FixedZone("UTC+8", 8*3600))
+	for _, v := range []any{"2024-03-01 08:30:00", []byte("2024-03-01 08:30:00"), want, local} {
+		var ut utcTime
+		if err := ut.Scan(v); err != nil {
+			t.Fatal(err)
+		}
+		if !ut.Time.Equal(want) {
+			t.Errorf("%v should be %v, %v", v, want, ut.Time)
+		}
+	}
+	var ut utcTime
+	if err := ut.Scan(1); err == nil {
+		t.Fatal("should fail")
+	}
+
+	// scanned into the fields of the structs
+	f := &fakeDB{}
+	f.reply(`^SELECT id, script, kind, success, remark, error_detail, failed_stmt, created_at, record_uid, author, ticket FROM schema_version`,
+		[]string{"id", "script", "kind", "success", "remark", "error_detail", "failed_stmt", "created_at", "record_uid", "author", "ticket"},
+		[]driver.Value{int64(1), "v0.0.1.sql", kindVersioned, true, "Executed", nil, nil, local, nil, nil, nil})
+	hist, err := History(f.open(t), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hist) != 1 || !hist[0].CreatedAt.Equal(want) {
+		t.Fatalf("incorrect history, %+v", hist)
+	}
+}
+
+func TestTimeArg(t *testing.T) {
+	// loc=Local of the DSN, the driver would convert time.Time to the local time before sending
+	prev := time.Local
+	time.Local = time.FixedZone("UTC+8", 8*3600)
+	defer func() { time.Local = prev }()
+
+	at := time.Date(2024, 3, 1, 8, 30, 0, 123000000, time.Local)
+	if v := timeArg(MySQLDialect{}, at); v != "2024-03-01 00:30:00.123" {
+		t.Fatalf("timestamp should be bound as UTC string, %v", v)
+	}
+	if v := timeArg(OracleDialect{}, at); v != at.UTC() {
+		t.Fatalf("timestamp should be bound in UTC, %v", v)
+	}
+}
+
+func TestRunClock(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.on(`^SELECT id FROM schema_version LIMIT 1$`, func(args []driver.Value) (fakeRows, error) {
+		return fakeRows{}, errors.New("Table 'schema_version' doesn't exist")
+	})
+	now := time.Date(2024, 3, 1, 8, 30, 0, 0, time.FixedZone("UTC+8", 8*3600))
+	log := &bufLogger{}
+	_, err := Run(f.open(t), log, MigrateConfig{
+		App:           "test",
+		Fs:            fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);")}},
+		BaseDir:       "schema",
+		Clock:         fixedClock{t: now},
+		Deterministic: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ver := f.executed(`^INSERT INTO schema_version`)
+	utc := now.UTC().Format(bindTimeLayout)
+	if len(ver) != 1 || ver[0].Args[7] != utc {
+		t.Fatalf("created_at of schema_version should be the time of the clock in UTC, %+v", ver)
+	}
+	run := f.executed(`^INSERT INTO schema_run`)
+	if len(run) != 1 || run[0].Args[1] != utc || run[0].Args[2] != utc {
+		t.Fatalf("started_at and ended_at of schema_run should be the time of the clock in UTC, %+v", run)
+	}
+	if log.contains("took") {
+		t.Fatalf("time took should not be logged in deterministic mode, %v", log.lines)
+	}
+
+	log = &bufLogger{}
+	if _, err := Run(f.open(t), log, MigrateConfig{App: "test", Fs: fstest.MapFS{}, BaseDir: "schema"}); err != nil {
+		t.Fatal(err)
+	}
+	if !log.contains("Migrate schema took") {
+		t.Fatalf("time took should be logged, %v", log.lines)
+	}
+}
@@ -0,0 +1,96 @@
+// Command svc migrates the schema using the scripts and the configuration file.
+//
+// e.g.,
+//
+//	svc -config svc.yaml
+//	svc -config svc.yaml -dry-run -output json
+//	svc -config svc.yaml -output pretty
+//
+// The DSN of the database is configured using 'dsn' in the configuration file, or SVC_DSN. Logs are written to
+// stderr, the summary of the migration is written to stdout, and the exit code is 1 if the migration failed.
+//
+// With '-output pretty', the progress of each script and a summary table are written to stdout instead (colored
+// if stdout is a terminal and NO_COLOR is not set), only errors are logged.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/curtisnewbie/svc"
+)
+
+const (
+	outputText   = "text"
+	outputJson   = "json"
+	outputPretty = "pretty"
+)
+
+// Logger that only prints errors, the progress is reported by PrettyReporter.
+type errorLogger struct {
+	svc.PrintLogger
+}
+
+func (errorLogger) Info(args ...any) {}
+
+func (errorLogger) Infof(pat string, args ...any) {}
+
+// Check if the file is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func main() {
+	config := flag.String("config", "svc.yaml", "configuration file, .yaml, .yml or .toml")
+	output := flag.String("output", outputText, "format of the summary, 'text' (key=value), 'json' or 'pretty'")
+	dryRun := flag.Bool("dry-run", false, "resolve and report the pending scripts without executing them")
+	flag.Parse()
+
+	if *output != outputText && *output != outputJson && *output != outputPretty {
+		fmt.Fprintf(os.Stderr, "unknown output format '%v'\n", *output)
+		os.Exit(2)
+	}
+
+	c, err := svc.LoadConfig(*config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *dryRun {
+		c.DryRun = true
+	}
+	if c.DSN == "" && c.Connect == nil {
+		fmt.Fprintln(os.Stderr, "dsn is not configured")
+		os.Exit(2)
+	}
+
+	var log svc.Logger = svc.PrintLogger{}
+	if *output == outputPretty {
+		r := svc.NewPrettyReporter(os.Stdout)
+		_, noColor := os.LookupEnv("NO_COLOR")
+		r.NoColor = noColor || !isTerminal(os.Stdout)
+		r.NoElapsed = c.Deterministic
+		c.Reporter = r
+		log = errorLogger{}
+	}
+
+	res, err := svc.Run(nil, log, c)
+	s := svc.NewSummary(c.App, res, err)
+	switch *output {
+	case outputJson:
+		buf, er := json.Marshal(s)
+		if er != nil {
+			fmt.Fprintln(os.Stderr, er)
+			os.Exit(1)
+		}
+		fmt.Println(string(buf))
+	case outputText:
+		fmt.Println(s.String())
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
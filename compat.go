@@ -0,0 +1,162 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Returned (wrapped) when the pending scripts violate the CompatPolicy.
+	ErrIncompatible = errors.New("incompatible with compatibility policy")
+
+	compatDropTableRegex   = regexp.MustCompile(`(?is)^DROP\s+TABLE\s`)
+	compatRenameTableRegex = regexp.MustCompile(`(?is)^RENAME\s+TABLE\s`)
+	compatAlterTableRegex  = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+` + identPat + `\s+(.*)$`)
+	compatDropRegex        = regexp.MustCompile(`(?is)\bDROP\s+(` + identPat + `)`)
+	compatRenameRegex      = regexp.MustCompile(`(?is)\bRENAME\s+(` + identPat + `)`)
+	compatChangeRegex      = regexp.MustCompile(`(?is)\bCHANGE\s+(?:COLUMN\s+)?(` + identPat + `)\s+(` + identPat + `)`)
+	compatAddColumnRegex   = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(` + identPat + `)\s+(.*)$`)
+
+	// keywords following DROP / RENAME in ALTER TABLE that don't concern columns or tables
+	compatKeywords = map[string]struct{}{
+		"index": {}, "key": {}, "primary": {}, "foreign": {}, "constraint": {}, "check": {}, "partition": {},
+		"default": {},
+	}
+)
+
+// Forward compatibility policy for blue/green deployments.
+//
+// During blue/green deployments, the old version of the application keeps running against the migrated schema,
+// so the changes must follow the expand/contract discipline: columns and tables are first deprecated (the
+// application stops using them), and only dropped or renamed in a later version.
+//
+// A script that drops or renames must declare the version in which the columns / tables were deprecated using
+// '-- svc:deprecated <version>', the version must be before the script's version, and it must have been applied
+// already, i.e., not pending in the same migration.
+//
+// e.g., v0.0.5.sql
+//
+//	-- svc:deprecated v0.0.4
+//	ALTER TABLE t DROP COLUMN legacy_name;
+type CompatPolicy struct {
+	// Forbid DROP TABLE and ALTER TABLE ... DROP [COLUMN].
+	ForbidDrop bool
+
+	// Forbid RENAME TABLE, ALTER TABLE ... RENAME [COLUMN | TO] and ALTER TABLE ... CHANGE with a new column name.
+	ForbidRename bool
+
+	// Forbid adding NOT NULL columns without DEFAULT, the inserts of the old version would fail.
+	//
+	// Unlike drops and renames, it's not allowed even if deprecated.
+	ForbidNotNullWithoutDefault bool
+}
+
+// Policy forbidding all the incompatible changes.
+func StrictCompatPolicy() *CompatPolicy {
+	return &CompatPolicy{ForbidDrop: true, ForbidRename: true, ForbidNotNullWithoutDefault: true}
+}
+
+// Verify the pending scripts against the policy, all violations are reported. applied is the last version applied
+// before the migration, empty if none.
+func (p CompatPolicy) verify(pending []schemaFile, applied string) error {
+	isPending := func(ver string) bool {
+		for _, sf := range pending {
+			if !sf.RunAlways && VerEq(sf.Name, ver) {
+				return true
+			}
+		}
+		return false
+	}
+	var violations []string
+	for _, sf := range pending {
+		deprecated := sf.Deprecated != "" && VerAfter(sf.Name, sf.Deprecated) && applied != "" &&
+			VerAfterEq(applied, sf.Deprecated) && !isPending(sf.Deprecated)
+		for _, sql := range sf.SQLs {
+			for _, v := range p.check(sql) {
+				if v.deprecable && deprecated {
+					continue
+				}
+				violations = append(violations, fmt.Sprintf("%v: %v in '%v'", sf.Name, v.reason, sql))
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("%w, %v", ErrIncompatible, strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+type compatViolation struct {
+	reason string
+
+	// whether the violation is allowed if deprecated
+	deprecable bool
+}
+
+// Check the statement against the policy.
+func (p CompatPolicy) check(sql string) []compatViolation {
+	_, rest := splitLeadingComments(sql)
+	rest = strings.TrimSpace(rest)
+
+	var vs []compatViolation
+	if p.ForbidDrop && compatDropTableRegex.MatchString(rest) {
+		vs = append(vs, compatViolation{reason: "table dropped", deprecable: true})
+	}
+	if p.ForbidRename && compatRenameTableRegex.MatchString(rest) {
+		vs = append(vs, compatViolation{reason: "table renamed", deprecable: true})
+	}
+
+	m := compatAlterTableRegex.FindStringSubmatch(rest)
+	if m == nil {
+		return vs
+	}
+	body := m[1]
+	if p.ForbidDrop {
+		for _, dm := range compatDropRegex.FindAllStringSubmatch(body, -1) {
+			if !isCompatKeyword(dm[1]) {
+				vs = append(vs, compatViolation{reason: "column dropped", deprecable: true})
+			}
+		}
+	}
+	if p.ForbidRename {
+		for _, rm := range compatRenameRegex.FindAllStringSubmatch(body, -1) {
+			if !isCompatKeyword(rm[1]) {
+				vs = append(vs, compatViolation{reason: "column or table renamed", deprecable: true})
+			}
+		}
+		for _, cm := range compatChangeRegex.FindAllStringSubmatch(body, -1) {
+			if !strings.EqualFold(unquoteIdent(cm[1]), unquoteIdent(cm[2])) {
+				vs = append(vs, compatViolation{reason: "column renamed", deprecable: true})
+			}
+		}
+	}
+	if p.ForbidNotNullWithoutDefault {
+		for _, clause := range splitTopLevel(body) {
+			am := compatAddColumnRegex.FindStringSubmatch(strings.TrimSpace(clause))
+			if am == nil || isCompatKeyword(am[1]) || isAddKeyword(am[1]) {
+				continue
+			}
+			def := strings.ToUpper(am[2])
+			if strings.Contains(def, "NOT NULL") && !strings.Contains(def, "DEFAULT") && !strings.Contains(def, "AUTO_INCREMENT") {
+				vs = append(vs, compatViolation{reason: "NOT NULL column added without DEFAULT"})
+			}
+		}
+	}
+	return vs
+}
+
+func isCompatKeyword(word string) bool {
+	_, ok := compatKeywords[strings.ToLower(word)]
+	return ok
+}
+
+func isAddKeyword(word string) bool {
+	_, ok := undoAddKeywords[strings.ToLower(word)]
+	return ok
+}
+
+func unquoteIdent(ident string) string {
+	return strings.ReplaceAll(ident, "`", "")
+}
@@ -0,0 +1,84 @@
+package svc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompatPolicyCheck(t *testing.T) {
+	p := StrictCompatPolicy()
+	cases := map[string]int{
+		"DROP TABLE t":                                                1,
+		"RENAME TABLE t TO r":                                         1,
+		"ALTER TABLE t DROP COLUMN a, DROP b":                         2,
+		"ALTER TABLE t DROP INDEX idx_a, DROP FOREIGN KEY fk_a":       0,
+		"ALTER TABLE t ALTER COLUMN a DROP DEFAULT":                   0,
+		"ALTER TABLE t RENAME COLUMN a TO b":                          1,
+		"ALTER TABLE t RENAME TO r":                                   1,
+		"ALTER TABLE t RENAME INDEX idx_a TO idx_b":                   0,
+		"ALTER TABLE t CHANGE COLUMN a b INT":                         1,
+		"ALTER TABLE t CHANGE `a` a BIGINT":                           0,
+		"ALTER TABLE t ADD COLUMN a INT NOT NULL":                     1,
+		"ALTER TABLE t ADD COLUMN a INT NOT NULL DEFAULT 0":           0,
+		"ALTER TABLE t ADD COLUMN a INT, ADD INDEX idx_a (a)":         0,
+		"ALTER TABLE t ADD COLUMN price DECIMAL(10,2) NOT NULL":       1,
+		"ALTER TABLE t ADD price DECIMAL(10,2) NOT NULL DEFAULT 0":    0,
+		"ALTER TABLE t ADD a INT, ADD b DECIMAL(10,2) NOT NULL":       1,
+		"CREATE TABLE t (id INT NOT NULL, name VARCHAR(10) NOT NULL)": 0,
+	}
+	for sql, n := range cases {
+		if vs := p.check(sql); len(vs) != n {
+			t.Errorf("'%v' should have %v violations, %+v", sql, n, vs)
+		}
+	}
+}
+
+func TestCompatPolicyVerify(t *testing.T) {
+	p := CompatPolicy{ForbidDrop: true}
+	drop := schemaFile{Name: "v0.0.5.sql", SQLs: []string{"ALTER TABLE t DROP COLUMN legacy_name"}}
+	if err := p.verify([]schemaFile{drop}, "v0.0.4.sql"); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("should be incompatible, %v", err)
+	}
+
+	drop.Deprecated = "v0.0.4"
+	if err := p.verify([]schemaFile{drop}, "v0.0.4.sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	// deprecated in the same version is not enough
+	drop.Deprecated = "v0.0.5"
+	if err := p.verify([]schemaFile{drop}, "v0.0.4.sql"); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("should be incompatible, %v", err)
+	}
+
+	// the deprecating version must have been applied
+	drop.Deprecated = "v0.0.4"
+	deprecate := schemaFile{Name: "v0.0.4.sql", SQLs: []string{"UPDATE t SET legacy_name = NULL"}}
+	if err := p.verify([]schemaFile{deprecate, drop}, "v0.0.3.sql"); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("pending deprecation should be incompatible, %v", err)
+	}
+	if err := p.verify([]schemaFile{drop}, "v0.0.3.sql"); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("deprecation not applied should be incompatible, %v", err)
+	}
+	if err := p.verify([]schemaFile{drop}, ""); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("deprecation not applied should be incompatible, %v", err)
+	}
+
+	// the failed deprecating script is retried
+	if err := p.verify([]schemaFile{deprecate, drop}, "v0.0.4.sql"); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("pending deprecation should be incompatible, %v", err)
+	}
+}
+
+func TestParseDeprecated(t *testing.T) {
+	sf, err := parseScript("-- svc:deprecated v0.0.4\nALTER TABLE t DROP COLUMN legacy_name;", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.Deprecated != "v0.0.4" {
+		t.Fatalf("should be v0.0.4, %v", sf.Deprecated)
+	}
+	if _, err := parseScript("-- svc:deprecated\nSELECT 1;", server{}); err == nil {
+		t.Fatal("should fail")
+	}
+}
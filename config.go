@@ -0,0 +1,230 @@
+package svc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix            = "SVC_"
+	envPlaceholderPrefix = envPrefix + "PLACEHOLDER_"
+)
+
+// Configuration file for MigrateConfig.
+//
+// e.g., svc.yaml
+//
+//	app: myapp
+//	root_dir: /opt/myapp
+//	base_dir: schema/svc
+//	exclude:
+//	  - v0.0.1.sql
+//	placeholders:
+//	  schema: myapp
+//	recursive: true
+type FileConfig struct {
+	App               string            `yaml:"app" toml:"app"`
+	RootDir           string            `yaml:"root_dir" toml:"root_dir"`
+	DSN               string            `yaml:"dsn" toml:"dsn"`
+	Namespace         string            `yaml:"namespace" toml:"namespace"`
+	BaseDir           string            `yaml:"base_dir" toml:"base_dir"`
+	StartingVersion   string            `yaml:"starting_version" toml:"starting_version"`
+	Exclude           []string          `yaml:"exclude" toml:"exclude"`
+	Placeholders      map[string]string `yaml:"placeholders" toml:"placeholders"`
+	RequiredCharset   string            `yaml:"required_charset" toml:"required_charset"`
+	RequiredCollation string            `yaml:"required_collation" toml:"required_collation"`
+	OnlineAlter       string            `yaml:"online_alter" toml:"online_alter"`
+
+	// recorded in schema_run, values of the secret placeholders are masked
+	Env                string   `yaml:"env" toml:"env"`
+	SecretPlaceholders []string `yaml:"secret_placeholders" toml:"secret_placeholders"`
+
+	// base64 encoded MigrateConfig.StmtKey, preferably provided using SVC_STMT_KEY
+	StmtKey string `yaml:"stmt_key" toml:"stmt_key"`
+
+	// MigrateConfig.Throttle.Delay, e.g., '200ms'
+	ThrottleDelay string `yaml:"throttle_delay" toml:"throttle_delay"`
+
+	// 'retry' or 'skip', see FailurePolicy
+	OnPreviousFailure FailurePolicy `yaml:"on_previous_failure" toml:"on_previous_failure"`
+
+	Deterministic      bool  `yaml:"deterministic" toml:"deterministic"`
+	GuardPool          bool  `yaml:"guard_pool" toml:"guard_pool"`
+	Adopt              bool  `yaml:"adopt" toml:"adopt"`
+	RewriteIfNotExists bool  `yaml:"rewrite_if_not_exists" toml:"rewrite_if_not_exists"`
+	CaptureWarnings    bool  `yaml:"capture_warnings" toml:"capture_warnings"`
+	StrictSQLMode      bool  `yaml:"strict_sql_mode" toml:"strict_sql_mode"`
+	RequireSource      *bool `yaml:"require_source" toml:"require_source"` // nil if absent, see MigrateConfig.RequireSource
+	Recursive          bool  `yaml:"recursive" toml:"recursive"`
+	PrimaryReads       bool  `yaml:"primary_reads" toml:"primary_reads"`
+	DryRun             bool  `yaml:"dry_run" toml:"dry_run"`
+	ReorderForeignKeys bool  `yaml:"reorder_foreign_keys" toml:"reorder_foreign_keys"`
+	ContinueOnError    bool  `yaml:"continue_on_error" toml:"continue_on_error"`
+	SmartSkip          bool  `yaml:"smart_skip" toml:"smart_skip"`
+	StrictEmptyScripts bool  `yaml:"strict_empty_scripts" toml:"strict_empty_scripts"`
+}
+
+// Load MigrateConfig from YAML (.yaml, .yml) or TOML (.toml) file.
+//
+// Values in the file can be overridden by environment variables named after the keys in the file, with the
+// prefix 'SVC_', e.g., SVC_APP, SVC_BASE_DIR, SVC_STRICT_SQL_MODE. Exclusions are separated by comma in
+// SVC_EXCLUDE (and SVC_SECRET_PLACEHOLDERS), and placeholders are specified as SVC_PLACEHOLDER_<NAME>.
+//
+// Scripts are loaded from RootDir (the current working directory if absent) using DirFS,
+// BaseDir is relative to RootDir.
+func LoadConfig(path string) (MigrateConfig, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return MigrateConfig{}, fmt.Errorf("failed to read config file %v, %w", path, err)
+	}
+
+	var fc FileConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(buf, &fc)
+	case ".toml":
+		err = toml.Unmarshal(buf, &fc)
+	default:
+		return MigrateConfig{}, fmt.Errorf("unsupported config file format %v", path)
+	}
+	if err != nil {
+		return MigrateConfig{}, fmt.Errorf("failed to parse config file %v, %w", path, err)
+	}
+
+	fc.overrideFromEnv(os.Environ())
+	c := fc.MigrateConfig()
+	if fc.StmtKey != "" {
+		key, err := base64.StdEncoding.DecodeString(fc.StmtKey)
+		if err != nil {
+			return MigrateConfig{}, fmt.Errorf("failed to decode stmt_key, %w", err)
+		}
+		c.StmtKey = key
+	}
+	if fc.ThrottleDelay != "" {
+		d, err := time.ParseDuration(fc.ThrottleDelay)
+		if err != nil {
+			return MigrateConfig{}, fmt.Errorf("failed to parse throttle_delay, %w", err)
+		}
+		c.Throttle = &Throttle{Delay: d}
+	}
+	return c, nil
+}
+
+func (fc *FileConfig) overrideFromEnv(environ []string) {
+	strs := map[string]*string{
+		"APP":                &fc.App,
+		"ROOT_DIR":           &fc.RootDir,
+		"BASE_DIR":           &fc.BaseDir,
+		"DSN":                &fc.DSN,
+		"NAMESPACE":          &fc.Namespace,
+		"ENV":                &fc.Env,
+		"STMT_KEY":           &fc.StmtKey,
+		"THROTTLE_DELAY":     &fc.ThrottleDelay,
+		"REQUIRED_CHARSET":   &fc.RequiredCharset,
+		"REQUIRED_COLLATION": &fc.RequiredCollation,
+		"ONLINE_ALTER":       &fc.OnlineAlter,
+		"STARTING_VERSION":   &fc.StartingVersion,
+	}
+	bools := map[string]*bool{
+		"DETERMINISTIC":         &fc.Deterministic,
+		"GUARD_POOL":            &fc.GuardPool,
+		"ADOPT":                 &fc.Adopt,
+		"REWRITE_IF_NOT_EXISTS": &fc.RewriteIfNotExists,
+		"CAPTURE_WARNINGS":      &fc.CaptureWarnings,
+		"STRICT_SQL_MODE":       &fc.StrictSQLMode,
+		"RECURSIVE":             &fc.Recursive,
+		"PRIMARY_READS":         &fc.PrimaryReads,
+		"DRY_RUN":               &fc.DryRun,
+		"REORDER_FOREIGN_KEYS":  &fc.ReorderForeignKeys,
+		"CONTINUE_ON_ERROR":     &fc.ContinueOnError,
+		"SMART_SKIP":            &fc.SmartSkip,
+		"STRICT_EMPTY_SCRIPTS":  &fc.StrictEmptyScripts,
+	}
+
+	for _, kv := range environ {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(k, envPrefix) {
+			continue
+		}
+		if strings.HasPrefix(k, envPlaceholderPrefix) {
+			if fc.Placeholders == nil {
+				fc.Placeholders = map[string]string{}
+			}
+			fc.Placeholders[strings.ToLower(strings.TrimPrefix(k, envPlaceholderPrefix))] = v
+			continue
+		}
+
+		key := strings.TrimPrefix(k, envPrefix)
+		if key == "EXCLUDE" {
+			fc.Exclude = splitList(v)
+		} else if key == "SECRET_PLACEHOLDERS" {
+			fc.SecretPlaceholders = splitList(v)
+		} else if key == "REQUIRE_SOURCE" {
+			require := cast.ToBool(v)
+			fc.RequireSource = &require
+		} else if key == "ON_PREVIOUS_FAILURE" {
+			fc.OnPreviousFailure = FailurePolicy(strings.ToLower(v))
+		} else if p, ok := strs[key]; ok {
+			*p = v
+		} else if p, ok := bools[key]; ok {
+			*p = cast.ToBool(v)
+		}
+	}
+}
+
+// Split comma separated list, empty values are dropped.
+func splitList(v string) []string {
+	var l []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			l = append(l, s)
+		}
+	}
+	return l
+}
+
+// Build MigrateConfig.
+func (fc FileConfig) MigrateConfig() MigrateConfig {
+	root := fc.RootDir
+	if root == "" {
+		root = "."
+	}
+	return MigrateConfig{
+		App:                fc.App,
+		Fs:                 DirFS(root),
+		BaseDir:            fc.BaseDir,
+		DSN:                fc.DSN,
+		Namespace:          Namespace(fc.Namespace),
+		RequiredCharset:    fc.RequiredCharset,
+		RequiredCollation:  fc.RequiredCollation,
+		OnlineAlter:        fc.OnlineAlter,
+		StartingVersion:    fc.StartingVersion,
+		Exclude:            fc.Exclude,
+		Placeholders:       fc.Placeholders,
+		SecretPlaceholders: fc.SecretPlaceholders,
+		Env:                fc.Env,
+		OnPreviousFailure:  fc.OnPreviousFailure,
+		Deterministic:      fc.Deterministic,
+		GuardPool:          fc.GuardPool,
+		Adopt:              fc.Adopt,
+		RewriteIfNotExists: fc.RewriteIfNotExists,
+		CaptureWarnings:    fc.CaptureWarnings,
+		StrictSQLMode:      fc.StrictSQLMode,
+		RequireSource:      fc.RequireSource,
+		Recursive:          fc.Recursive,
+		PrimaryReads:       fc.PrimaryReads,
+		DryRun:             fc.DryRun,
+		ReorderForeignKeys: fc.ReorderForeignKeys,
+		ContinueOnError:    fc.ContinueOnError,
+		SmartSkip:          fc.SmartSkip,
+		StrictEmptyScripts: fc.StrictEmptyScripts,
+	}
+}
@@ -0,0 +1,83 @@
+package svc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	yml := filepath.Join(dir, "svc.yaml")
+	err := os.WriteFile(yml, []byte(`
+app: myapp
+root_dir: `+dir+`
+base_dir: schema
+exclude:
+  - v0.0.1.sql
+placeholders:
+  schema: myapp
+recursive: true
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SVC_APP", "override")
+	t.Setenv("SVC_STRICT_SQL_MODE", "true")
+	t.Setenv("SVC_PLACEHOLDER_ENV", "prod")
+	t.Setenv("SVC_ON_PREVIOUS_FAILURE", "retry")
+
+	c, err := LoadConfig(yml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.App != "override" {
+		t.Fatalf("app should be overridden, %v", c.App)
+	}
+	if c.BaseDir != "schema" || !c.Recursive || !c.StrictSQLMode {
+		t.Fatalf("incorrect config, %+v", c)
+	}
+	if c.OnPreviousFailure != RetryFailed {
+		t.Fatalf("incorrect failure policy, %v", c.OnPreviousFailure)
+	}
+	if len(c.Exclude) != 1 || c.Exclude[0] != "v0.0.1.sql" {
+		t.Fatalf("incorrect exclude, %v", c.Exclude)
+	}
+	if c.Placeholders["schema"] != "myapp" || c.Placeholders["env"] != "prod" {
+		t.Fatalf("incorrect placeholders, %v", c.Placeholders)
+	}
+
+	tml := filepath.Join(dir, "svc.toml")
+	if err := os.WriteFile(tml, []byte("app = \"myapp\"\nbase_dir = \"schema\"\nadopt = true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err = LoadConfig(tml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.BaseDir != "schema" || !c.Adopt {
+		t.Fatalf("incorrect config, %+v", c)
+	}
+	if c.RequireSource != nil {
+		t.Fatalf("require_source should be absent, %v", *c.RequireSource)
+	}
+
+	if err := os.WriteFile(tml, []byte("app = \"myapp\"\nrequire_source = false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err = LoadConfig(tml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RequireSource == nil || *c.RequireSource {
+		t.Fatalf("require_source should be false, %v", c.RequireSource)
+	}
+	t.Setenv("SVC_REQUIRE_SOURCE", "true")
+	if c, err = LoadConfig(tml); err != nil {
+		t.Fatal(err)
+	}
+	if c.RequireSource == nil || !*c.RequireSource {
+		t.Fatalf("require_source should be overridden, %v", c.RequireSource)
+	}
+}
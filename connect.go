@@ -0,0 +1,42 @@
+package svc
+
+import (
+	"fmt"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// Factory of the dedicated connection for the migration.
+type ConnectFunc func() (*gorm.DB, error)
+
+// Factory opening MySQL connection using the DSN.
+func MySQLConnect(dsn string) ConnectFunc {
+	return func() (*gorm.DB, error) {
+		return gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	}
+}
+
+// Open the dedicated connection for the migration if MigrateConfig.Connect or MigrateConfig.DSN is provided,
+// otherwise db is returned as is.
+//
+// The returned func closes the dedicated connection, it's a no-op if db is returned.
+func (c MigrateConfig) connect(db *gorm.DB) (*gorm.DB, func(), error) {
+	connect := c.Connect
+	if connect == nil && c.DSN != "" {
+		connect = MySQLConnect(c.DSN)
+	}
+	if connect == nil {
+		return db, func() {}, nil
+	}
+
+	conn, err := connect()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open migration connection, %w", err)
+	}
+	sqlDb, err := conn.DB()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to obtain connection pool, %w", err)
+	}
+	return conn, func() { _ = sqlDb.Close() }, nil
+}
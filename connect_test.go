@@ -0,0 +1,23 @@
+package svc
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestConnect(t *testing.T) {
+	db := &gorm.DB{}
+	conn, closeConn, err := MigrateConfig{}.connect(db)
+	if err != nil || conn != db {
+		t.Fatalf("should use the db passed in, %v", err)
+	}
+	closeConn()
+
+	cause := errors.New("access denied")
+	c := MigrateConfig{DSN: "ddl:pw@tcp(localhost:3306)/tt", Connect: func() (*gorm.DB, error) { return nil, cause }}
+	if _, _, err := c.connect(db); !errors.Is(err, cause) {
+		t.Fatalf("Connect should take precedence over DSN, %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// prefix of schema_version.remark of the failed scripts that the migration continued after
+	continuedRemarkPrefix = "Continued after failure: "
+)
+
+// Script that failed without stopping the migration, see MigrateConfig.ContinueOnError and '-- svc:optional'.
+//
+// The failure is recorded in schema_version (the script is reported as failed in Status), but the script is not
+// retried automatically in the later migrations.
+type ScriptFailure struct {
+	Script string
+	Err    error
+}
+
+// Check if the migration continues after the failure of the script.
+//
+// The failed checks and throttles of the versioned scripts always stop the migration, the script (or the rest of
+// it) is not executed, it would never be retried if the migration continued with the later scripts.
+func (c MigrateConfig) continuesOnFailure(sf schemaFile, err error) bool {
+	if sf.kind() == kindVersioned && (isCheckFailure(err) || isThrottleFailure(err)) {
+		return false
+	}
+	return c.ContinueOnError || sf.Optional
+}
+
+func isContinuedFailure(remark string) bool {
+	return strings.HasPrefix(remark, continuedRemarkPrefix)
+}
+
+// Record the failure of the script in Result and schema_version, the migration continues with the next script.
+//
+// The run-always scripts have their own history entry for each execution, and they are executed in every migration,
+// their failures are recorded as is.
+func continueAfterFailure(meta *stmtCache, log Logger, c MigrateConfig, sf schemaFile, err error, res *Result) error {
+	if sf.kind() == kindVersioned {
+		var stmt int
+		var se *ScriptError
+		if errors.As(err, &se) {
+			stmt = se.Index
+		}
+		if er := saveSchemaVerDetail(meta, c.App, sf.Name, kindVersioned, false, continuedRemarkPrefix+err.Error(), err.Error(), stmt); er != nil {
+			return fmt.Errorf("failed to save schema_version, %w", er)
+		}
+	}
+	res.Failures = append(res.Failures, ScriptFailure{Script: sf.Name, Err: err})
+	log.Errorf("Failed to exec sql file %v, continue with the next script, %v", sf.Name, err)
+	return nil
+}
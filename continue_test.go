@@ -0,0 +1,65 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestContinuesOnFailure(t *testing.T) {
+	sf, err := parseScript("-- svc:optional\nUPDATE t SET a = 1;", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sf.Optional || !(MigrateConfig{}).continuesOnFailure(sf, nil) {
+		t.Fatal("optional script should not stop the migration")
+	}
+	if (MigrateConfig{}).continuesOnFailure(schemaFile{}, nil) || !(MigrateConfig{ContinueOnError: true}).continuesOnFailure(schemaFile{}, nil) {
+		t.Fatal("incorrect ContinueOnError")
+	}
+	ckErr := &checkError{err: errors.New("check failed")}
+	if (MigrateConfig{ContinueOnError: true}).continuesOnFailure(sf, ckErr) {
+		t.Fatal("failed check of versioned script should stop the migration")
+	}
+	if !(MigrateConfig{ContinueOnError: true}).continuesOnFailure(schemaFile{RunAlways: true}, ckErr) {
+		t.Fatal("failed check of run-always script should not stop the migration")
+	}
+	if !isContinuedFailure(continuedRemarkPrefix+"table t exists") || isContinuedFailure("table t exists") {
+		t.Fatal("incorrect continued failure")
+	}
+}
+
+func TestContinueCheckFailure(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT id FROM t WHERE remark IS NULL`, []string{"id"}, []driver.Value{int64(1)})
+	c := MigrateConfig{
+		App: "test",
+		Fs: fstest.MapFS{
+			"schema/v0.0.1.sql": {Data: []byte("-- svc:check SELECT id FROM t WHERE remark IS NULL LIMIT 1\n" +
+				"ALTER TABLE t MODIFY COLUMN remark VARCHAR(255) NOT NULL;")},
+			"schema/v0.0.2.sql": {Data: []byte("CREATE TABLE t2 (id INT);")},
+		},
+		BaseDir:         "schema",
+		ContinueOnError: true,
+	}
+	if _, err := Run(f.open(t), PrintLogger{}, c); err == nil || !isCheckFailure(err) {
+		t.Fatalf("failed check should stop the migration, %v", err)
+	}
+	if q := f.executed(`^(ALTER TABLE t |CREATE TABLE t2 )`); len(q) > 0 {
+		t.Fatalf("statements should not be executed, %+v", q)
+	}
+	if q := f.executed(`^INSERT INTO schema_version`); len(q) > 0 {
+		t.Fatalf("failed check should not be recorded, %+v", q)
+	}
+
+	// the check is run in a read-only transaction that is rolled back
+	var seq []string
+	for _, q := range f.executed(`^(BEGIN|ROLLBACK|COMMIT|SELECT id FROM t )`) {
+		seq = append(seq, q.SQL)
+	}
+	if len(seq) != 3 || seq[0] != "BEGIN READ ONLY" || seq[2] != "ROLLBACK" {
+		t.Fatalf("check should be run in a read-only transaction, %v", seq)
+	}
+}
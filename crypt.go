@@ -0,0 +1,100 @@
+package svc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// prefix of the encrypted statements recorded in schema_script_sql
+	encryptedStmtPrefix = "svc:enc:"
+)
+
+// Cipher of the statements recorded in schema_script_sql, see MigrateConfig.StmtKey.
+//
+// Statements are encrypted using AES-GCM, the app and the script name are used as the additional data, so the
+// encrypted statement can't be moved to another script. The zero value doesn't encrypt anything.
+type stmtCipher struct {
+	aead cipher.AEAD
+}
+
+func newStmtCipher(key []byte) (stmtCipher, error) {
+	if len(key) < 1 {
+		return stmtCipher{}, nil
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return stmtCipher{}, fmt.Errorf("invalid StmtKey, %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return stmtCipher{}, fmt.Errorf("invalid StmtKey, %w", err)
+	}
+	return stmtCipher{aead: aead}, nil
+}
+
+func stmtAD(app string, script string) []byte {
+	return []byte(app + "\x00" + script)
+}
+
+// Encrypt the statement of the script, it's returned as is if the key is absent.
+func (s stmtCipher) encrypt(app string, script string, stmt string) (string, error) {
+	if s.aead == nil {
+		return stmt, nil
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce, %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(stmt), stmtAD(app, script))
+	return encryptedStmtPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt the statement recorded in schema_script_sql.
+//
+// Statements recorded without encryption (e.g., before the key is provided) are returned as is.
+func (s stmtCipher) decrypt(app string, script string, stored string) (string, error) {
+	enc, ok := strings.CutPrefix(stored, encryptedStmtPrefix)
+	if !ok {
+		return stored, nil
+	}
+	if s.aead == nil {
+		return "", fmt.Errorf("statement of %v is encrypted, StmtKey is required", script)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode statement of %v, %w", script, err)
+	}
+	ns := s.aead.NonceSize()
+	if len(sealed) < ns {
+		return "", fmt.Errorf("failed to decrypt statement of %v, %w", script, errors.New("ciphertext too short"))
+	}
+	plain, err := s.aead.Open(nil, sealed[:ns], sealed[ns:], stmtAD(app, script))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt statement of %v, %w", script, err)
+	}
+	return string(plain), nil
+}
+
+// Decrypt the statements recorded in schema_script_sql in place.
+func (s stmtCipher) decryptAll(app string, script string, stored []string) error {
+	for i, st := range stored {
+		plain, err := s.decrypt(app, script, st)
+		if err != nil {
+			return err
+		}
+		stored[i] = plain
+	}
+	return nil
+}
+
+// Cipher of the statements recorded in schema_script_sql.
+func (c MigrateConfig) stmtCipher() (stmtCipher, error) {
+	return newStmtCipher(c.StmtKey)
+}
@@ -0,0 +1,55 @@
+package svc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStmtCipher(t *testing.T) {
+	cph, err := newStmtCipher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := "INSERT INTO user (name, password) VALUES ('admin', 'secret')"
+	enc, err := cph.encrypt("myapp", "v0.0.1.sql", stmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enc, encryptedStmtPrefix) || strings.Contains(enc, "secret") {
+		t.Fatalf("statement is not encrypted, %v", enc)
+	}
+	dec, err := cph.decrypt("myapp", "v0.0.1.sql", enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec != stmt {
+		t.Fatalf("incorrect decrypted statement, %v", dec)
+	}
+
+	// bound to the app and script
+	if _, err := cph.decrypt("myapp", "v0.0.2.sql", enc); err == nil {
+		t.Fatal("should fail to decrypt statement of another script")
+	}
+
+	// recorded before the key is provided
+	plain := []string{"CREATE TABLE user (id INT)", enc}
+	if err := cph.decryptAll("myapp", "v0.0.1.sql", plain); err != nil {
+		t.Fatal(err)
+	}
+	if plain[0] != "CREATE TABLE user (id INT)" || plain[1] != stmt {
+		t.Fatalf("incorrect decrypted statements, %v", plain)
+	}
+
+	// key is absent
+	var none stmtCipher
+	if s, _ := none.encrypt("myapp", "v0.0.1.sql", stmt); s != stmt {
+		t.Fatalf("statement should not be encrypted, %v", s)
+	}
+	if _, err := none.decrypt("myapp", "v0.0.1.sql", enc); err == nil {
+		t.Fatal("should fail to decrypt without the key")
+	}
+
+	if _, err := newStmtCipher([]byte("short")); err == nil {
+		t.Fatal("should reject invalid key")
+	}
+}
@@ -0,0 +1,254 @@
+package svc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+const (
+	DialectMySQL  = "mysql"
+	DialectOracle = "oracle"
+)
+
+var (
+	// Returned when the migration lock is not acquired within MigrateConfig.LockTimeout.
+	ErrLockNotAcquired = errors.New("migration lock not acquired")
+)
+
+// Dialect of the database, it encapsulates the SQLs that differ between databases, e.g., svc's own tables,
+// statement splitting and locking.
+//
+// MySQLDialect (also used for MariaDB) and OracleDialect are provided.
+type Dialect interface {
+	Name() string
+
+	// Detect the flavor of the database server.
+	DetectFlavor(db *gorm.DB) (Flavor, error)
+
+	// Create svc's own tables if necessary, columns added in later versions of svc are added as well.
+	//
+	// The tables are named after the namespace, see Namespace.Table.
+	InitMetaTables(db *gorm.DB, ns Namespace) error
+
+	// Clause to limit the query to one row, e.g., 'LIMIT 1'.
+	LimitOne() string
+
+	// Rebind '?' placeholders in the query for prepared statements, e.g., ':1' for Oracle.
+	Rebind(query string) string
+
+	// Split script lines into statements.
+	SplitStatements(lines []string) []string
+
+	// Acquire the named lock for the session, returns false if the lock is not acquired within the timeout.
+	Lock(db *gorm.DB, name string, timeout time.Duration) (bool, error)
+
+	// Release the named lock held by the session.
+	Unlock(db *gorm.DB, name string) error
+
+	// Check if the error indicates that the DDL has already been applied, e.g., table exists.
+	IsAlreadyApplied(err error) bool
+
+	// Name of the current database (schema for Oracle) of the session.
+	CurrentDatabase(db *gorm.DB) (string, error)
+
+	// Switch the current database (schema for Oracle) of the session.
+	UseDatabase(db *gorm.DB, name string) error
+}
+
+// Pick dialect based on the gorm dialector if d is nil.
+func dialectOf(db *gorm.DB, d Dialect) Dialect {
+	if d != nil {
+		return d
+	}
+	if db != nil && db.Dialector != nil && db.Dialector.Name() == DialectOracle {
+		return OracleDialect{}
+	}
+	return MySQLDialect{}
+}
+
+// Value of the app column bound to svc's queries, Oracle treats the empty string as NULL, the empty app is stored
+// as oracleEmptyApp instead.
+func appArg(d Dialect, app string) string {
+	if app == "" && d != nil && d.Name() == DialectOracle {
+		return oracleEmptyApp
+	}
+	return app
+}
+
+// Value of the app column of c.App.
+func (c MigrateConfig) appArg(db *gorm.DB) string {
+	return appArg(dialectOf(db, c.Dialect), c.App)
+}
+
+// Lock timeout in whole seconds, rounded up, so that a sub-second timeout still waits instead of failing immediately.
+func lockSeconds(timeout time.Duration) int {
+	secs := int(math.Ceil(timeout.Seconds()))
+	if secs < 1 {
+		return 1
+	}
+	return secs
+}
+
+// Database server that the scripts are parsed for and executed against.
+type server struct {
+	dialect Dialect
+	flavor  Flavor
+
+	// namespace of the directives in the scripts
+	ns Namespace
+}
+
+func (s server) splitStatements(lines []string) []string {
+	if s.dialect == nil {
+		return splitStatements(lines)
+	}
+	return s.dialect.SplitStatements(lines)
+}
+
+func detectServer(db *gorm.DB, d Dialect) (server, error) {
+	d = dialectOf(db, d)
+	fl, err := d.DetectFlavor(db)
+	if err != nil {
+		return server{}, err
+	}
+	return server{dialect: d, flavor: fl}, nil
+}
+
+// Dialect for MySQL and MariaDB.
+type MySQLDialect struct {
+}
+
+func (MySQLDialect) Name() string {
+	return DialectMySQL
+}
+
+func (MySQLDialect) DetectFlavor(db *gorm.DB) (Flavor, error) {
+	var ver string
+	if err := db.Raw(`SELECT VERSION()`).Scan(&ver).Error; err != nil {
+		return Flavor{}, fmt.Errorf("failed to query server version, %w", err)
+	}
+	return parseFlavor(ver), nil
+}
+
+func (MySQLDialect) InitMetaTables(db *gorm.DB, ns Namespace) error {
+	return initMetaTables(db, ns)
+}
+
+func (MySQLDialect) LimitOne() string {
+	return "LIMIT 1"
+}
+
+func (MySQLDialect) Rebind(query string) string {
+	return query
+}
+
+func (MySQLDialect) SplitStatements(lines []string) []string {
+	return splitStatements(lines)
+}
+
+func (MySQLDialect) Lock(db *gorm.DB, name string, timeout time.Duration) (bool, error) {
+	var acquired *int
+	if err := db.Raw(`SELECT GET_LOCK(?, ?)`, name, lockSeconds(timeout)).Scan(&acquired).Error; err != nil {
+		return false, fmt.Errorf("failed to acquire lock %v, %w", name, err)
+	}
+	return acquired != nil && *acquired == 1, nil
+}
+
+func (MySQLDialect) Unlock(db *gorm.DB, name string) error {
+	return db.Exec(`SELECT RELEASE_LOCK(?)`, name).Error
+}
+
+func (MySQLDialect) IsAlreadyApplied(err error) bool {
+	return isAlreadyApplied(err)
+}
+
+// Check if the error indicates that the DDL has already been applied.
+func isAlreadyApplied(err error) bool {
+	var me *mysql.MySQLError
+	if !errors.As(err, &me) {
+		return false
+	}
+	switch me.Number {
+	case 1050, // ER_TABLE_EXISTS_ERROR
+		1060, // ER_DUP_FIELDNAME
+		1061: // ER_DUP_KEYNAME
+		return true
+	}
+	return false
+}
+
+// Check if the error indicates that the ALGORITHM or LOCK clause is not supported for the ALTER.
+func isOnlineAlterRejected(err error) bool {
+	var me *mysql.MySQLError
+	if !errors.As(err, &me) {
+		return false
+	}
+	switch me.Number {
+	case 1845, // ER_ALTER_OPERATION_NOT_SUPPORTED
+		1846: // ER_ALTER_OPERATION_NOT_SUPPORTED_REASON
+		return true
+	}
+	return false
+}
+
+func (MySQLDialect) CurrentDatabase(db *gorm.DB) (string, error) {
+	var name *string
+	if err := db.Raw(`SELECT DATABASE()`).Scan(&name).Error; err != nil {
+		return "", fmt.Errorf("failed to query current database, %w", err)
+	}
+	if name == nil {
+		return "", nil
+	}
+	return *name, nil
+}
+
+func (MySQLDialect) UseDatabase(db *gorm.DB, name string) error {
+	if err := db.Exec("USE " + quoteIdent(name)).Error; err != nil {
+		return fmt.Errorf("failed to use database %v, %w", name, err)
+	}
+	return nil
+}
+
+// Quote MySQL identifier with backticks.
+func quoteIdent(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// Run fn in the database, the session is switched back to the original database when fn returns.
+//
+// USE is session-scoped, if db is not bound to a single connection, fn runs on a dedicated connection, so that
+// the other queries (e.g., the ones on svc's own tables) are not affected. If name is empty, fn runs on db as is.
+func withDatabase(db *gorm.DB, d Dialect, name string, fn func(conn *gorm.DB) error) error {
+	if name == "" {
+		return fn(db)
+	}
+	d = dialectOf(db, d)
+	use := func(conn *gorm.DB) error {
+		orig, err := d.CurrentDatabase(conn)
+		if err != nil {
+			return err
+		}
+		if err := d.UseDatabase(conn, name); err != nil {
+			return err
+		}
+		err = fn(conn)
+		if orig != "" {
+			if er := d.UseDatabase(conn, orig); er != nil && err == nil {
+				err = er
+			}
+		}
+		return err
+	}
+	switch db.Statement.ConnPool.(type) {
+	case *sql.Conn, *sql.Tx:
+		return use(db)
+	}
+	return db.Connection(use)
+}
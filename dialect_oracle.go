@@ -0,0 +1,326 @@
+package svc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	FlavorOracle = "oracle"
+
+	// app column of the empty app, Oracle stores the empty string as NULL, which never matches 'app = ?'
+	oracleEmptyApp = " "
+)
+
+var (
+	oracleIdentRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_$#]*$`)
+	plsqlBlockRegex  = regexp.MustCompile(`(?i)^(DECLARE|BEGIN|CREATE\s+(OR\s+REPLACE\s+)?(EDITIONABLE\s+|NONEDITIONABLE\s+)?` +
+		`(PROCEDURE|FUNCTION|PACKAGE|TRIGGER|TYPE))\b`)
+
+	oracleMetaTables = []struct {
+		Name    string
+		Columns [][2]string
+		Indexes []string
+	}{
+		{
+			Name: "schema_version",
+			Columns: [][2]string{
+				{"id", "NUMBER(19) GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"},
+				{"app", "VARCHAR2(50) DEFAULT ' ' NOT NULL"},
+				{"created_at", "TIMESTAMP NOT NULL"},
+				{"script", "VARCHAR2(256)"},
+				{"kind", "VARCHAR2(20) DEFAULT 'versioned'"},
+				{"success", "NUMBER(1) DEFAULT 1 NOT NULL"},
+				{"remark", "VARCHAR2(256)"},
+				{"error_detail", "CLOB"},
+				{"failed_stmt", "NUMBER(10)"},
+				{"record_uid", "VARCHAR2(32)"},
+				{"author", "VARCHAR2(256)"},
+				{"ticket", "VARCHAR2(256)"},
+			},
+			Indexes: []string{"CREATE INDEX schema_version_app_idx ON schema_version (app)"},
+		},
+		{
+			Name: "schema_script_sql",
+			Columns: [][2]string{
+				{"id", "NUMBER(19) GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"},
+				{"app", "VARCHAR2(50) DEFAULT ' ' NOT NULL"},
+				{"script", "VARCHAR2(256)"},
+				{"stmt", "CLOB"},
+				{"undo_stmt", "CLOB"},
+				{"reversible", "NUMBER(1) DEFAULT 0 NOT NULL"},
+				{"rows_affected", "NUMBER(19)"},
+				{"warnings", "CLOB"},
+				{"skipped", "NUMBER(1) DEFAULT 0 NOT NULL"},
+				{"created_at", "TIMESTAMP NOT NULL"},
+			},
+			Indexes: []string{"CREATE INDEX schema_script_sql_app_idx ON schema_script_sql (app, script)"},
+		},
+		{
+			Name: "schema_object",
+			Columns: [][2]string{
+				{"id", "NUMBER(19) GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"},
+				{"app", "VARCHAR2(50) DEFAULT ' ' NOT NULL"},
+				{"type", "VARCHAR2(20)"},
+				{"name", "VARCHAR2(128)"},
+				{"checksum", "VARCHAR2(64)"},
+				{"created_at", "TIMESTAMP NOT NULL"},
+				{"updated_at", "TIMESTAMP NOT NULL"},
+			},
+			Indexes: []string{"CREATE UNIQUE INDEX schema_object_app_uk ON schema_object (app, type, name)"},
+		},
+		{
+			Name: "schema_cursor",
+			Columns: [][2]string{
+				{"id", "NUMBER(19) GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"},
+				{"app", "VARCHAR2(50) DEFAULT ' ' NOT NULL"},
+				{"script", "VARCHAR2(256)"},
+				{"stmt_index", "NUMBER(10) DEFAULT 0 NOT NULL"},
+				{"last_key", "NUMBER(19) DEFAULT 0 NOT NULL"},
+				{"updated_at", "TIMESTAMP NOT NULL"},
+			},
+			Indexes: []string{"CREATE UNIQUE INDEX schema_cursor_app_uk ON schema_cursor (app, script, stmt_index)"},
+		},
+		{
+			Name: "schema_run",
+			Columns: [][2]string{
+				{"id", "NUMBER(19) GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY"},
+				{"app", "VARCHAR2(50) DEFAULT ' ' NOT NULL"},
+				{"started_at", "TIMESTAMP NOT NULL"},
+				{"ended_at", "TIMESTAMP NOT NULL"},
+				{"host", "VARCHAR2(255)"},
+				{"scripts", "CLOB"},
+				{"script_count", "NUMBER(10) DEFAULT 0 NOT NULL"},
+				{"rows_affected", "NUMBER(19) DEFAULT 0 NOT NULL"},
+				{"success", "NUMBER(1) DEFAULT 1 NOT NULL"},
+				{"error_msg", "CLOB"},
+				{"env", "VARCHAR2(50)"},
+				{"placeholders", "CLOB"},
+				{"record_uid", "VARCHAR2(32)"},
+			},
+			Indexes: []string{"CREATE INDEX schema_run_app_idx ON schema_run (app, started_at)"},
+		},
+	}
+)
+
+// Dialect for Oracle (12c or later).
+//
+// svc's own tables use NUMBER, VARCHAR2, CLOB and identity columns. VARCHAR2 columns are nullable,
+// since Oracle treats empty strings as NULL, except the app columns, the empty app is stored as a single space. PL/SQL blocks (DECLARE, BEGIN, CREATE PROCEDURE, etc.) are
+// terminated by a line containing only '/', other statements are terminated by ';'.
+//
+// The migration lock is based on DBMS_LOCK, the lock is held by the session (release_on_commit is FALSE),
+// EXECUTE privilege on DBMS_LOCK is required.
+type OracleDialect struct {
+}
+
+func (OracleDialect) Name() string {
+	return DialectOracle
+}
+
+func (OracleDialect) DetectFlavor(db *gorm.DB) (Flavor, error) {
+	var ver string
+	if err := db.Raw(`SELECT version FROM product_component_version WHERE product LIKE 'Oracle%' AND ROWNUM = 1`).
+		Scan(&ver).Error; err != nil {
+		return Flavor{}, fmt.Errorf("failed to query server version, %w", err)
+	}
+	return Flavor{Name: FlavorOracle, Version: ver}, nil
+}
+
+func (d OracleDialect) InitMetaTables(db *gorm.DB, ns Namespace) error {
+	for _, t := range oracleMetaTables {
+		name := ns.Table(t.Name)
+		var cnt int
+		if err := db.Raw(`SELECT COUNT(*) FROM user_tables WHERE table_name = UPPER(?)`, name).Scan(&cnt).Error; err != nil {
+			return fmt.Errorf("failed to check table %v, %w", name, err)
+		}
+		if cnt < 1 {
+			cols := make([]string, 0, len(t.Columns))
+			for _, c := range t.Columns {
+				cols = append(cols, c[0]+" "+c[1])
+			}
+			if err := db.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", name, strings.Join(cols, ", "))).Error; err != nil {
+				return fmt.Errorf("failed to create %v table, %w", name, err)
+			}
+			for _, idx := range t.Indexes {
+				if err := db.Exec(ns.rewrite(idx)).Error; err != nil {
+					return fmt.Errorf("failed to create index for %v table, %w", name, err)
+				}
+			}
+			continue
+		}
+
+		// columns added in later versions of svc
+		for _, c := range t.Columns {
+			if err := db.Raw(`SELECT COUNT(*) FROM user_tab_columns WHERE table_name = UPPER(?) AND column_name = UPPER(?)`,
+				name, c[0]).Scan(&cnt).Error; err != nil {
+				return fmt.Errorf("failed to check column %v.%v, %w", name, c[0], err)
+			}
+			if cnt > 0 {
+				continue
+			}
+			if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD (%s %s)", name, c[0], c[1])).Error; err != nil {
+				return fmt.Errorf("failed to add column %v.%v, %w", name, c[0], err)
+			}
+		}
+
+		// app columns created by earlier versions of svc are nullable, the rows of the empty app are stored as NULL
+		if err := db.Raw(`SELECT COUNT(*) FROM user_tab_columns WHERE table_name = UPPER(?) AND column_name = 'APP' AND nullable = 'Y'`,
+			name).Scan(&cnt).Error; err != nil {
+			return fmt.Errorf("failed to check column %v.app, %w", name, err)
+		}
+		if cnt > 0 {
+			if err := db.Exec(fmt.Sprintf("UPDATE %s SET app = %s WHERE app IS NULL", name, oracleQuote(oracleEmptyApp))).Error; err != nil {
+				return fmt.Errorf("failed to update column %v.app, %w", name, err)
+			}
+			if err := db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY (app DEFAULT %s NOT NULL)", name, oracleQuote(oracleEmptyApp))).Error; err != nil {
+				return fmt.Errorf("failed to modify column %v.app, %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (OracleDialect) LimitOne() string {
+	return "FETCH FIRST 1 ROWS ONLY"
+}
+
+func (OracleDialect) Rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(fmt.Sprintf(":%d", n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Split statements, PL/SQL blocks are terminated by '/', other statements are terminated by ';'.
+func (OracleDialect) SplitStatements(lines []string) []string {
+	sqls := []string{}
+	buf := []string{}
+	plsql := false
+	flush := func() {
+		if s := strings.TrimSpace(strings.Join(buf, "\n")); s != "" {
+			sqls = append(sqls, s)
+		}
+		buf = buf[:0]
+	}
+
+	for _, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if plsql {
+			if trimmed == "/" {
+				flush()
+				plsql = false
+				continue
+			}
+			buf = append(buf, l)
+			continue
+		}
+
+		if trimmed == "/" {
+			flush()
+			continue
+		}
+		if strings.TrimSpace(strings.Join(buf, "\n")) == "" && plsqlBlockRegex.MatchString(trimmed) {
+			buf = buf[:0]
+			buf = append(buf, l)
+			plsql = true
+			continue
+		}
+
+		// plain statements terminated by ';', the ';' is not sent to Oracle
+		for {
+			i := strings.Index(l, ";")
+			if i < 0 {
+				buf = append(buf, l)
+				break
+			}
+			buf = append(buf, l[:i])
+			flush()
+			l = l[i+1:]
+		}
+	}
+	flush()
+	return sqls
+}
+
+func (OracleDialect) Lock(db *gorm.DB, name string, timeout time.Duration) (bool, error) {
+	// DBMS_LOCK.REQUEST returns 0 (success) or 4 (already own the lock), 1 is timeout
+	err := db.Exec(fmt.Sprintf(`
+	DECLARE
+		h VARCHAR2(128);
+		r INTEGER;
+	BEGIN
+		DBMS_LOCK.ALLOCATE_UNIQUE(%s, h);
+		r := DBMS_LOCK.REQUEST(h, DBMS_LOCK.X_MODE, %d, FALSE);
+		IF r = 1 THEN
+			RAISE_APPLICATION_ERROR(-20001, 'svc lock timeout');
+		ELSIF r NOT IN (0, 4) THEN
+			RAISE_APPLICATION_ERROR(-20002, 'svc lock request failed: ' || r);
+		END IF;
+	END;`, oracleQuote(name), lockSeconds(timeout))).Error
+	if err != nil {
+		if strings.Contains(err.Error(), "ORA-20001") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to acquire lock %v, %w", name, err)
+	}
+	return true, nil
+}
+
+func (OracleDialect) Unlock(db *gorm.DB, name string) error {
+	return db.Exec(fmt.Sprintf(`
+	DECLARE
+		h VARCHAR2(128);
+		r INTEGER;
+	BEGIN
+		DBMS_LOCK.ALLOCATE_UNIQUE(%s, h);
+		r := DBMS_LOCK.RELEASE(h);
+	END;`, oracleQuote(name))).Error
+}
+
+func (OracleDialect) IsAlreadyApplied(err error) bool {
+	msg := err.Error()
+	for _, code := range []string{
+		"ORA-00955", // name is already used by an existing object
+		"ORA-01430", // column being added already exists in table
+		"ORA-01408", // such column list already indexed
+	} {
+		if strings.Contains(msg, code) {
+			return true
+		}
+	}
+	return false
+}
+
+func oracleQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func (OracleDialect) CurrentDatabase(db *gorm.DB) (string, error) {
+	var name string
+	if err := db.Raw(`SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM dual`).Scan(&name).Error; err != nil {
+		return "", fmt.Errorf("failed to query current schema, %w", err)
+	}
+	return name, nil
+}
+
+func (OracleDialect) UseDatabase(db *gorm.DB, name string) error {
+	if !oracleIdentRegex.MatchString(name) {
+		return fmt.Errorf("invalid schema name '%v'", name)
+	}
+	if err := db.Exec(`ALTER SESSION SET CURRENT_SCHEMA = ` + name).Error; err != nil {
+		return fmt.Errorf("failed to use schema %v, %w", name, err)
+	}
+	return nil
+}
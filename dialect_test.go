@@ -0,0 +1,140 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOracleSplitStatements(t *testing.T) {
+	sf, err := parseScript(`
+CREATE TABLE t (id NUMBER(19) PRIMARY KEY);
+INSERT INTO t (id) VALUES (1); INSERT INTO t (id) VALUES (2);
+
+CREATE OR REPLACE PROCEDURE p_cleanup AS
+BEGIN
+	DELETE FROM t WHERE id < 0;
+END;
+/
+
+BEGIN
+	EXECUTE IMMEDIATE 'DROP TABLE r';
+END;
+/
+-- migrate:down
+DROP TABLE t;`, server{dialect: OracleDialect{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sf.SQLs) != 5 {
+		t.Fatalf("should be 5, %q", sf.SQLs)
+	}
+	if sf.SQLs[0] != "CREATE TABLE t (id NUMBER(19) PRIMARY KEY)" {
+		t.Fatalf("incorrect statement, %q", sf.SQLs[0])
+	}
+	if sf.SQLs[2] != "INSERT INTO t (id) VALUES (2)" {
+		t.Fatalf("incorrect statement, %q", sf.SQLs[2])
+	}
+	if sf.SQLs[3] != "CREATE OR REPLACE PROCEDURE p_cleanup AS\nBEGIN\n\tDELETE FROM t WHERE id < 0;\nEND;" {
+		t.Fatalf("incorrect statement, %q", sf.SQLs[3])
+	}
+	if !strings.HasPrefix(sf.SQLs[4], "BEGIN") || !strings.HasSuffix(sf.SQLs[4], "END;") {
+		t.Fatalf("incorrect statement, %q", sf.SQLs[4])
+	}
+	if len(sf.Down) != 1 || sf.Down[0] != "DROP TABLE t" {
+		t.Fatalf("incorrect down statements, %q", sf.Down)
+	}
+}
+
+func TestOracleRebind(t *testing.T) {
+	q := OracleDialect{}.Rebind(`INSERT INTO schema_version (app, script, kind) VALUES (?,?,?)`)
+	if q != `INSERT INTO schema_version (app, script, kind) VALUES (:1,:2,:3)` {
+		t.Fatalf("incorrect query, %v", q)
+	}
+	if q := (MySQLDialect{}).Rebind(`SELECT ?`); q != `SELECT ?` {
+		t.Fatalf("incorrect query, %v", q)
+	}
+}
+
+func TestOracleIsAlreadyApplied(t *testing.T) {
+	d := OracleDialect{}
+	if !d.IsAlreadyApplied(errors.New("ORA-00955: name is already used by an existing object")) {
+		t.Fatal("should be already applied")
+	}
+	if d.IsAlreadyApplied(errors.New("ORA-00942: table or view does not exist")) {
+		t.Fatal("should not be already applied")
+	}
+	if oracleQuote("svc:it's") != "'svc:it''s'" {
+		t.Fatalf("incorrect quote, %v", oracleQuote("svc:it's"))
+	}
+}
+
+func TestDialectOf(t *testing.T) {
+	if dialectOf(nil, nil).Name() != DialectMySQL {
+		t.Fatal("should be mysql")
+	}
+	if dialectOf(nil, OracleDialect{}).Name() != DialectOracle {
+		t.Fatal("should be oracle")
+	}
+}
+
+func TestAppArg(t *testing.T) {
+	if appArg(OracleDialect{}, "") != oracleEmptyApp {
+		t.Fatal("empty app should be mapped for oracle")
+	}
+	if appArg(OracleDialect{}, "myapp") != "myapp" {
+		t.Fatal("app should not be mapped")
+	}
+	if appArg(MySQLDialect{}, "") != "" {
+		t.Fatal("empty app should not be mapped for mysql")
+	}
+}
+
+func TestOracleInitMetaTablesApp(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`nullable = 'Y'`, []string{"cnt"}, []driver.Value{int64(1)})
+	f.reply(`^SELECT COUNT\(\*\) FROM user_(tables|tab_columns)`, []string{"cnt"}, []driver.Value{int64(1)})
+	db := f.open(t)
+	if err := (OracleDialect{}).InitMetaTables(db, namespace); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^UPDATE schema_version SET app = ' ' WHERE app IS NULL$`); len(q) != 1 {
+		t.Fatalf("rows of the empty app should be updated, %+v", f.executed(`^UPDATE`))
+	}
+	if q := f.executed(`^ALTER TABLE schema_run MODIFY \(app DEFAULT ' ' NOT NULL\)$`); len(q) != 1 {
+		t.Fatalf("app column should be modified, %+v", f.executed(`^ALTER`))
+	}
+	for _, tb := range oracleMetaTables {
+		if tb.Columns[1][0] != "app" || !strings.HasSuffix(tb.Columns[1][1], "NOT NULL") {
+			t.Fatalf("app column of %v should be NOT NULL, %v", tb.Name, tb.Columns[1])
+		}
+	}
+}
+
+func TestLockSeconds(t *testing.T) {
+	for timeout, want := range map[time.Duration]int{200 * time.Millisecond: 1, time.Second: 1, 1500 * time.Millisecond: 2,
+		10 * time.Second: 10} {
+		if got := lockSeconds(timeout); got != want {
+			t.Fatalf("incorrect seconds of %v, %v", timeout, got)
+		}
+	}
+
+	f := &fakeDB{}
+	f.reply(`^SELECT GET_LOCK`, []string{"acquired"}, []driver.Value{int64(1)})
+	db := f.open(t)
+	ok, err := MySQLDialect{}.Lock(db, "svc:app", 200*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("lock should be acquired, %v", err)
+	}
+	if q := f.executed(`^SELECT GET_LOCK`); len(q) != 1 || q[0].Args[1] != int64(1) {
+		t.Fatalf("sub-second timeout should wait for 1 second, %+v", q)
+	}
+	if _, err := (OracleDialect{}).Lock(db, "svc:app", 200*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`DBMS_LOCK.X_MODE, 1, FALSE`); len(q) != 1 {
+		t.Fatalf("sub-second timeout should wait for 1 second, %+v", f.executed(`DBMS_LOCK`))
+	}
+}
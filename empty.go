@@ -0,0 +1,43 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// remark of the empty scripts recorded in schema_version
+	emptyScriptRemark = "Executed, empty script (no statement found)"
+)
+
+var (
+	// Returned (wrapped) when StrictEmptyScripts is enabled and the scripts contain no statement.
+	ErrEmptyScript = errors.New("script contains no statement")
+)
+
+// Check if the statements are comments only, e.g., the content of the script is commented out.
+func isCommentOnly(sqls []string) bool {
+	for _, sql := range sqls {
+		if _, rest := splitLeadingComments(sql); strings.TrimSpace(rest) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify that none of the scripts is empty, see MigrateConfig.StrictEmptyScripts.
+func verifyNotEmpty(files ...[]schemaFile) error {
+	empty := []string{}
+	for _, fs := range files {
+		for _, sf := range fs {
+			if sf.Empty {
+				empty = append(empty, sf.Name)
+			}
+		}
+	}
+	if len(empty) > 0 {
+		return fmt.Errorf("%w, %v", ErrEmptyScript, strings.Join(empty, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package svc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEmptyScript(t *testing.T) {
+	sf, err := parseScript("\n  \n-- ALTER TABLE t ADD COLUMN remark VARCHAR(255);\n-- author: alice\n", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf.Name = "v0.0.2.sql"
+	if !sf.Empty || len(sf.SQLs) != 0 {
+		t.Fatalf("script should be empty, %+v", sf)
+	}
+	err = verifyNotEmpty([]schemaFile{{Name: "v0.0.1.sql", SQLs: []string{"SELECT 1"}}}, []schemaFile{sf})
+	if !errors.Is(err, ErrEmptyScript) {
+		t.Fatalf("should be ErrEmptyScript, %v", err)
+	}
+
+	sf, err = parseScript("SELECT 1;", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.Empty {
+		t.Fatal("script should not be empty")
+	}
+
+	// statements skipped by the checkpoint don't make the script empty
+	sf.SQLs = nil
+	if err := verifyNotEmpty([]schemaFile{sf}); err != nil {
+		t.Fatal(err)
+	}
+}
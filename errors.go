@@ -0,0 +1,63 @@
+package svc
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	excerptLen = 120
+)
+
+// Error of a failed statement in a script.
+type ScriptError struct {
+	// Name of the script, e.g., v0.0.1.sql.
+	Script string
+
+	// Path of the script in ReadFS.
+	Path string
+
+	// 1-based index of the failed statement in the script.
+	Index int
+
+	// Approximate 1-based line number of the failed statement in the script, 0 if unknown.
+	Line int
+
+	// Trimmed excerpt of the failed statement.
+	Excerpt string
+
+	Err error
+}
+
+func (e *ScriptError) Error() string {
+	loc := fmt.Sprintf("statement [%d]", e.Index)
+	if e.Line > 0 {
+		loc += fmt.Sprintf(" at %v:%d", e.Path, e.Line)
+	}
+	return fmt.Sprintf("failed to execute script %v, %v, '%v', %v", e.Script, loc, e.Excerpt, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+func newScriptError(sf schemaFile, i int, stmt string, err error) *ScriptError {
+	p := sf.pos(i)
+	return &ScriptError{
+		Script:  sf.Name,
+		Path:    sf.Path,
+		Index:   p.Index,
+		Line:    p.Line,
+		Excerpt: excerpt(stmt),
+		Err:     err,
+	}
+}
+
+// Collapse whitespaces and trim the statement to excerptLen runes.
+func excerpt(stmt string) string {
+	s := strings.Join(strings.Fields(stmt), " ")
+	if r := []rune(s); len(r) > excerptLen {
+		return string(r[:excerptLen]) + "..."
+	}
+	return s
+}
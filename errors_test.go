@@ -0,0 +1,51 @@
+package svc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLocateStatements(t *testing.T) {
+	sf, err := parseScript(`-- svc:assert SELECT 1 = 1
+CREATE TABLE t (
+	id INT
+);
+
+INSERT INTO t (id) VALUES (1); INSERT INTO t (id) VALUES (2);
+UPDATE t SET id = 3
+	WHERE id = 2;`, server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []stmtPos{{1, 2}, {2, 6}, {3, 6}, {4, 7}}
+	if len(sf.Pos) != len(want) {
+		t.Fatalf("should be %v, %v", want, sf.Pos)
+	}
+	for i, p := range want {
+		if sf.Pos[i] != p {
+			t.Errorf("[%d] should be %v, %v", i, p, sf.Pos[i])
+		}
+	}
+}
+
+func TestScriptError(t *testing.T) {
+	cause := errors.New("Error 1146: Table 'tt.r' doesn't exist")
+	sf := schemaFile{Name: "v0.0.2.sql", Path: "schema/svc/v0.0.2.sql", SQLs: []string{"SELECT 1", "UPDATE r\n\tSET a = 1"},
+		Pos: []stmtPos{{1, 1}, {2, 3}}}
+	var err error = newScriptError(sf, 1, sf.SQLs[1], cause)
+
+	var se *ScriptError
+	if !errors.As(err, &se) || !errors.Is(err, cause) {
+		t.Fatal("should be ScriptError")
+	}
+	if se.Index != 2 || se.Line != 3 || se.Excerpt != "UPDATE r SET a = 1" {
+		t.Fatalf("incorrect ScriptError, %+v", se)
+	}
+	if !strings.Contains(err.Error(), "schema/svc/v0.0.2.sql:3") {
+		t.Fatalf("incorrect message, %v", err)
+	}
+	if e := excerpt(strings.Repeat("a", 200)); len(e) != excerptLen+3 {
+		t.Fatalf("incorrect excerpt, %v", e)
+	}
+}
@@ -0,0 +1,49 @@
+package svc
+
+import (
+	"gorm.io/gorm"
+)
+
+// Statement to be executed.
+type Statement struct {
+	// Name of the script, e.g., v0.0.1.sql, or path of the repeatable object, e.g., views/v_order.sql.
+	Script string
+
+	// 1-based index of the statement in the script.
+	Index int
+
+	// The statement, placeholders are resolved and rewrites are applied.
+	SQL string
+}
+
+// Executor of the statements in scripts and repeatable objects.
+//
+// It can be wrapped to customize the execution, e.g., adding query hints, routing to a specific node, or sending DDL
+// to a change-management queue. The queries on svc's own tables are not executed through the Executor.
+type Executor interface {
+	// Execute the statement, returns the number of rows affected.
+	Exec(db *gorm.DB, stmt Statement) (int64, error)
+}
+
+// Function as Executor.
+type ExecutorFunc func(db *gorm.DB, stmt Statement) (int64, error)
+
+func (f ExecutorFunc) Exec(db *gorm.DB, stmt Statement) (int64, error) {
+	return f(db, stmt)
+}
+
+// Executor executing the statements on db as is.
+type DefaultExecutor struct {
+}
+
+func (DefaultExecutor) Exec(db *gorm.DB, stmt Statement) (int64, error) {
+	t := db.Exec(stmt.SQL)
+	return t.RowsAffected, t.Error
+}
+
+func executorOrDefault(e Executor) Executor {
+	if e == nil {
+		return DefaultExecutor{}
+	}
+	return e
+}
@@ -0,0 +1,22 @@
+package svc
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestExecutorFunc(t *testing.T) {
+	var got Statement
+	var e Executor = ExecutorFunc(func(db *gorm.DB, stmt Statement) (int64, error) {
+		got = stmt
+		return 1, nil
+	})
+	n, err := e.Exec(nil, Statement{Script: "v0.0.1.sql", Index: 2, SQL: "SELECT 1"})
+	if err != nil || n != 1 || got.Index != 2 {
+		t.Fatalf("incorrect execution, %v, %v, %+v", n, err, got)
+	}
+	if _, ok := executorOrDefault(nil).(DefaultExecutor); !ok {
+		t.Fatal("should be DefaultExecutor")
+	}
+}
@@ -0,0 +1,294 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var (
+	fakeSpaceRegex = regexp.MustCompile(`\s+`)
+)
+
+// Scripted database/sql driver for the tests, queries are answered by the first matching handler, the unmatched
+// ones return no rows.
+type fakeDB struct {
+	mu       sync.Mutex
+	handlers []fakeHandler
+	log      []fakeQuery
+	lastId   int64
+}
+
+type fakeHandler struct {
+	re *regexp.Regexp
+	fn func(args []driver.Value) (fakeRows, error)
+}
+
+type fakeQuery struct {
+	SQL  string
+	Args []driver.Value
+}
+
+type fakeRows struct {
+	cols     []string
+	rows     [][]driver.Value
+	affected int64
+}
+
+// Answer the queries matching the pattern (on the whitespace-collapsed query) with fn.
+func (f *fakeDB) on(pattern string, fn func(args []driver.Value) (fakeRows, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers = append(f.handlers, fakeHandler{re: regexp.MustCompile(pattern), fn: fn})
+}
+
+// Answer the queries matching the pattern with the rows.
+func (f *fakeDB) reply(pattern string, cols []string, rows ...[]driver.Value) {
+	f.on(pattern, func(args []driver.Value) (fakeRows, error) { return fakeRows{cols: cols, rows: rows}, nil })
+}
+
+// Queries (and BEGIN, COMMIT, ROLLBACK) executed so far that match the pattern.
+func (f *fakeDB) executed(pattern string) []fakeQuery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	re := regexp.MustCompile(pattern)
+	var matched []fakeQuery
+	for _, q := range f.log {
+		if re.MatchString(q.SQL) {
+			matched = append(matched, q)
+		}
+	}
+	return matched
+}
+
+func (f *fakeDB) handle(query string, args []driver.Value) (fakeRows, error) {
+	query = strings.TrimSpace(fakeSpaceRegex.ReplaceAllString(query, " "))
+	f.mu.Lock()
+	f.log = append(f.log, fakeQuery{SQL: query, Args: args})
+	var fn func(args []driver.Value) (fakeRows, error)
+	for _, h := range f.handlers {
+		if h.re.MatchString(query) {
+			fn = h.fn
+			break
+		}
+	}
+	f.lastId++
+	f.mu.Unlock()
+	if fn == nil {
+		return fakeRows{}, nil
+	}
+	return fn(args)
+}
+
+func (f *fakeDB) sqlDB() *sql.DB {
+	return sql.OpenDB(fakeConnector{db: f})
+}
+
+// Open gorm.DB (MySQL dialector) backed by the fake driver.
+func (f *fakeDB) open(t *testing.T) *gorm.DB {
+	sqlDb := f.sqlDB()
+	t.Cleanup(func() { sqlDb.Close() })
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDb, SkipInitializeWithVersion: true}),
+		&gorm.Config{SkipDefaultTransaction: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+type fakeConnector struct {
+	db *fakeDB
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{db: c.db} }
+
+type fakeDriver struct {
+	db *fakeDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{db: d.db}, nil }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.db.handle("BEGIN", nil)
+	return fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		c.db.handle("BEGIN READ ONLY", nil)
+		return fakeTx{conn: c}, nil
+	}
+	return c.Begin()
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r, err := c.db.handle(query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	return &fakeResultRows{r: r}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r, err := c.db.handle(query, values(args))
+	if err != nil {
+		return nil, err
+	}
+	c.db.mu.Lock()
+	id := c.db.lastId
+	c.db.mu.Unlock()
+	return fakeResult{id: id, affected: r.affected}, nil
+}
+
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, 0, len(args))
+	for _, a := range args {
+		vals = append(vals, a.Value)
+	}
+	return vals
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (t fakeTx) Commit() error {
+	_, err := t.conn.db.handle("COMMIT", nil)
+	return err
+}
+
+func (t fakeTx) Rollback() error {
+	_, err := t.conn.db.handle("ROLLBACK", nil)
+	return err
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, named(args))
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, named(args))
+}
+
+func named(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, 0, len(args))
+	for i, a := range args {
+		nv = append(nv, driver.NamedValue{Ordinal: i + 1, Value: a})
+	}
+	return nv
+}
+
+type fakeResult struct {
+	id       int64
+	affected int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.id, nil }
+func (r fakeResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+type fakeResultRows struct {
+	r fakeRows
+	i int
+}
+
+func (r *fakeResultRows) Columns() []string { return r.r.cols }
+func (r *fakeResultRows) Close() error      { return nil }
+
+func (r *fakeResultRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.r.rows) {
+		return io.EOF
+	}
+	row := r.r.rows[r.i]
+	if len(row) != len(dest) {
+		return errors.New("fake row doesn't match the columns")
+	}
+	copy(dest, row)
+	r.i++
+	return nil
+}
+
+func TestFakeDB(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	db := f.open(t)
+	fl, err := MySQLDialect{}.DetectFlavor(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fl.Version != "8.0.36" {
+		t.Fatalf("incorrect flavor, %+v", fl)
+	}
+	if err := db.Exec("UPDATE t SET a = ?", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^UPDATE t`); len(q) != 1 || q[0].Args[0] != int64(1) {
+		t.Fatalf("incorrect queries, %+v", q)
+	}
+}
+
+// Logger collecting the log lines.
+type bufLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *bufLogger) Info(args ...any)               { l.add(fmt.Sprint(args...)) }
+func (l *bufLogger) Infof(pat string, args ...any)  { l.add(fmt.Sprintf(pat, args...)) }
+func (l *bufLogger) Error(args ...any)              { l.add(fmt.Sprint(args...)) }
+func (l *bufLogger) Errorf(pat string, args ...any) { l.add(fmt.Sprintf(pat, args...)) }
+
+func (l *bufLogger) add(line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, line)
+}
+
+// Check if any of the lines contains s.
+func (l *bufLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clock that always returns the same time.
+type fixedClock struct {
+	t time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.t
+}
@@ -0,0 +1,205 @@
+package svc
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// placeholder of the table name in the statements of canary scripts
+	tablePlaceholder = "svc.table"
+
+	// max number of tables in a table family
+	maxFamilySize = 10000
+)
+
+var (
+	tableFamilyRegex = regexp.MustCompile(`^([^{}]*)\{(\d+)\.\.(\d+)\}([^{}]*)$`)
+	foreachNameRegex = regexp.MustCompile(`^\w+$`)
+)
+
+// Expand table family, e.g., 't_{0001..0064}' => t_0001, t_0002, ..., t_0064.
+//
+// The numbers are zero-padded to the width of the widest bound if either of the bounds has leading zeros.
+func expandTableFamily(family string) ([]string, error) {
+	m := tableFamilyRegex.FindStringSubmatch(strings.TrimSpace(family))
+	if m == nil {
+		return nil, fmt.Errorf("malformed table family '%v', expected e.g., 't_{0001..0064}'", family)
+	}
+	prefix, lo, hi, suffix := m[1], m[2], m[3], m[4]
+	from, err := strconv.Atoi(lo)
+	if err != nil {
+		return nil, fmt.Errorf("malformed table family '%v', %w", family, err)
+	}
+	to, err := strconv.Atoi(hi)
+	if err != nil {
+		return nil, fmt.Errorf("malformed table family '%v', %w", family, err)
+	}
+	if from > to {
+		return nil, fmt.Errorf("malformed table family '%v', %v is greater than %v", family, lo, hi)
+	}
+	if to-from+1 > maxFamilySize {
+		return nil, fmt.Errorf("table family '%v' exceeds %v tables", family, maxFamilySize)
+	}
+	width := 0
+	if (len(lo) > 1 && lo[0] == '0') || (len(hi) > 1 && hi[0] == '0') {
+		width = len(lo)
+		if len(hi) > width {
+			width = len(hi)
+		}
+	}
+	tables := make([]string, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		tables = append(tables, fmt.Sprintf("%s%0*d%s", prefix, width, n, suffix))
+	}
+	return tables, nil
+}
+
+// Canary declared in script using '-- svc:canary <table family> [canary table]'.
+//
+// The statements containing '${svc.table}' are expanded for each table in the family, the expansion for the canary
+// table (the first one in the family by default) is executed first, the rest are only executed if it succeeds, e.g.,
+//
+//	-- svc:canary t_{0001..0064}
+//	ALTER TABLE ${svc.table} ADD COLUMN remark VARCHAR(255) NOT NULL DEFAULT '';
+//
+// Each expansion is recorded in schema_script_sql as a separate statement, if the script fails and it's retried,
+// the tables that are already altered are skipped.
+type canary struct {
+	Table  string
+	Tables []string
+
+	// expanded statements for the canary table
+	stmts map[string]struct{}
+}
+
+func parseCanary(arg string) (*canary, error) {
+	fields := strings.Fields(arg)
+	if len(fields) < 1 || len(fields) > 2 {
+		return nil, fmt.Errorf("malformed canary '%v', expected '<table family> [canary table]'", arg)
+	}
+	tables, err := expandTableFamily(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	c := &canary{Table: tables[0], Tables: tables}
+	if len(fields) > 1 {
+		c.Table = fields[1]
+		found := false
+		for _, t := range tables {
+			if t == c.Table {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("canary table '%v' is not in table family '%v'", c.Table, fields[0])
+		}
+	}
+	return c, nil
+}
+
+// Check if the statement is expanded for each table in the family.
+func isTableTemplate(sql string) bool {
+	return strings.Contains(sql, "${"+tablePlaceholder+"}")
+}
+
+// Expand the statements containing '${svc.table}', the canary table goes first.
+func (c *canary) expand(sqls []string, pos []stmtPos) ([]string, []stmtPos, error) {
+	order := make([]string, 0, len(c.Tables))
+	order = append(order, c.Table)
+	for _, t := range c.Tables {
+		if t != c.Table {
+			order = append(order, t)
+		}
+	}
+
+	c.stmts = map[string]struct{}{}
+	for _, sql := range sqls {
+		if isTableTemplate(sql) {
+			c.stmts[resolvePlaceholders(sql, map[string]string{tablePlaceholder: c.Table})] = struct{}{}
+		}
+	}
+	expanded, expandedPos, templates := expandStatements(sqls, pos, tablePlaceholder, order)
+	if templates < 1 {
+		return nil, nil, fmt.Errorf("no statement contains '${%v}' for canary", tablePlaceholder)
+	}
+	return expanded, expandedPos, nil
+}
+
+// Expand the statements containing '${name}' for each of the values (in the order of the values), the expanded
+// statements share the position of the template. Returns the number of templates expanded.
+func expandStatements(sqls []string, pos []stmtPos, name string, values []string) ([]string, []stmtPos, int) {
+	placeholder := "${" + name + "}"
+	expanded := make([]string, 0, len(sqls))
+	expandedPos := make([]stmtPos, 0, len(sqls))
+	templates := 0
+	for i, sql := range sqls {
+		p := stmtPos{Index: i + 1}
+		if i < len(pos) {
+			p = pos[i]
+		}
+		if !strings.Contains(sql, placeholder) {
+			expanded = append(expanded, sql)
+			expandedPos = append(expandedPos, p)
+			continue
+		}
+		templates++
+		for _, v := range values {
+			expanded = append(expanded, resolvePlaceholders(sql, map[string]string{name: v}))
+			expandedPos = append(expandedPos, p)
+		}
+	}
+	return expanded, expandedPos, templates
+}
+
+// Fan-out declared in script using '-- svc:foreach <name>=<table family>'.
+//
+// The statements containing '${<name>}' are expanded for each table in the family, in the order of the family, e.g.,
+//
+//	-- svc:foreach table=t_{0001..0064}
+//	CREATE INDEX idx_created_at ON ${table} (created_at);
+//
+// Each expansion is recorded in schema_script_sql as a separate statement. The directive can be declared more
+// than once with different names, statements referencing multiple names are expanded for each combination.
+// The names are resolved when the script is parsed, they take precedence over MigrateConfig.Placeholders.
+type foreach struct {
+	Name   string
+	Tables []string
+}
+
+func parseForeach(arg string) (foreach, error) {
+	name, family, ok := strings.Cut(arg, "=")
+	name, family = strings.TrimSpace(name), strings.TrimSpace(family)
+	if !ok || name == "" || family == "" {
+		return foreach{}, fmt.Errorf("malformed foreach '%v', expected '<name>=<table family>'", arg)
+	}
+	if !foreachNameRegex.MatchString(name) {
+		return foreach{}, fmt.Errorf("malformed foreach '%v', invalid name '%v'", arg, name)
+	}
+	tables, err := expandTableFamily(family)
+	if err != nil {
+		return foreach{}, err
+	}
+	return foreach{Name: name, Tables: tables}, nil
+}
+
+// Expand the statements containing '${<name>}'.
+func (f foreach) expand(sqls []string, pos []stmtPos) ([]string, []stmtPos, error) {
+	expanded, expandedPos, templates := expandStatements(sqls, pos, f.Name, f.Tables)
+	if templates < 1 {
+		return nil, nil, fmt.Errorf("no statement contains '${%v}' for foreach", f.Name)
+	}
+	return expanded, expandedPos, nil
+}
+
+// Check if the statement is expanded for the canary table.
+func (c *canary) isCanary(sql string) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.stmts[sql]
+	return ok
+}
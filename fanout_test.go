@@ -0,0 +1,97 @@
+package svc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandTableFamily(t *testing.T) {
+	tables, err := expandTableFamily("t_{0001..0064}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 64 || tables[0] != "t_0001" || tables[63] != "t_0064" {
+		t.Fatalf("incorrect tables, %v", tables)
+	}
+	tables, err = expandTableFamily("order_{8..10}_bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tables, ",") != "order_8_bak,order_9_bak,order_10_bak" {
+		t.Fatalf("incorrect tables, %v", tables)
+	}
+	for _, f := range []string{"t_0001", "t_{2..1}", "t_{a..b}", "t_{1..2}_{1..2}", "t_{0..10000}"} {
+		if _, err := expandTableFamily(f); err == nil {
+			t.Fatalf("'%v' should fail", f)
+		}
+	}
+}
+
+func TestParseCanary(t *testing.T) {
+	sf, err := parseScript("-- svc:canary t_{01..03} t_02\nALTER TABLE ${svc.table} ADD COLUMN remark TEXT;\nSELECT 1;", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"ALTER TABLE t_02 ADD COLUMN remark TEXT",
+		"ALTER TABLE t_01 ADD COLUMN remark TEXT",
+		"ALTER TABLE t_03 ADD COLUMN remark TEXT",
+		"SELECT 1",
+	}
+	if strings.Join(sf.SQLs, ";") != strings.Join(want, ";") {
+		t.Fatalf("incorrect statements, %v", sf.SQLs)
+	}
+	if len(sf.Pos) != len(sf.SQLs) || sf.Pos[2].Index != 1 || sf.Pos[3].Index != 2 {
+		t.Fatalf("incorrect positions, %v", sf.Pos)
+	}
+	if !sf.Canary.isCanary(sf.SQLs[0]) || sf.Canary.isCanary(sf.SQLs[1]) {
+		t.Fatal("incorrect canary statements")
+	}
+	for _, s := range []string{
+		"-- svc:canary t_{01..03} t_04\nALTER TABLE ${svc.table} ADD COLUMN remark TEXT;",
+		"-- svc:canary t_{01..03}\nSELECT 1;",
+		"-- svc:canary\nALTER TABLE ${svc.table} ADD COLUMN remark TEXT;",
+	} {
+		if _, err := parseScript(s, server{}); err == nil {
+			t.Fatalf("'%v' should fail", s)
+		}
+	}
+}
+
+func TestParseForeach(t *testing.T) {
+	sf, err := parseScript(`-- svc:foreach table=t_{1..2}
+-- svc:foreach shard=s{1..2}
+CREATE INDEX idx_created_at ON ${table} (created_at);
+INSERT INTO ${shard}.${table} (id) VALUES (1);
+SELECT 1;`, server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"CREATE INDEX idx_created_at ON t_1 (created_at)",
+		"CREATE INDEX idx_created_at ON t_2 (created_at)",
+		"INSERT INTO s1.t_1 (id) VALUES (1)",
+		"INSERT INTO s2.t_1 (id) VALUES (1)",
+		"INSERT INTO s1.t_2 (id) VALUES (1)",
+		"INSERT INTO s2.t_2 (id) VALUES (1)",
+		"SELECT 1",
+	}
+	if strings.Join(sf.SQLs, ";") != strings.Join(want, ";") {
+		t.Fatalf("incorrect statements, %v", sf.SQLs)
+	}
+	if len(sf.Pos) != len(sf.SQLs) || sf.Pos[1].Index != 1 || sf.Pos[5].Index != 2 || sf.Pos[6].Index != 3 {
+		t.Fatalf("incorrect positions, %v", sf.Pos)
+	}
+	for _, s := range []string{
+		"-- svc:foreach t_{1..2}\nSELECT 1;",
+		"-- svc:foreach table=\nSELECT 1;",
+		"-- svc:foreach my-table=t_{1..2}\nSELECT * FROM ${my-table};",
+		"-- svc:foreach table=t_{1..2}\nSELECT 1;",
+		"-- svc:foreach table=t_{1..2}\n-- svc:foreach table=t_{3..4}\nSELECT * FROM ${table};",
+		"-- svc:foreach table=t_{1..2}\n-- svc:resumable ${table}.id 100\nUPDATE ${table} SET a = 1 WHERE id > ${svc.from};",
+	} {
+		if _, err := parseScript(s, server{}); err == nil {
+			t.Fatalf("'%v' should fail", s)
+		}
+	}
+}
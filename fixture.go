@@ -0,0 +1,72 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Load fixtures from the .sql files in dir.
+//
+// Files are executed in the order of their names, the SQLs are parsed the same way as the migration scripts
+// (including the svc:assert directives), but nothing is recorded in schema_version or schema_script_sql.
+//
+// e.g.,
+//
+//	//go:embed testdata/fixtures/*.sql
+//	var fixtureFs embed.FS
+//
+//	err := LoadFixtures(db, fixtureFs, "testdata/fixtures")
+func LoadFixtures(db *gorm.DB, fs ReadFS, dir string) error {
+	if fs == nil {
+		return errors.New("fs is nil")
+	}
+	if db == nil {
+		return errors.New("db is nil")
+	}
+
+	srv, err := detectServer(db, nil)
+	if err != nil {
+		return err
+	}
+	srv.ns = namespace
+
+	entries, err := fs.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open %v folders, %w", dir, err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if !strings.HasSuffix(strings.ToLower(e.Name()), ".sql") {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sf, err := readSchemaFile(fs, sourceFile{Name: name, Path: path.Join(dir, name)}, srv)
+		if err != nil {
+			return err
+		}
+		for i, sql := range sf.SQLs {
+			if err := db.Exec(sql).Error; err != nil {
+				return fmt.Errorf("failed to load fixture %v, statement [%v]: '%v', %w", sf.Path, i+1, sql, err)
+			}
+		}
+		for _, a := range sf.Asserts {
+			if err := runAssertion(db, a); err != nil {
+				return fmt.Errorf("failed to load fixture %v, %w", sf.Path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT COUNT\(\*\) FROM t_user$`, []string{"cnt"}, []driver.Value{int64(2)})
+	db := f.open(t)
+
+	mfs := fstest.MapFS{
+		"fixtures/02_order.sql": {Data: []byte("INSERT INTO t_order (id, user_id) VALUES (1, 1);")},
+		"fixtures/01_user.sql": {Data: []byte(`-- svc:assert SELECT COUNT(*) FROM t_user = 2
+INSERT INTO t_user (id) VALUES (1);
+INSERT INTO t_user (id) VALUES (2);`)},
+		"fixtures/readme.md": {Data: []byte("DROP TABLE t_user;")},
+	}
+	if err := LoadFixtures(db, mfs, "fixtures"); err != nil {
+		t.Fatal(err)
+	}
+	inserts := f.executed(`^INSERT INTO`)
+	prefixes := []string{"INSERT INTO t_user (id) VALUES (1)", "INSERT INTO t_user (id) VALUES (2)", "INSERT INTO t_order"}
+	if len(inserts) != len(prefixes) {
+		t.Fatalf("incorrect statements, %+v", inserts)
+	}
+	for i, p := range prefixes {
+		if !strings.HasPrefix(inserts[i].SQL, p) {
+			t.Errorf("[%d] should be %v, %v", i, p, inserts[i].SQL)
+		}
+	}
+	if q := f.executed(`schema_version|schema_script_sql|DROP TABLE`); len(q) > 0 {
+		t.Fatalf("nothing should be recorded, %+v", q)
+	}
+
+	// failed assertion
+	f = &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT COUNT\(\*\) FROM t_user$`, []string{"cnt"}, []driver.Value{int64(1)})
+	err := LoadFixtures(f.open(t), mfs, "fixtures")
+	if err == nil || !strings.Contains(err.Error(), "01_user.sql") || !strings.Contains(err.Error(), "expected: 2") {
+		t.Fatalf("should fail with the assertion, %v", err)
+	}
+	if q := f.executed(`^INSERT INTO t_order`); len(q) > 0 {
+		t.Fatalf("the fixtures after the failed one should not be loaded, %+v", q)
+	}
+}
@@ -0,0 +1,131 @@
+package svc
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	referencesRegex = regexp.MustCompile(`(?is)\bREFERENCES\s+(` + identPat + `)`)
+)
+
+// Table name without the backticks, the case is kept, e.g., for the lookup of the table on a case-sensitive server.
+func unquoteTable(name string) string {
+	return strings.ReplaceAll(name, "`", "")
+}
+
+// Table name for matching, i.e., without the backticks and in lower case.
+func normalizeTable(name string) string {
+	return strings.ToLower(unquoteTable(name))
+}
+
+// Reorder the CREATE TABLE statements in the script, so that the tables referenced by foreign keys are created first.
+//
+// The statements are only reordered within each run of consecutive CREATE TABLE statements, a statement is never
+// moved past a statement of another kind, e.g., an ALTER TABLE or INSERT of the table. An error is returned if a
+// table references a table created in a later run, if a table is created more than once, or if the tables
+// reference each other.
+func reorderForeignKeys(sf schemaFile) (schemaFile, bool, error) {
+	type table struct {
+		slot int // index in sf.SQLs
+		run  int // index of the run of consecutive CREATE TABLE statements
+		name string
+		refs []string
+	}
+	tables := map[string]*table{}
+	runs := [][]*table{}
+	for i, sql := range sf.SQLs {
+		_, rest := splitLeadingComments(sql)
+		m := undoCreateTableRegex.FindStringSubmatch(strings.TrimSpace(rest))
+		if m == nil {
+			continue
+		}
+		t := &table{slot: i, name: normalizeTable(m[1])}
+		if _, ok := tables[t.name]; ok {
+			return sf, false, fmt.Errorf("table %v is created more than once in %v, the CREATE TABLE statements can't be reordered",
+				t.name, sf.Name)
+		}
+		for _, r := range referencesRegex.FindAllStringSubmatch(rest, -1) {
+			if ref := normalizeTable(r[1]); ref != t.name {
+				t.refs = append(t.refs, ref)
+			}
+		}
+		if n := len(runs); n > 0 && runs[n-1][len(runs[n-1])-1].slot == i-1 {
+			t.run = n - 1
+			runs[n-1] = append(runs[n-1], t)
+		} else {
+			t.run = n
+			runs = append(runs, []*table{t})
+		}
+		tables[t.name] = t
+	}
+
+	changed := false
+	sqls := append([]string{}, sf.SQLs...)
+	pos := make([]stmtPos, len(sf.SQLs))
+	for i := range sf.SQLs {
+		pos[i] = sf.pos(i)
+	}
+	for _, run := range runs {
+		// Kahn's algorithm, ties are broken by the original order
+		indegree := map[string]int{}
+		dependents := map[string][]string{}
+		for _, t := range run {
+			for _, r := range t.refs {
+				rt, ok := tables[r]
+				if !ok || rt.run < t.run {
+					continue // created by previous scripts or before the run
+				}
+				if rt.run > t.run {
+					return sf, false, fmt.Errorf("table %v references %v in %v, which is created after other statements, the"+
+						" CREATE TABLE statements can't be reordered, move the CREATE TABLE of %v before it", t.name, r, sf.Name, r)
+				}
+				indegree[t.name]++
+				dependents[r] = append(dependents[r], t.name)
+			}
+		}
+		ready := []*table{}
+		for _, t := range run {
+			if indegree[t.name] == 0 {
+				ready = append(ready, t)
+			}
+		}
+		ordered := make([]*table, 0, len(run))
+		for len(ready) > 0 {
+			sort.Slice(ready, func(i, j int) bool { return ready[i].slot < ready[j].slot })
+			t := ready[0]
+			ready = ready[1:]
+			ordered = append(ordered, t)
+			for _, d := range dependents[t.name] {
+				indegree[d]--
+				if indegree[d] == 0 {
+					ready = append(ready, tables[d])
+				}
+			}
+		}
+		if len(ordered) < len(run) {
+			cyclic := []string{}
+			for _, t := range run {
+				if indegree[t.name] > 0 {
+					cyclic = append(cyclic, fmt.Sprintf("%v references %v", t.name, strings.Join(t.refs, ", ")))
+				}
+			}
+			sort.Strings(cyclic)
+			return sf, false, fmt.Errorf("foreign keys of the tables created in %v form a cycle (%v), create the tables first"+
+				" and add the foreign keys using ALTER TABLE", sf.Name, strings.Join(cyclic, "; "))
+		}
+
+		for i, t := range ordered {
+			slot := run[i].slot
+			if t.slot != slot {
+				changed = true
+			}
+			sqls[slot] = sf.SQLs[t.slot]
+			pos[slot] = sf.pos(t.slot)
+		}
+	}
+	sf.SQLs, sf.Pos = sqls, pos
+	return sf, changed, nil
+}
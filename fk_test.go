@@ -0,0 +1,66 @@
+package svc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReorderForeignKeys(t *testing.T) {
+	sf, err := parseScript(`
+CREATE TABLE t_role (id INT PRIMARY KEY);
+INSERT INTO t_role (id) VALUES (1);
+CREATE TABLE order_item (id INT PRIMARY KEY, order_id INT, FOREIGN KEY (order_id) REFERENCES `+"`t_order`"+` (id));
+CREATE TABLE t_order (id INT PRIMARY KEY, user_id INT, CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES t_user (id));
+CREATE TABLE t_user (id INT PRIMARY KEY, role_id INT, FOREIGN KEY (role_id) REFERENCES t_role (id));
+`, server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf, changed, err := reorderForeignKeys(sf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("should be reordered")
+	}
+	prefixes := []string{"CREATE TABLE t_role", "INSERT INTO t_role", "CREATE TABLE t_user", "CREATE TABLE t_order", "CREATE TABLE order_item"}
+	for i, p := range prefixes {
+		if !strings.HasPrefix(sf.SQLs[i], p) {
+			t.Errorf("[%d] should be %v, %v", i, p, sf.SQLs[i])
+		}
+	}
+	if sf.Pos[2].Index != 5 || sf.Pos[4].Index != 3 {
+		t.Errorf("positions should be moved with the statements, %v", sf.Pos)
+	}
+
+	if _, changed, err := reorderForeignKeys(sf); err != nil || changed {
+		t.Fatalf("should not be reordered again, %v", err)
+	}
+
+	cyclic := schemaFile{Name: "v0.0.1.sql", SQLs: []string{
+		"CREATE TABLE a (id INT, b_id INT, FOREIGN KEY (b_id) REFERENCES b (id))",
+		"CREATE TABLE b (id INT, a_id INT, FOREIGN KEY (a_id) REFERENCES a (id))",
+	}}
+	if _, _, err := reorderForeignKeys(cyclic); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("should fail with cycle, %v", err)
+	}
+
+	duplicate := schemaFile{Name: "v0.0.1.sql", SQLs: []string{
+		"CREATE TABLE a (id INT)",
+		"CREATE TABLE b (id INT)",
+		"CREATE TABLE a (id INT)",
+	}}
+	if _, _, err := reorderForeignKeys(duplicate); err == nil || !strings.Contains(err.Error(), "more than once") {
+		t.Fatalf("should fail with duplicate table, %v", err)
+	}
+
+	// the CREATE TABLE is not moved past the ALTER TABLE of the table
+	split := schemaFile{Name: "v0.0.1.sql", SQLs: []string{
+		"CREATE TABLE child (id INT, parent_id INT, FOREIGN KEY (parent_id) REFERENCES parent (id))",
+		"ALTER TABLE child ADD COLUMN name VARCHAR(20)",
+		"CREATE TABLE parent (id INT)",
+	}}
+	if _, _, err := reorderForeignKeys(split); err == nil || !strings.Contains(err.Error(), "child references parent") {
+		t.Fatalf("should fail with table created after other statements, %v", err)
+	}
+}
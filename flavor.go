@@ -0,0 +1,81 @@
+package svc
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	FlavorMySQL   = "mysql"
+	FlavorMariaDB = "mariadb"
+)
+
+// Flavor of the database server, detected using SELECT VERSION() (or product_component_version for Oracle).
+type Flavor struct {
+	// FlavorMySQL, FlavorMariaDB or FlavorOracle.
+	Name string
+
+	// Server version, e.g., 8.0.36, 10.11.6-MariaDB.
+	Version string
+}
+
+func (f Flavor) IsMariaDB() bool {
+	return f.Name == FlavorMariaDB
+}
+
+// Check if server version is at or after ver, e.g., f.AtLeast("10.3").
+func (f Flavor) AtLeast(ver string) bool {
+	v, _, _ := strings.Cut(f.Version, "-")
+	return VerAfterEq(v, ver)
+}
+
+// CREATE SEQUENCE is supported since MariaDB 10.3, MySQL doesn't support sequences.
+func (f Flavor) SupportsSequence() bool {
+	return f.IsMariaDB() && f.AtLeast("10.3")
+}
+
+// INSERT ... RETURNING is supported since MariaDB 10.5, MySQL doesn't support RETURNING.
+func (f Flavor) SupportsReturning() bool {
+	return f.IsMariaDB() && f.AtLeast("10.5")
+}
+
+// CREATE INDEX IF NOT EXISTS and ALTER TABLE ... ADD COLUMN IF NOT EXISTS are only supported by MariaDB.
+func (f Flavor) SupportsIfNotExistsDDL() bool {
+	return f.IsMariaDB()
+}
+
+// Detect the flavor of the database server, the dialect is picked based on the gorm dialector.
+func DetectFlavor(db *gorm.DB) (Flavor, error) {
+	return dialectOf(db, nil).DetectFlavor(db)
+}
+
+func parseFlavor(ver string) Flavor {
+	f := Flavor{Name: FlavorMySQL, Version: ver}
+	if strings.Contains(strings.ToLower(ver), "mariadb") {
+		f.Name = FlavorMariaDB
+
+		// e.g., 5.5.5-10.11.6-MariaDB, the 5.5.5- prefix is added for replication compatibility
+		if strings.HasPrefix(ver, "5.5.5-") {
+			f.Version = strings.TrimPrefix(ver, "5.5.5-")
+		}
+	}
+	return f
+}
+
+// Evaluate the condition in '-- svc:if <cond>', the condition is a flavor name optionally prefixed with '!',
+// or flavor name with minimum version, e.g., 'mariadb>=10.5'.
+func (f Flavor) eval(cond string) bool {
+	cond = strings.ToLower(strings.TrimSpace(cond))
+	if strings.HasPrefix(cond, "!") {
+		return !f.eval(cond[1:])
+	}
+	name, ver, hasVer := strings.Cut(cond, ">=")
+	if strings.TrimSpace(name) != f.Name {
+		return false
+	}
+	if hasVer {
+		return f.AtLeast(strings.TrimSpace(ver))
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package svc
+
+import (
+	"testing"
+)
+
+func TestParseFlavor(t *testing.T) {
+	f := parseFlavor("5.5.5-10.11.6-MariaDB-1:10.11.6+maria~ubu2204")
+	if !f.IsMariaDB() {
+		t.Fatal("should be mariadb")
+	}
+	if !f.SupportsSequence() || !f.SupportsReturning() {
+		t.Fatal("should support sequence and returning")
+	}
+	if !f.eval("mariadb>=10.5") || f.eval("mariadb>=11") || f.eval("mysql") || !f.eval("!mysql") {
+		t.Fatal("incorrect condition evaluation")
+	}
+
+	f = parseFlavor("8.0.36")
+	if f.IsMariaDB() || f.SupportsSequence() || f.SupportsIfNotExistsDDL() {
+		t.Fatal("should be mysql")
+	}
+	if !f.eval("mysql") || f.eval("mariadb") {
+		t.Fatal("incorrect condition evaluation")
+	}
+}
+
+func TestParseConditionalBlocks(t *testing.T) {
+	content := `
+-- svc:if mariadb
+CREATE SEQUENCE s_order;
+-- svc:else
+CREATE TABLE s_order (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY);
+-- svc:endif
+SELECT 1;`
+
+	sf, err := parseScript(content, server{flavor: parseFlavor("10.11.6-MariaDB")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sf.SQLs) != 2 || sf.SQLs[0] != "CREATE SEQUENCE s_order" {
+		t.Fatalf("incorrect statements, %v", sf.SQLs)
+	}
+
+	sf, err = parseScript(content, server{flavor: parseFlavor("8.0.36")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sf.SQLs) != 2 || sf.SQLs[0] != "CREATE TABLE s_order (id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY)" {
+		t.Fatalf("incorrect statements, %v", sf.SQLs)
+	}
+
+	if _, err := parseScript("-- svc:if mysql\nSELECT 1;", server{}); err == nil {
+		t.Fatal("should return error")
+	}
+}
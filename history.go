@@ -0,0 +1,158 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Record in schema_version.
+type HistoryRecord struct {
+	Id      int64
+	Script  string
+	Kind    string // 'versioned', 'run-always' or 'ignored'
+	Success bool
+	Remark  string
+
+	// Full error of the failed script, and the 1-based index of the failed statement (0 if it's not caused by a statement).
+	ErrorDetail string
+	FailedStmt  int
+
+	// Time of the record in UTC.
+	CreatedAt time.Time
+
+	// Identifier of the record, see MigrateConfig.IDGenerator, empty for the records saved by previous versions of svc.
+	Uid string
+
+	// Author and ticket of the script, declared with the '-- author:' and '-- ticket:' header comments.
+	Author string
+	Ticket string
+}
+
+// List schema_version records of the app in the order of creation.
+func History(db *gorm.DB, app string) ([]HistoryRecord, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	var rows []struct {
+		Id          int64
+		Script      string
+		Kind        string
+		Success     bool
+		Remark      string
+		ErrorDetail *string
+		FailedStmt  *int
+		CreatedAt   utcTime
+		RecordUid   *string
+		Author      *string
+		Ticket      *string
+	}
+	if err := db.Raw(namespace.rewrite(`SELECT id, script, kind, success, remark, error_detail, failed_stmt, created_at, record_uid, author, ticket FROM schema_version WHERE app = ? ORDER BY id ASC`), appArg(dialectOf(db, nil), app)).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schema_version, %w", err)
+	}
+	hist := make([]HistoryRecord, 0, len(rows))
+	for _, r := range rows {
+		h := HistoryRecord{
+			Id:        r.Id,
+			Script:    r.Script,
+			Kind:      r.Kind,
+			Success:   r.Success,
+			Remark:    r.Remark,
+			CreatedAt: r.CreatedAt.Time,
+		}
+		if r.ErrorDetail != nil {
+			h.ErrorDetail = *r.ErrorDetail
+		}
+		if r.FailedStmt != nil {
+			h.FailedStmt = *r.FailedStmt
+		}
+		if r.RecordUid != nil {
+			h.Uid = *r.RecordUid
+		}
+		if r.Author != nil {
+			h.Author = *r.Author
+		}
+		if r.Ticket != nil {
+			h.Ticket = *r.Ticket
+		}
+		hist = append(hist, h)
+	}
+	return hist, nil
+}
+
+// Versioned script applied successfully, see AppliedBetween.
+type AppliedScript struct {
+	Script string
+
+	// Time of the schema_version record in UTC.
+	AppliedAt time.Time
+
+	// Statements recorded in schema_script_sql in the order of execution.
+	Statements []AppliedStatement
+}
+
+// Statement recorded in schema_script_sql.
+type AppliedStatement struct {
+	Stmt       string
+	UndoStmt   string
+	Reversible bool
+}
+
+// Check if the script is after fromVer and before or eq to toVer, empty fromVer or toVer is unbounded.
+func inVersionRange(script string, fromVer string, toVer string) bool {
+	if fromVer != "" && !VerAfter(script, fromVer) {
+		return false
+	}
+	if toVer != "" && VerAfter(script, toVer) {
+		return false
+	}
+	return true
+}
+
+// List the versioned scripts (and their statements) applied successfully after fromVer up to (and including) toVer,
+// in the order of versions, e.g., AppliedBetween(db, c, "v1.2.0", "v1.3.0") returns what's applied when upgrading
+// from v1.2.0 to v1.3.0. Empty fromVer or toVer is unbounded.
+//
+// It's mainly used to prepare rollback instructions of a release, the undo statements are included if svc knows
+// how to reverse the statements.
+func AppliedBetween(db *gorm.DB, c MigrateConfig, fromVer string, toVer string) ([]AppliedScript, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	var rows []struct {
+		Script    string
+		CreatedAt utcTime
+	}
+	if err := db.Raw(c.ns().rewrite(`SELECT script, created_at FROM schema_version WHERE app = ? AND kind = ? AND success = ? ORDER BY id ASC`),
+		c.appArg(db), kindVersioned, true).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schema_version, %w", err)
+	}
+
+	cph, err := c.stmtCipher()
+	if err != nil {
+		return nil, err
+	}
+	applied := make([]AppliedScript, 0, len(rows))
+	for _, r := range rows {
+		if !inVersionRange(r.Script, fromVer, toVer) {
+			continue
+		}
+		var stmts []AppliedStatement
+		if err := db.Raw(c.ns().rewrite(`SELECT stmt, undo_stmt, reversible FROM schema_script_sql WHERE app = ? AND script = ? ORDER BY id ASC`),
+			c.appArg(db), r.Script).Scan(&stmts).Error; err != nil {
+			return nil, fmt.Errorf("failed to list schema_script_sql, %w", err)
+		}
+		for i := range stmts {
+			if stmts[i].Stmt, err = cph.decrypt(c.App, r.Script, stmts[i].Stmt); err != nil {
+				return nil, err
+			}
+		}
+		applied = append(applied, AppliedScript{Script: r.Script, AppliedAt: r.CreatedAt.Time, Statements: stmts})
+	}
+	sort.SliceStable(applied, func(i, j int) bool { return VerAfter(applied[j].Script, applied[i].Script) })
+	return applied, nil
+}
@@ -0,0 +1,24 @@
+package svc
+
+import "testing"
+
+func TestInVersionRange(t *testing.T) {
+	cases := []struct {
+		script string
+		from   string
+		to     string
+		in     bool
+	}{
+		{"v1.2.0.sql", "v1.2.0", "v1.3.0", false},
+		{"v1.2.1.sql", "v1.2.0", "v1.3.0", true},
+		{"v1.3.0.sql", "v1.2.0", "v1.3.0", true},
+		{"v1.3.1.sql", "v1.2.0", "v1.3.0", false},
+		{"v0.0.1.sql", "", "v1.3.0", true},
+		{"v2.0.0.sql", "v1.2.0", "", true},
+	}
+	for _, c := range cases {
+		if in := inVersionRange(c.script, c.from, c.to); in != c.in {
+			t.Fatalf("inVersionRange(%v, %v, %v) should be %v", c.script, c.from, c.to, c.in)
+		}
+	}
+}
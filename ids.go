@@ -0,0 +1,91 @@
+package svc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"sync"
+	"time"
+)
+
+const (
+	crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// Generator of the identifiers recorded in schema_version.record_uid and schema_run.record_uid.
+//
+// t is the creation time of the record (i.e., created_at or started_at), identifiers that sort in the order of
+// t (e.g., ULID) disambiguate records created within the same millisecond in the history APIs.
+type IDGenerator interface {
+	NewID(t time.Time) string
+}
+
+// IDGenerator of ULIDs (https://github.com/ulid/spec), 26 characters, lexicographically sortable by time.
+//
+// The randomness is read from crypto/rand. IDs generated within the same millisecond are monotonic, the random part
+// of the previous ID is incremented instead, as the spec suggests.
+type ULIDGenerator struct {
+}
+
+var (
+	// the last ULID generated, shared by all ULIDGenerator values
+	ulidMu   sync.Mutex
+	lastULID [16]byte
+)
+
+func (ULIDGenerator) NewID(t time.Time) string {
+	var b [16]byte
+	ms := uint64(t.UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (8 * (5 - i)))
+	}
+
+	ulidMu.Lock()
+	defer ulidMu.Unlock()
+	if bytes.Equal(b[:6], lastULID[:6]) && incrementRandom(&lastULID) {
+		copy(b[6:], lastULID[6:])
+	} else if _, err := rand.Read(b[6:]); err != nil {
+		// crypto/rand doesn't fail on supported platforms, the id is still unique by time if it does
+		for i := 6; i < len(b); i++ {
+			b[i] = 0
+		}
+	}
+	lastULID = b
+	return encodeULID(b)
+}
+
+// Increment the 80 bits random part of the ULID, returns false if it overflows.
+func incrementRandom(b *[16]byte) bool {
+	for i := len(b) - 1; i >= 6; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Encode 128 bits in Crockford's base32, the 130 bits encoded have 2 leading zero bits.
+func encodeULID(b [16]byte) string {
+	bit := func(j int) byte {
+		if j < 0 {
+			return 0
+		}
+		return (b[j/8] >> (7 - j%8)) & 1
+	}
+	out := make([]byte, 26)
+	for i := range out {
+		var v byte
+		for j := 0; j < 5; j++ {
+			v = v<<1 | bit(i*5-2+j)
+		}
+		out[i] = crockfordBase32[v]
+	}
+	return string(out)
+}
+
+func idGeneratorOrDefault(g IDGenerator) IDGenerator {
+	if g == nil {
+		return ULIDGenerator{}
+	}
+	return g
+}
@@ -0,0 +1,51 @@
+package svc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestULIDGenerator(t *testing.T) {
+	var b [16]byte
+	ms := uint64(1469918176385)
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (8 * (5 - i)))
+	}
+	if id := encodeULID(b); id != "01ARYZ6S410000000000000000" {
+		t.Fatalf("incorrect ulid, %v", id)
+	}
+
+	g := ULIDGenerator{}
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	a, z := g.NewID(t0), g.NewID(t0.Add(time.Millisecond))
+	if len(a) != 26 || len(z) != 26 {
+		t.Fatalf("incorrect length, %v, %v", a, z)
+	}
+	if a >= z {
+		t.Fatalf("ulid should be sorted by time, %v, %v", a, z)
+	}
+	if g.NewID(t0) == a {
+		t.Fatal("ulid should be unique")
+	}
+
+	// monotonic within the same millisecond
+	prev := g.NewID(t0)
+	for i := 0; i < 100; i++ {
+		id := g.NewID(t0.Add(500 * time.Microsecond))
+		if id <= prev || id[:10] != prev[:10] {
+			t.Fatalf("ulid should be monotonic, %v, %v", prev, id)
+		}
+		prev = id
+	}
+
+	r := [16]byte{15: 0xff}
+	if !incrementRandom(&r) || r[14] != 1 || r[15] != 0 {
+		t.Fatalf("incorrect increment, %v", r)
+	}
+	for i := 6; i < 16; i++ {
+		r[i] = 0xff
+	}
+	if incrementRandom(&r) {
+		t.Fatal("should overflow")
+	}
+}
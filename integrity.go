@@ -0,0 +1,157 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	IssueOrphanStatements     = "orphan-statements"     // schema_script_sql records of the script missing from schema_version
+	IssueUnfinishedStatements = "unfinished-statements" // script is successful, but some of its statements never completed
+	IssueDuplicateVersions    = "duplicate-versions"    // multiple schema_version records of the same versioned (or ignored) script
+)
+
+// Anomaly found in svc's own tables, see CheckIntegrity.
+type IntegrityIssue struct {
+	Kind   string
+	Script string
+	Detail string
+
+	// ids of the affected records
+	Ids []int64
+
+	// Whether the issue is repaired by RepairIntegrity.
+	Repaired bool
+}
+
+func (i IntegrityIssue) String() string {
+	return fmt.Sprintf("%v: %v, %v", i.Kind, i.Script, i.Detail)
+}
+
+type scriptRecord struct {
+	Id     int64
+	Script string
+}
+
+// Group the records by script, detail is formatted with the number of records.
+func groupIssues(kind string, detail string, records []scriptRecord) []IntegrityIssue {
+	issues := []IntegrityIssue{}
+	idx := map[string]int{}
+	for _, r := range records {
+		i, ok := idx[r.Script]
+		if !ok {
+			i = len(issues)
+			idx[r.Script] = i
+			issues = append(issues, IntegrityIssue{Kind: kind, Script: r.Script})
+		}
+		issues[i].Ids = append(issues[i].Ids, r.Id)
+	}
+	for i := range issues {
+		issues[i].Detail = fmt.Sprintf(detail, len(issues[i].Ids))
+	}
+	return issues
+}
+
+// Check svc's own tables for anomalies, nothing is changed, see RepairIntegrity.
+//
+// The issues detected are:
+//
+//   - IssueOrphanStatements: schema_script_sql records of the scripts missing from schema_version, e.g., the process
+//     was killed in the middle of the script.
+//   - IssueUnfinishedStatements: the script is recorded as successful, but some of its statements were never
+//     completed (schema_script_sql.rows_affected is NULL). The statements recorded by the old versions of svc
+//     (before rows_affected was introduced) are reported as well. The failed statements of the scripts skipped
+//     by SkipFailed are not reported, the failure is kept in the remark.
+//   - IssueDuplicateVersions: multiple schema_version records of the same versioned (or ignored) script.
+//
+// It should not be called while the app is being migrated.
+func CheckIntegrity(db *gorm.DB, c MigrateConfig) ([]IntegrityIssue, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	ns := c.ns()
+	issues := []IntegrityIssue{}
+
+	var orphans []scriptRecord
+	if err := db.Raw(ns.rewrite(`SELECT s.id, s.script FROM schema_script_sql s WHERE s.app = ? AND NOT EXISTS
+		(SELECT 1 FROM schema_version v WHERE v.app = s.app AND v.script = s.script) ORDER BY s.id ASC`), c.appArg(db)).
+		Scan(&orphans).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schema_script_sql, %w", err)
+	}
+	issues = append(issues, groupIssues(IssueOrphanStatements, "%d statements recorded without schema_version", orphans)...)
+
+	var unfinished []scriptRecord
+	if err := db.Raw(ns.rewrite(`SELECT s.id, s.script FROM schema_script_sql s WHERE s.app = ? AND s.rows_affected IS NULL
+		AND EXISTS (SELECT 1 FROM schema_version v WHERE v.app = s.app AND v.script = s.script AND v.kind = ? AND v.success = ?
+		AND v.remark NOT LIKE ?) ORDER BY s.id ASC`), c.appArg(db), kindVersioned, true, skippedRemarkPrefix+"%").
+		Scan(&unfinished).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schema_script_sql, %w", err)
+	}
+	issues = append(issues, groupIssues(IssueUnfinishedStatements, "script is successful, but %d statements never completed", unfinished)...)
+
+	var versions []struct {
+		Id     int64
+		Script string
+		Kind   string
+	}
+	if err := db.Raw(ns.rewrite(`SELECT id, script, kind FROM schema_version WHERE app = ? AND kind IN (?,?) ORDER BY id ASC`),
+		c.appArg(db), kindVersioned, kindIgnored).Scan(&versions).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schema_version, %w", err)
+	}
+	ids := map[string][]int64{}
+	keys := []string{}
+	for _, v := range versions {
+		key := v.Kind + "/" + v.Script
+		if _, ok := ids[key]; !ok {
+			keys = append(keys, key)
+		}
+		ids[key] = append(ids[key], v.Id)
+	}
+	for _, key := range keys {
+		if len(ids[key]) < 2 {
+			continue
+		}
+		kind, script, _ := strings.Cut(key, "/")
+		issues = append(issues, IntegrityIssue{Kind: IssueDuplicateVersions, Script: script, Ids: ids[key],
+			Detail: fmt.Sprintf("%d %v records in schema_version", len(ids[key]), kind)})
+	}
+	return issues, nil
+}
+
+// Check svc's own tables for anomalies (see CheckIntegrity), and repair them, returns the issues found,
+// IntegrityIssue.Repaired tells whether the issue is repaired.
+//
+//   - IssueOrphanStatements: the records of the statements never completed are removed, the ones of the completed
+//     statements are kept, e.g., so that the killed script is resumed by the next migration without executing
+//     the completed DDL again. The issue is reported until the script is migrated.
+//   - IssueUnfinishedStatements: not repaired, whether the statements took effect is unknown, they should be
+//     verified (and executed if necessary) manually.
+//   - IssueDuplicateVersions: the latest record is kept, the others are removed.
+//
+// It should not be called while the app is being migrated.
+func RepairIntegrity(db *gorm.DB, c MigrateConfig) ([]IntegrityIssue, error) {
+	issues, err := CheckIntegrity(db, c)
+	if err != nil {
+		return nil, err
+	}
+	ns := c.ns()
+	for i, is := range issues {
+		var err error
+		switch is.Kind {
+		case IssueOrphanStatements:
+			err = db.Exec(ns.rewrite(`DELETE FROM schema_script_sql WHERE id IN ? AND rows_affected IS NULL`), is.Ids).Error
+		case IssueDuplicateVersions:
+			err = db.Exec(ns.rewrite(`DELETE FROM schema_version WHERE id IN ?`), is.Ids[:len(is.Ids)-1]).Error
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to repair %v, %w", is, err)
+		}
+		issues[i].Repaired = true
+	}
+	return issues, nil
+}
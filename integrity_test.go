@@ -0,0 +1,56 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestGroupIssues(t *testing.T) {
+	issues := groupIssues(IssueOrphanStatements, "%d statements", []scriptRecord{
+		{Id: 1, Script: "v0.0.2.sql"}, {Id: 2, Script: "v0.0.3.sql"}, {Id: 3, Script: "v0.0.2.sql"},
+	})
+	if len(issues) != 2 {
+		t.Fatalf("incorrect issues, %v", issues)
+	}
+	if issues[0].Script != "v0.0.2.sql" || len(issues[0].Ids) != 2 || issues[0].Ids[1] != 3 || issues[0].Detail != "2 statements" {
+		t.Fatalf("incorrect issue, %+v", issues[0])
+	}
+	if s := issues[1].String(); s != "orphan-statements: v0.0.3.sql, 1 statements" {
+		t.Fatalf("incorrect issue, %v", s)
+	}
+}
+
+func TestRepairIntegrity(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`NOT EXISTS`, []string{"id", "script"}, []driver.Value{int64(1), "v0.0.3.sql"}, []driver.Value{int64(2), "v0.0.3.sql"})
+	f.reply(`rows_affected IS NULL`, []string{"id", "script"}, []driver.Value{int64(5), "v0.0.2.sql"})
+	f.reply(`^SELECT id, script, kind FROM schema_version`, []string{"id", "script", "kind"},
+		[]driver.Value{int64(7), "v0.0.1.sql", kindVersioned}, []driver.Value{int64(8), "v0.0.1.sql", kindVersioned},
+		[]driver.Value{int64(9), "v0.0.2.sql", kindVersioned})
+	db := f.open(t)
+
+	issues, err := RepairIntegrity(db, MigrateConfig{App: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 3 {
+		t.Fatalf("incorrect issues, %+v", issues)
+	}
+	for _, is := range issues {
+		if is.Repaired != (is.Kind != IssueUnfinishedStatements) {
+			t.Fatalf("incorrect repaired, %+v", is)
+		}
+	}
+	if q := f.executed(`^SELECT .*rows_affected IS NULL`); len(q) != 1 || q[0].Args[3] != skippedRemarkPrefix+"%" {
+		t.Fatalf("statements of the skipped scripts should be excluded, %+v", q)
+	}
+	if q := f.executed(`^UPDATE schema_script_sql`); len(q) > 0 {
+		t.Fatalf("unfinished statements should not be rewritten, %+v", q)
+	}
+	if q := f.executed(`^DELETE FROM schema_script_sql WHERE id IN \(\?,\?\) AND rows_affected IS NULL$`); len(q) != 1 || len(q[0].Args) != 2 {
+		t.Fatalf("orphan statements never completed should be removed, %+v", q)
+	}
+	if q := f.executed(`^DELETE FROM schema_version WHERE id IN`); len(q) != 1 || len(q[0].Args) != 1 || q[0].Args[0] != int64(7) {
+		t.Fatalf("only the latest duplicate record should be kept, %+v", q)
+	}
+}
@@ -0,0 +1,339 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	kindVersioned = "versioned"
+	kindRunAlways = "run-always"
+	kindIgnored   = "ignored"
+)
+
+// Create svc's own tables (in the namespace) if necessary.
+func initMetaTables(db *gorm.DB, ns Namespace) error {
+	exec := func(ddl string) *gorm.DB { return db.Exec(ns.rewrite(ddl)) }
+	t := exec(`
+	CREATE TABLE IF NOT EXISTS schema_version (
+		id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+		app VARCHAR(50) NOT NULL DEFAULT '',
+		created_at DATETIME(3) NOT NULL,
+		script VARCHAR(256) NOT NULL DEFAULT '',
+		kind VARCHAR(20) NOT NULL DEFAULT 'versioned',
+		success TINYINT(1) NOT NULL DEFAULT 1,
+		remark VARCHAR(256) NOT NULL DEFAULT '',
+		error_detail TEXT,
+		failed_stmt INT DEFAULT NULL,
+		record_uid VARCHAR(32) NOT NULL DEFAULT '',
+		author VARCHAR(256) NOT NULL DEFAULT '',
+		ticket VARCHAR(256) NOT NULL DEFAULT '',
+		PRIMARY KEY (id),
+		KEY app_idx (app)
+	) ENGINE=INNODB DEFAULT CHARSET=utf8mb4 comment='svc schema version';
+	`)
+	if t.Error != nil {
+		return fmt.Errorf("failed to create schema_verion table, %w", t.Error)
+	}
+
+	t = exec(`
+	CREATE TABLE IF NOT EXISTS schema_script_sql (
+		id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+		app VARCHAR(50) NOT NULL DEFAULT '',
+		script VARCHAR(256) NOT NULL DEFAULT '',
+		stmt TEXT,
+		undo_stmt TEXT,
+		reversible TINYINT(1) NOT NULL DEFAULT 0,
+		rows_affected BIGINT(20) DEFAULT NULL,
+		warnings TEXT,
+		skipped TINYINT(1) NOT NULL DEFAULT 0,
+		created_at DATETIME(3) NOT NULL,
+		PRIMARY KEY (id),
+		KEY app_idx (app, script)
+	) ENGINE=INNODB DEFAULT CHARSET=utf8mb4 comment='svc schema script sqls';
+	`)
+	if t.Error != nil {
+		return fmt.Errorf("failed to create schema_script_sql table, %w", t.Error)
+	}
+
+	t = exec(`
+	CREATE TABLE IF NOT EXISTS schema_object (
+		id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+		app VARCHAR(50) NOT NULL DEFAULT '',
+		type VARCHAR(20) NOT NULL DEFAULT '',
+		name VARCHAR(128) NOT NULL DEFAULT '',
+		checksum VARCHAR(64) NOT NULL DEFAULT '',
+		created_at DATETIME(3) NOT NULL,
+		updated_at DATETIME(3) NOT NULL,
+		PRIMARY KEY (id),
+		UNIQUE KEY app_object_uk (app, type, name)
+	) ENGINE=INNODB DEFAULT CHARSET=utf8mb4 comment='svc managed repeatable objects';
+	`)
+	if t.Error != nil {
+		return fmt.Errorf("failed to create schema_object table, %w", t.Error)
+	}
+
+	t = exec(`
+	CREATE TABLE IF NOT EXISTS schema_cursor (
+		id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+		app VARCHAR(50) NOT NULL DEFAULT '',
+		script VARCHAR(256) NOT NULL DEFAULT '',
+		stmt_index INT NOT NULL DEFAULT 0,
+		last_key BIGINT(20) NOT NULL DEFAULT 0,
+		updated_at DATETIME(3) NOT NULL,
+		PRIMARY KEY (id),
+		UNIQUE KEY app_script_stmt_uk (app, script, stmt_index)
+	) ENGINE=INNODB DEFAULT CHARSET=utf8mb4 comment='svc cursors of resumable scripts';
+	`)
+	if t.Error != nil {
+		return fmt.Errorf("failed to create schema_cursor table, %w", t.Error)
+	}
+
+	t = exec(`
+	CREATE TABLE IF NOT EXISTS schema_run (
+		id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+		app VARCHAR(50) NOT NULL DEFAULT '',
+		started_at DATETIME(3) NOT NULL,
+		ended_at DATETIME(3) NOT NULL,
+		host VARCHAR(255) NOT NULL DEFAULT '',
+		scripts TEXT,
+		script_count INT NOT NULL DEFAULT 0,
+		rows_affected BIGINT(20) NOT NULL DEFAULT 0,
+		success TINYINT(1) NOT NULL DEFAULT 1,
+		error_msg TEXT,
+		env VARCHAR(50) NOT NULL DEFAULT '',
+		placeholders TEXT,
+		record_uid VARCHAR(32) NOT NULL DEFAULT '',
+		PRIMARY KEY (id),
+		KEY app_idx (app, started_at)
+	) ENGINE=INNODB DEFAULT CHARSET=utf8mb4 comment='svc migration runs';
+	`)
+	if t.Error != nil {
+		return fmt.Errorf("failed to create schema_run table, %w", t.Error)
+	}
+
+	// columns added in later versions of svc
+	if err := ensureColumn(db, ns.Table("schema_version"), "kind", "VARCHAR(20) NOT NULL DEFAULT 'versioned'"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_version"), "error_detail", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_version"), "failed_stmt", "INT DEFAULT NULL"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_script_sql"), "undo_stmt", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_script_sql"), "reversible", "TINYINT(1) NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_script_sql"), "rows_affected", "BIGINT(20) DEFAULT NULL"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_script_sql"), "warnings", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_script_sql"), "skipped", "TINYINT(1) NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_run"), "env", "VARCHAR(50) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_run"), "placeholders", "TEXT"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_version"), "record_uid", "VARCHAR(32) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_version"), "author", "VARCHAR(256) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_version"), "ticket", "VARCHAR(256) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, ns.Table("schema_run"), "record_uid", "VARCHAR(32) NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+
+	// timestamps were TIMESTAMP columns filled by the server, or DATETIME columns with second precision, in previous
+	// versions of svc
+	for _, c := range [][2]string{
+		{"schema_version", "created_at"},
+		{"schema_script_sql", "created_at"},
+		{"schema_object", "created_at"},
+		{"schema_object", "updated_at"},
+		{"schema_cursor", "updated_at"},
+		{"schema_run", "started_at"},
+		{"schema_run", "ended_at"},
+	} {
+		if err := ensureDatetime(db, ns.Table(c[0]), c[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Convert TIMESTAMP column to DATETIME(3) in UTC, DATETIME columns with second precision are converted to DATETIME(3).
+//
+// The conversion runs in a session with time_zone '+00:00', so the TIMESTAMP values (stored in UTC internally)
+// are converted to DATETIME values in UTC. A transaction is used to pin the session, DDL commits implicitly though.
+func ensureDatetime(db *gorm.DB, table string, column string) error {
+	var col struct {
+		DataType          string
+		DatetimePrecision *int
+	}
+	err := db.Raw(`SELECT data_type, datetime_precision FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`, table, column).Scan(&col).Error
+	if err != nil {
+		return fmt.Errorf("failed to check column %v.%v, %w", table, column, err)
+	}
+	if strings.EqualFold(col.DataType, "datetime") && (col.DatetimePrecision == nil || *col.DatetimePrecision < 3) {
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s DATETIME(3) NOT NULL", table, column)).Error; err != nil {
+			return fmt.Errorf("failed to convert column %v.%v to DATETIME(3), %w", table, column, err)
+		}
+		return nil
+	}
+	if !strings.EqualFold(col.DataType, "timestamp") {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var tz string
+		if err := tx.Raw(`SELECT @@session.time_zone`).Scan(&tz).Error; err != nil {
+			return fmt.Errorf("failed to query time_zone, %w", err)
+		}
+		if err := tx.Exec(`SET time_zone = '+00:00'`).Error; err != nil {
+			return fmt.Errorf("failed to set time_zone, %w", err)
+		}
+		defer tx.Exec(`SET time_zone = ?`, tz)
+		if err := tx.Exec(fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s DATETIME(3) NOT NULL", table, column)).Error; err != nil {
+			return fmt.Errorf("failed to convert column %v.%v to DATETIME(3), %w", table, column, err)
+		}
+		return nil
+	})
+}
+
+// Add column to svc's own table if it's missing, the tables may be created by previous versions of svc.
+func ensureColumn(db *gorm.DB, table string, column string, definition string) error {
+	var cnt int
+	err := db.Raw(`SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?`, table, column).Scan(&cnt).Error
+	if err != nil {
+		return fmt.Errorf("failed to check column %v.%v, %w", table, column, err)
+	}
+	if cnt > 0 {
+		return nil
+	}
+	if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)).Error; err != nil {
+		return fmt.Errorf("failed to add column %v.%v, %w", table, column, err)
+	}
+	return nil
+}
+
+// Cache of prepared statements for the bookkeeping queries on svc's own tables.
+//
+// These queries are executed for every single statement in the scripts, preparing them once
+// saves a round trip for each execution.
+type stmtCache struct {
+	ctx     context.Context
+	pool    gorm.ConnPool
+	dialect Dialect
+	clock   Clock
+	ids     IDGenerator
+	ns      Namespace
+	stmts   map[string]*sql.Stmt
+
+	// whether the queries are routed to the primary, see MigrateConfig.PrimaryReads
+	primary bool
+
+	// ids of schema_version records saved, keyed by app/script/kind
+	versionIds map[string]int64
+}
+
+func newStmtCache(db *gorm.DB, d Dialect, clock Clock, ids IDGenerator, ns Namespace) *stmtCache {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &stmtCache{
+		ctx:     ctx,
+		pool:    db.Statement.ConnPool,
+		dialect: dialectOf(db, d),
+		clock:   clockOrDefault(clock),
+		ids:     idGeneratorOrDefault(ids),
+		ns:      ns,
+		stmts:   map[string]*sql.Stmt{},
+
+		versionIds: map[string]int64{},
+	}
+}
+
+func (c *stmtCache) prepare(query string) (*sql.Stmt, error) {
+	if st, ok := c.stmts[query]; ok {
+		return st, nil
+	}
+	st, err := c.pool.PrepareContext(c.ctx, c.dialect.Rebind(c.ns.rewrite(query)))
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = st
+	return st, nil
+}
+
+// Value of the app column, see appArg.
+func (c *stmtCache) app(app string) string {
+	return appArg(c.dialect, app)
+}
+
+// Current time in UTC, svc's own timestamps are always in UTC.
+func (c *stmtCache) now() time.Time {
+	return c.clock.Now().UTC()
+}
+
+// Value of the timestamp, see timeArg.
+func (c *stmtCache) timeArg(t time.Time) any {
+	return timeArg(c.dialect, t)
+}
+
+func (c *stmtCache) forUpdate() string {
+	if c.primary {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+func (c *stmtCache) exec(query string, args ...any) (sql.Result, error) {
+	st, err := c.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return st.ExecContext(c.ctx, args...)
+}
+
+// Query single row, returns false if no row is found.
+func (c *stmtCache) queryRow(query string, args []any, dest ...any) (bool, error) {
+	st, err := c.prepare(query)
+	if err != nil {
+		return false, err
+	}
+	if err := st.QueryRowContext(c.ctx, args...).Scan(dest...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *stmtCache) close() {
+	for _, st := range c.stmts {
+		st.Close()
+	}
+	c.stmts = map[string]*sql.Stmt{}
+}
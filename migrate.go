@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"path"
 	"sort"
 	"strings"
 	"time"
@@ -14,6 +15,9 @@ import (
 
 var (
 	excluded = map[string]struct{}{}
+
+	// default value of MigrateConfig.RequireSource
+	requireSource = false
 )
 
 // Interface that impls both fs.ReadFileFS and fs.ReadDirFS
@@ -27,6 +31,29 @@ type ReadFS interface {
 	fs.ReadDirFS
 }
 
+// ReadFS backed by os.DirFS.
+type dirFS struct {
+	root string
+	fsys fs.FS
+}
+
+func (d dirFS) Open(name string) (fs.File, error) {
+	return d.fsys.Open(name)
+}
+
+func (d dirFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(d.fsys, name)
+}
+
+func (d dirFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(d.fsys, name)
+}
+
+// ReadFS for the files in the directory, e.g., DirFS("/opt/app").
+func DirFS(dir string) ReadFS {
+	return dirFS{root: dir, fsys: os.DirFS(dir)}
+}
+
 type MigrateConfig struct {
 	App     string
 	Fs      ReadFS
@@ -35,60 +62,456 @@ type MigrateConfig struct {
 	// Starting version, it's optional. If provided, svc tries to start with the provided version.
 	// If absent, svc follows the previous version.
 	StartingVersion string
+
+	// Clock used by svc, it's optional. If absent, SystemClock is used. See also DatabaseClock.
+	Clock Clock
+
+	// Generator of the identifiers recorded in schema_version and schema_run (record_uid), it's optional. If absent,
+	// ULIDGenerator is used.
+	IDGenerator IDGenerator
+
+	// Formatter of the remarks recorded in schema_version for the scripts applied successfully, it's optional. If
+	// absent, the default remarks are recorded, e.g., 'Executed'.
+	RemarkFormatter RemarkFormatter
+
+	// Deterministic mode, log lines based on wall time (e.g., time took) are not printed.
+	Deterministic bool
+
+	// Guard the connection pool during the migration.
+	//
+	// If enabled, svc runs the migration on a dedicated connection and constrains the pool to a single
+	// open connection until the migration is finished, application queries sharing the same pool are
+	// blocked instead of racing against half-applied DDL.
+	//
+	// Queries on the guarded pool made by the hooks during the migration (e.g., a custom Executor or
+	// Throttle.ReplicationLag using the db passed in instead of the connection provided) wait for the migration to
+	// release the connection, i.e., deadlock. DatabaseClock backed by the same pool is bound to the dedicated
+	// connection automatically.
+	GuardPool bool
+
+	// Adopt mode, errors indicating that the statement has already been applied (table exists, duplicate
+	// column, duplicate key name) are treated as successful no-ops.
+	//
+	// It's mainly used when adopting svc on databases where old scripts were partially applied manually.
+	Adopt bool
+
+	// Smart-skip mode, before executing CREATE TABLE, CREATE INDEX and ALTER TABLE ... ADD COLUMN / INDEX, svc checks
+	// information_schema and skips the statement if the table, column or index already exists. The skipped statements
+	// are recorded in schema_script_sql with skipped = 1. Only supported by MySQL.
+	//
+	// Unlike Adopt, the statement is not executed at all, ALTER TABLE with multiple clauses is always executed.
+	SmartSkip bool
+
+	// Rewrite CREATE TABLE statements to CREATE TABLE IF NOT EXISTS before execution, it makes re-runs
+	// of interrupted scripts safer.
+	//
+	// The statements recorded in schema_script_sql are not rewritten. Only supported by MySQL (and MariaDB), Oracle
+	// doesn't support IF NOT EXISTS before 23c.
+	RewriteIfNotExists bool
+
+	// Clauses appended to ALTER TABLE statements, e.g., DefaultOnlineAlter ('ALGORITHM=INPLACE, LOCK=NONE'), it's
+	// optional. Statements specifying ALGORITHM or LOCK explicitly are not changed. If the server rejects the
+	// online algorithm, the script fails instead of falling back to a table-locking ALTER. Only supported by MySQL.
+	//
+	// It can also be enabled for a single script using '-- svc:online-alter [clauses]'. The statements recorded
+	// in schema_script_sql are not changed.
+	OnlineAlter string
+
+	// Run SHOW WARNINGS after each statement, the warnings are logged and recorded in schema_script_sql.
+	//
+	// Warnings are session-scoped, the migration runs on a dedicated connection if enabled.
+	CaptureWarnings bool
+
+	// Enforce strict sql_mode (STRICT_TRANS_TABLES and STRICT_ALL_TABLES) for the migration session,
+	// and fail the migration on data truncation warnings.
+	//
+	// sql_mode is session-scoped, the migration runs on a dedicated connection if enabled.
+	StrictSQLMode bool
+
+	// Require BaseDir to exist and contain at least one .sql file, otherwise the migration fails.
+	//
+	// If absent (nil), the default set by SetRequireSource is used. It can be set to false explicitly to opt out
+	// of the default.
+	RequireSource *bool
+
+	// Fail the migration if the pending scripts contain no statement (e.g., the content is commented out), nothing
+	// is executed. By default, the empty scripts are recorded as applied with a warning, see Result.Empty.
+	StrictEmptyScripts bool
+
+	// Discover scripts in the nested directories under BaseDir (e.g., schema/svc/2024/v1.3.0.sql), versions are
+	// extracted from the file names only. The directories of repeatable objects (e.g., views/) are not included.
+	Recursive bool
+
+	// Names of the script files that are excluded, in addition to the ones excluded using ExcludeFile.
+	Exclude []string
+
+	// Placeholders in the statements, e.g., '${schema}', are replaced with the values before execution.
+	//
+	// They are replaced in the statements of the scripts, the repeatable objects (views/ and routines/), the queries
+	// of '-- svc:assert' and '-- svc:check', '-- svc:database', and the statements executed in Rollback (both the
+	// '-- migrate:down' section and the undo statements). The statements recorded in schema_script_sql are not replaced.
+	Placeholders map[string]string
+
+	// Names of the placeholders holding secrets, their values are not recorded in schema_run, nor published in the
+	// schema change events (see Publisher). Placeholders with
+	// names containing 'password', 'secret', 'token' or 'credential' are always treated as secrets.
+	SecretPlaceholders []string
+
+	// Key of AES-GCM (16, 24 or 32 bytes, i.e., AES-128, AES-192 or AES-256) used to encrypt the statements recorded
+	// in schema_script_sql, it's optional. Statements recorded before the key is provided remain readable, but the
+	// key can't be removed or changed once statements are encrypted with it. The undo statements are not encrypted.
+	StmtKey []byte
+
+	// Name of the environment, e.g., staging or prod, it's optional. It's recorded in schema_run together with
+	// the placeholder values, so that the exact SQL executed in the environment can be reproduced later.
+	Env string
+
+	// Dry run, pending scripts are resolved and reported in Result without being executed, nothing is written to the database.
+	DryRun bool
+
+	// Force the queries on svc's own tables to be routed to the primary, it's mainly used behind proxies that split
+	// reads and writes (e.g., ProxySQL), where a read right after the write may hit a stale replica.
+	//
+	// The queries are executed with 'FOR UPDATE', which proxies route to the primary. The ids of schema_version
+	// records saved in the run are cached, they are updated without being read again. Only supported by MySQL.
+	PrimaryReads bool
+
+	// Charset and collation required for the tables created by the scripts, e.g., utf8mb4 and utf8mb4_0900_ai_ci,
+	// they are optional. If provided, the tables created are checked after each script, and the script fails if
+	// the table options don't match. Only supported by MySQL.
+	RequiredCharset   string
+	RequiredCollation string
+
+	// Executor of the statements, it's optional. If absent, DefaultExecutor is used.
+	Executor Executor
+
+	// DSN of the dedicated connection for the migration, e.g., a DDL-privileged user, it's optional. If provided,
+	// svc opens (and closes) its own MySQL connection, the db passed in is not used, and it can be nil.
+	DSN string
+
+	// Factory of the dedicated connection for the migration, same as DSN, but it can be used for any driver.
+	// It takes precedence over DSN.
+	Connect ConnectFunc
+
+	// What to do if the previous migration was failed, FailHard by default.
+	OnPreviousFailure FailurePolicy
+
+	// Record the failure of a script and continue with the next ones, instead of stopping the migration, e.g., for
+	// fleet-wide maintenance scripts that are independent of each other. Scripts can also be marked with
+	// '-- svc:optional' individually. The failed checks ('-- svc:check') of the versioned scripts and the
+	// failures of the Throttle still stop the migration. See ScriptFailure.
+	ContinueOnError bool
+
+	// Reorder the CREATE TABLE statements in each script, so that the tables referenced by foreign keys are created
+	// first, only within each run of consecutive CREATE TABLE statements. The migration fails before anything is
+	// executed if the foreign keys form a cycle, if a table references a table created after other statements, or
+	// if a table is created more than once.
+	ReorderForeignKeys bool
+
+	// Forward compatibility policy, it's optional. If provided, pending scripts are verified against the policy
+	// before anything is executed, e.g., columns can't be dropped unless they were deprecated in an earlier version.
+	CompatPolicy *CompatPolicy
+
+	// Throttling of the statements, e.g., a fixed delay between statements, or waiting for the replication lag to
+	// drop below a threshold, it's optional. See Throttle.
+	Throttle *Throttle
+
+	// Dialect of the database, it's optional. If absent, the dialect is picked based on the gorm dialector,
+	// i.e., OracleDialect for 'oracle', MySQLDialect for everything else.
+	Dialect Dialect
+
+	// Acquire the migration lock (named '<namespace>:<app>', e.g., 'svc:myapp') before the migration, concurrent migrations of the same app
+	// (e.g., multiple instances starting at the same time) wait for each other.
+	//
+	// The lock is session-scoped, the migration runs on a dedicated connection if enabled.
+	Lock bool
+
+	// How long to wait for the migration lock, ErrLockNotAcquired is returned on timeout. Defaults to 1 minute.
+	LockTimeout time.Duration
+
+	// Reporter of the migration progress, it's optional, e.g., PrettyReporter for the terminal.
+	Reporter Reporter
+
+	// Publisher of the schema change event, it's optional. If provided, an event describing the executed DDL is
+	// published after the migration succeeded. See Publisher.
+	Publisher Publisher
+
+	// Namespace of svc's own tables, the migration lock and the directives, it's optional. If absent, the one set by
+	// SetNamespace is used, which is 'svc' by default. See Namespace.
+	Namespace Namespace
+
+	// database server, detected at the beginning of the migration
+	srv server
+}
+
+// Check if the script file is excluded.
+func (c MigrateConfig) isExcluded(name string) bool {
+	if isExcluded(name) {
+		return true
+	}
+	for _, ex := range c.Exclude {
+		if strings.EqualFold(ex, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether BaseDir is required, see RequireSource.
+func (c MigrateConfig) sourceRequired() bool {
+	if c.RequireSource != nil {
+		return *c.RequireSource
+	}
+	return requireSource
+}
+
+// Check if the migration should run on a dedicated connection.
+func (c MigrateConfig) needsSession() bool {
+	return c.GuardPool || c.CaptureWarnings || c.StrictSQLMode || c.Lock
+}
+
+// Bind the clock backed by the pool to the dedicated connection of the migration, so that it doesn't wait for the
+// connection held by the migration itself, see GuardPool.
+func (c MigrateConfig) onSession(db *gorm.DB, conn *gorm.DB) MigrateConfig {
+	if dc, ok := c.Clock.(databaseClock); ok && dc.db != nil && dc.db.Statement.ConnPool == db.Statement.ConnPool {
+		c.Clock = databaseClock{db: conn}
+	}
+	return c
+}
+
+// Suffix of the queries on svc's own tables, it's ' FOR UPDATE' if PrimaryReads is enabled.
+func (c MigrateConfig) forUpdate() string {
+	if c.PrimaryReads {
+		return " FOR UPDATE"
+	}
+	return ""
 }
 
+func (c MigrateConfig) lockTimeout() time.Duration {
+	if c.LockTimeout > 0 {
+		return c.LockTimeout
+	}
+	return time.Minute
+}
+
+// Result of the migration.
+type Result struct {
+	// Whether it's a dry run, the scripts are not executed in dry run.
+	DryRun bool
+
+	// Flavor of the database server.
+	Flavor Flavor
+
+	// Scripts executed.
+	Scripts []ScriptResult
+
+	// Total number of rows affected by the executed statements.
+	RowsAffected int64
+
+	// Scripts that failed without stopping the migration, see MigrateConfig.ContinueOnError and '-- svc:optional'.
+	Failures []ScriptFailure
+
+	// Scripts that contain no statement, they are recorded as applied with a warning, see MigrateConfig.StrictEmptyScripts.
+	Empty []string
+}
+
+// Result of a single executed script.
+type ScriptResult struct {
+	Script string
+
+	// Number of statements executed.
+	Statements int
+
+	// Number of rows affected by the executed statements.
+	RowsAffected int64
+
+	// Number of statements skipped because their effect already exists, see MigrateConfig.SmartSkip.
+	Skipped int
+
+	// The script contains no statement.
+	Empty bool
+
+	// Warnings captured, only available when CaptureWarnings is enabled.
+	Warnings []string
+
+	// Statements that would be executed, only available in dry run.
+	Planned []PlannedStatement
+
+	// Parsed version of the script, the zero value for the scripts that are not versioned, e.g., run-always ones.
+	Version Version
+
+	// DDL executed, only collected when Publisher is provided.
+	changes []SchemaChange
+}
+
+func (r *Result) add(sr ScriptResult) {
+	r.Scripts = append(r.Scripts, sr)
+	r.RowsAffected += sr.RowsAffected
+	if sr.Empty {
+		r.Empty = append(r.Empty, sr.Script)
+	}
+}
+
+// Policy of handling the failed previous migration.
+type FailurePolicy string
+
+const (
+	// Migration fails until the failed script and the schema_version record are fixed manually.
+	FailHard FailurePolicy = ""
+
+	// The failed script is executed again from its checkpoint, i.e., the statements executed successfully are skipped.
+	RetryFailed FailurePolicy = "retry"
+
+	// The failed script is recorded as skipped, and the migration continues with the scripts after it.
+	SkipFailed FailurePolicy = "skip"
+
+	// prefix of schema_version.remark of the failed scripts skipped by SkipFailed
+	skippedRemarkPrefix = "Skipped after failure: "
+)
+
 func MigrateSchema(db *gorm.DB, log Logger, c MigrateConfig) error {
-	start := time.Now()
-	defer func() { log.Infof("Migrate schema took %v", time.Since(start)) }()
+	_, err := Run(db, log, c)
+	return err
+}
 
+// Migrate schema, same as MigrateSchema, but the Result of the migration is returned.
+//
+// Result is returned even if the migration failed, it contains the scripts that have been executed.
+func Run(db *gorm.DB, log Logger, c MigrateConfig) (Result, error) {
 	if c.Fs == nil {
-		return errors.New("fs is nil")
+		return Result{}, errors.New("fs is nil")
 	}
 	if log == nil {
-		return errors.New("log is nil")
+		return Result{}, errors.New("log is nil")
+	}
+
+	clock := clockOrDefault(c.Clock)
+	start := clock.Now()
+	res, err := run(db, log, c, clock, start)
+	if !c.Deterministic {
+		log.Infof("Migrate schema took %v", clock.Now().Sub(start))
+	}
+
+	if err == nil {
+		publishChanges(log, c, res, clock.Now())
+	}
+	if c.Reporter != nil {
+		c.Reporter.Finished(c.App, res, clock.Now().Sub(start), err)
+	}
+
+	// machine-parsable summary, always the last line of the run
+	log.Info(NewSummary(c.App, res, err).String())
+	return res, err
+}
+
+func run(db *gorm.DB, log Logger, c MigrateConfig, clock Clock, start time.Time) (Result, error) {
+	db, closeConn, err := c.connect(db)
+	if err != nil {
+		return Result{}, err
 	}
+	defer closeConn()
 	if db == nil {
-		return errors.New("db is nil")
+		return Result{}, errors.New("db is nil")
+	}
+	switch c.OnPreviousFailure {
+	case FailHard, RetryFailed, SkipFailed:
+	default:
+		return Result{}, fmt.Errorf("unknown failure policy '%v'", c.OnPreviousFailure)
+	}
+	if _, err := c.stmtCipher(); err != nil {
+		return Result{}, err
+	}
+	if err := c.Throttle.validate(); err != nil {
+		return Result{}, err
+	}
+	c.Dialect = dialectOf(db, c.Dialect)
+	if c.Dialect.Name() != DialectMySQL && (c.CaptureWarnings || c.StrictSQLMode || c.PrimaryReads ||
+		c.RequiredCharset != "" || c.RequiredCollation != "" || c.OnlineAlter != "" || c.SmartSkip || c.RewriteIfNotExists) {
+		return Result{}, fmt.Errorf("CaptureWarnings, StrictSQLMode, PrimaryReads, RequiredCharset, RequiredCollation,"+
+			" OnlineAlter, SmartSkip and RewriteIfNotExists are not supported by %v dialect", c.Dialect.Name())
+	}
+
+	if !c.needsSession() {
+		res, err := migrateSchema(db, log, c)
+		recordRun(db, log, c, start, clock.Now(), res, err)
+		return res, err
+	}
+
+	sqlDb, err := db.DB()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to obtain connection pool, %w", err)
 	}
+	var res Result
+	err = db.Connection(func(conn *gorm.DB) error {
+		c = c.onSession(db, conn)
+		clock := clockOrDefault(c.Clock)
+		if c.GuardPool {
+			prev := sqlDb.Stats().MaxOpenConnections
+			sqlDb.SetMaxOpenConns(1)
+			defer sqlDb.SetMaxOpenConns(prev)
+			log.Infof("Connection pool constrained to 1 open connection during migration")
+		}
+		if c.Lock {
+			name := c.ns().lockName(c.App)
+			ok, err := c.Dialect.Lock(conn, name, c.lockTimeout())
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("failed to acquire lock %v within %v, %w", name, c.lockTimeout(), ErrLockNotAcquired)
+			}
+			defer func() {
+				if err := c.Dialect.Unlock(conn, name); err != nil {
+					log.Errorf("failed to release lock %v, %v", name, err)
+				}
+			}()
+		}
+		if c.StrictSQLMode {
+			restore, err := setStrictSQLMode(conn)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if err := restore(); err != nil {
+					log.Errorf("failed to restore sql_mode, %v", err)
+				}
+			}()
+		}
 
+		var err error
+		res, err = migrateSchema(conn, log, c)
+		recordRun(conn, log, c, start, clock.Now(), res, err)
+		return err
+	})
+	return res, err
+}
+
+func migrateSchema(db *gorm.DB, log Logger, c MigrateConfig) (Result, error) {
+	res := Result{DryRun: c.DryRun}
+
+	srv, err := detectServer(db, c.Dialect)
+	if err != nil {
+		return res, err
+	}
+	srv.ns = c.ns()
+	c.Dialect = srv.dialect
+	c.srv = srv
+	fl := srv.flavor
+	res.Flavor = fl
+	log.Infof("Detected database server %v %v", fl.Name, fl.Version)
 	// check if the table doesn't exist at all
 	// for the first time we run svc, we know that we don't need to migrate
 	// schema, the schema we have is already the latest version
 	var firstRun = false
-	if err := db.Exec(`SELECT id FROM schema_version LIMIT 1`).Error; err != nil {
+	if err := db.Exec(c.ns().rewrite(`SELECT id FROM schema_version ` + c.Dialect.LimitOne())).Error; err != nil {
 		firstRun = true
 		log.Infof("schema_version not exists, initializing schema_version to latest one")
 	}
 
-	t := db.Exec(`
-	CREATE TABLE IF NOT EXISTS schema_version (
-		id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
-		app VARCHAR(50) NOT NULL DEFAULT '',
-		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		script VARCHAR(256) NOT NULL DEFAULT '',
-		success TINYINT(1) NOT NULL DEFAULT 1,
-		remark VARCHAR(256) NOT NULL DEFAULT '',
-		PRIMARY KEY (id),
-		KEY app_idx (app)
-	) ENGINE=INNODB DEFAULT CHARSET=utf8mb4 comment='svc schema version';
-	`)
-	if t.Error != nil {
-		return fmt.Errorf("failed to create schema_verion table, %w", t.Error)
-	}
-
-	t = db.Exec(`
-	CREATE TABLE IF NOT EXISTS schema_script_sql (
-		id BIGINT(20) UNSIGNED NOT NULL AUTO_INCREMENT,
-		app VARCHAR(50) NOT NULL DEFAULT '',
-		script VARCHAR(256) NOT NULL DEFAULT '',
-		stmt TEXT,
-		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		PRIMARY KEY (id),
-		KEY app_idx (app, script)
-	) ENGINE=INNODB DEFAULT CHARSET=utf8mb4 comment='svc schema script sqls';
-	`)
-	if t.Error != nil {
-		return fmt.Errorf("failed to create schema_script_sql table, %w", t.Error)
+	if !c.DryRun {
+		if err := c.Dialect.InitMetaTables(db, c.ns()); err != nil {
+			return res, err
+		}
 	}
 
 	var last string
@@ -96,22 +519,85 @@ func MigrateSchema(db *gorm.DB, log Logger, c MigrateConfig) error {
 		last = c.StartingVersion
 	}
 
+	// baseline.sql sets the effective StartingVersion, and bootstraps new databases
+	baseline, hasBaseline, err := readBaseline(c.Fs, c.BaseDir, c.srv)
+	if err != nil {
+		return res, err
+	}
+	bootstrapped := false
+	if hasBaseline {
+		if last == "" {
+			last = baseline.Name
+		}
+		if firstRun {
+			empty, err := isEmptySchema(db)
+			if err != nil {
+				return res, err
+			}
+			if empty {
+				log.Infof("Bootstrapping new database using %v at version %v", baseline.Path, baseline.Name)
+				meta := newStmtCache(db, c.Dialect, c.Clock, c.IDGenerator, c.ns())
+				meta.primary = c.PrimaryReads
+				sr, err := runSQLFile(db, meta, log, c, baseline)
+				meta.close()
+				res.add(sr)
+				if err != nil {
+					return res, fmt.Errorf("failed to exec baseline %v, %w", baseline.Path, err)
+				}
+				firstRun, bootstrapped = false, true
+			}
+		}
+	}
+
+	// failed script that is retried from its checkpoint, failed script that the migration continued after, and failed
+	// script that is skipped
+	var retry, continued, skipped string
 	lastVer := new(schemaVersion)
-	if !firstRun {
-		t = db.Raw(`
+	if !firstRun && !bootstrapped {
+		var recorded []string
+		if err := db.Raw(c.ns().rewrite(`SELECT DISTINCT script FROM schema_version WHERE app = ? AND kind = ?`+c.forUpdate()),
+			c.appArg(db), kindVersioned).Scan(&recorded).Error; err != nil {
+			return res, fmt.Errorf("failed to list schema_verion, %w", err)
+		}
+		if err := verRuleConflict(recorded); err != nil {
+			return res, err
+		}
+
+		t := db.Raw(c.ns().rewrite(`
 		SELECT id, script, success, remark
 		FROM schema_version
-		WHERE app = ?
-		ORDER BY id DESC LIMIT 1`, c.App).Scan(lastVer)
+		WHERE app = ? AND kind = ?
+		ORDER BY id DESC `+c.Dialect.LimitOne()+c.forUpdate()), c.appArg(db), kindVersioned).Scan(lastVer)
 		if t.Error != nil {
-			return fmt.Errorf("failed to list schema_verion, %w", t.Error)
+			return res, fmt.Errorf("failed to list schema_verion, %w", t.Error)
 		}
 		if t.RowsAffected < 1 {
 			lastVer = nil
+		} else if !lastVer.Success && isThrottled(lastVer.Remark) {
+			log.Infof("Previous schema migration was interrupted by the throttle at '%v', resuming from the checkpoint", lastVer.Script)
+			retry = lastVer.Script
+		} else if !lastVer.Success && isContinuedFailure(lastVer.Remark) {
+			log.Infof("Previous schema migration continued after the failure of '%v', it's not retried", lastVer.Script)
+			continued = lastVer.Script
 		} else if !lastVer.Success {
-			return fmt.Errorf(`previous schema migration was failed, last attempt was '%v' (%v), please fix the execution
+			switch c.OnPreviousFailure {
+			case RetryFailed:
+				log.Infof("Previous schema migration was failed at '%v' (%v), retrying from the checkpoint", lastVer.Script, lastVer.Remark)
+				retry = lastVer.Script
+			case SkipFailed:
+				log.Infof("Previous schema migration was failed at '%v' (%v), skipped", lastVer.Script, lastVer.Remark)
+				skipped = lastVer.Script
+				if !c.DryRun {
+					if err := db.Exec(c.ns().rewrite(`UPDATE schema_version SET success = ?, remark = ? WHERE id = ?`),
+						true, truncateRemark(skippedRemarkPrefix+lastVer.Remark), lastVer.Id).Error; err != nil {
+						return res, fmt.Errorf("failed to update schema_version, %w", err)
+					}
+				}
+			default:
+				return res, fmt.Errorf(`previous schema migration was failed, last attempt was '%v' (%v), please fix the execution
  manually and update the last 'schema_version' record status (id: %v)`,
-				lastVer.Script, lastVer.Remark, lastVer.Id)
+					lastVer.Script, lastVer.Remark, lastVer.Id)
+			}
 		}
 	}
 
@@ -134,53 +620,155 @@ func MigrateSchema(db *gorm.DB, log Logger, c MigrateConfig) error {
 		log.Infof("Migrate schema version starting from '%s'", last)
 	}
 
-	files, err := c.Fs.ReadDir(c.BaseDir)
+	required := c.sourceRequired()
+	files, err := discoverFiles(c.Fs, c.BaseDir, c.Recursive, c.isExcluded)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			if required {
+				return res, fmt.Errorf("schema directory '%v' not found", c.BaseDir)
+			}
+			log.Infof("Schema directory '%v' not found, skipped", c.BaseDir)
+			return res, nil
 		}
-		return fmt.Errorf("failed to open %v folders, %w", c.BaseDir, err)
+		return res, fmt.Errorf("failed to open %v folders, %w", c.BaseDir, err)
 	}
 
-	schemaFiles, err := convertSchemaFiles(last, files, c.BaseDir, c.Fs)
+	resolved := listSQLFiles(files)
+	log.Infof("Resolved %d schema files in '%v': %v", len(resolved), c.BaseDir, resolved)
+	if required && len(resolved) < 1 {
+		return res, fmt.Errorf("no .sql file found in schema directory '%v'", c.BaseDir)
+	}
+
+	schemaFiles, runAlways, err := convertSchemaFiles(last, files, c.Fs, c.srv)
 	if err != nil {
-		return err
+		return res, err
 	}
 	sortSchemaFile(schemaFiles)
+	if c.StrictEmptyScripts {
+		if err := verifyNotEmpty(runAlways); err != nil {
+			return res, err
+		}
+	}
+
+	meta := newStmtCache(db, c.Dialect, c.Clock, c.IDGenerator, c.ns())
+	meta.primary = c.PrimaryReads
+	defer meta.close()
+
+	cph, err := c.stmtCipher()
+	if err != nil {
+		return res, err
+	}
 
 	if firstRun && len(schemaFiles) > 0 {
 		last := schemaFiles[len(schemaFiles)-1]
-		if er := saveSchemaVer(db, c.App, last.Name, true, fmt.Sprintf("Initialized at version %v", last.Name)); er != nil {
-			log.Errorf("failed to save schema_version, %v, %w", last.Name, er)
-			return err
+		if c.DryRun {
+			log.Infof("[dry-run] schema_version would be initialized at version %v", last.Name)
+			return res, nil
+		}
+		if er := saveSchemaVer(meta, c.App, last.Name, kindVersioned, true,
+			c.remark(RemarkInitialized, last, fmt.Sprintf("Initialized at version %v", last.Name))); er != nil {
+			log.Errorf("failed to save schema_version, %v, %v", last.Name, er)
+			return res, er
+		}
+		return runRepeatables(db, meta, log, c, runAlways, res)
+	}
+
+	ignored := map[string]struct{}{}
+	if !bootstrapped {
+		names, err := listIgnored(db, c.ns(), c.appArg(db), c.forUpdate())
+		if err != nil {
+			return res, err
+		}
+		for _, n := range names {
+			ignored[n] = struct{}{}
 		}
-		return nil
 	}
 
+	pending := make([]schemaFile, 0, len(schemaFiles))
 	for i, sf := range schemaFiles {
+		if _, ok := ignored[sf.Name]; ok {
+			log.Infof("Script %v is ignored, skipped", sf.Name)
+			continue
+		}
+
+		if continued != "" && VerEq(sf.Name, continued) {
+			continue
+		}
+
+		// the remaining statements of the skipped script are not executed, even if it's the last one
+		if skipped != "" && VerEq(sf.Name, skipped) {
+			continue
+		}
+
+		// the statements executed successfully before the failure are skipped, the assertions are run again
+		if retry != "" && VerEq(sf.Name, retry) {
+			sf, err := checkpoint(db, c, sf)
+			if err != nil {
+				return res, err
+			}
+			pending = append(pending, sf)
+			continue
+		}
 
 		// for the last one, check whether there are new sqls being added to the script file (e.g., during development)
-		if i == len(schemaFiles)-1 {
-			var executed []string
-			if err := db.Raw(`SELECT stmt FROM schema_script_sql WHERE app = ? and script = ?`, c.App, sf.Name).Scan(&executed).Error; err != nil {
-				return err
+		if i == len(schemaFiles)-1 && !bootstrapped {
+			var recorded []struct {
+				Id           int64
+				Stmt         string
+				RowsAffected *int64
+			}
+			if err := db.Raw(c.ns().rewrite(`SELECT id, stmt, rows_affected FROM schema_script_sql WHERE app = ? and script = ?`+c.forUpdate()),
+				c.appArg(db), sf.Name).Scan(&recorded).Error; err != nil {
+				return res, err
+			}
+			executed := make([]string, 0, len(recorded))
+			for _, r := range recorded {
+				executed = append(executed, r.Stmt)
+			}
+			if err := cph.decryptAll(c.App, sf.Name, executed); err != nil {
+				return res, err
+			}
+
+			// the script is interrupted before schema_version is saved, e.g., the process is killed during a backfill,
+			// the unfinished statements and the ones with a saved cursor are executed again (from the cursor)
+			unfinished, err := unfinishedStmts(db, c, sf, lastVer)
+			if err != nil {
+				return res, err
 			}
 
 			// start filtering
 			if len(executed) > 0 {
 				mem := map[string]struct{}{}
-				for _, s := range executed {
+				retried := []int64{}
+				for j, s := range executed {
+					if unfinished != nil {
+						if _, ok := unfinished[s]; ok || recorded[j].RowsAffected == nil {
+							retried = append(retried, recorded[j].Id)
+							continue
+						}
+					}
 					mem[s] = struct{}{}
 				}
+				if len(retried) > 0 {
+					log.Infof("Script %v was interrupted, %d unfinished statements are executed again", sf.Name, len(retried))
+					if !c.DryRun {
+						if err := db.Exec(c.ns().rewrite(`DELETE FROM schema_script_sql WHERE id IN ?`), retried).Error; err != nil {
+							return res, fmt.Errorf("failed to delete schema_script_sql, %w", err)
+						}
+					}
+				}
 
 				sqls := make([]string, 0, len(sf.SQLs))
-				for _, s := range sf.SQLs {
+				pos := make([]stmtPos, 0, len(sf.SQLs))
+				for j, s := range sf.SQLs {
 					if _, ok := mem[s]; ok {
 						continue
 					}
 					sqls = append(sqls, s)
+					pos = append(pos, sf.pos(j))
 				}
 				sf.SQLs = sqls
+				sf.Pos = pos
 			} else if VerEq(sf.Name, last) {
 				// schema_script_sql is emtpy, and the version is equal,
 				// we should just skip the script, the script has been executed already,
@@ -192,75 +780,210 @@ func MigrateSchema(db *gorm.DB, log Logger, c MigrateConfig) error {
 			continue
 		}
 
-		if len(sf.SQLs) > 0 {
-			if err := runSQLFile(db, log, c.App, sf.SQLs, sf.Name); err != nil {
-				return fmt.Errorf("failed to exec sql file %v, %w", sf.Name, err)
+		if len(sf.SQLs) > 0 || sf.Empty {
+			pending = append(pending, sf)
+		}
+	}
+	if c.StrictEmptyScripts {
+		if err := verifyNotEmpty(pending); err != nil {
+			return res, err
+		}
+	}
+
+	if c.ReorderForeignKeys {
+		for i, sf := range pending {
+			reordered, changed, err := reorderForeignKeys(sf)
+			if err != nil {
+				return res, err
+			}
+			if changed {
+				log.Infof("Reordered CREATE TABLE statements in %v by foreign keys", sf.Name)
 			}
+			pending[i] = reordered
 		}
 	}
-	return nil
+
+	if c.CompatPolicy != nil {
+		if err := c.CompatPolicy.verify(pending, last); err != nil {
+			return res, err
+		}
+	}
+
+	for _, sf := range pending {
+		sr, err := runSQLFile(db, meta, log, c, sf)
+		res.add(sr)
+		if err != nil {
+			if c.continuesOnFailure(sf, err) {
+				if err := continueAfterFailure(meta, log, c, sf, err, &res); err != nil {
+					return res, err
+				}
+				continue
+			}
+			return res, fmt.Errorf("failed to exec sql file %v, %w", sf.Name, err)
+		}
+	}
+	return runRepeatables(db, meta, log, c, runAlways, res)
+}
+
+// Sync the repeatable objects (e.g., views), and then run the scripts marked with '-- svc:run-always'.
+func runRepeatables(db *gorm.DB, meta *stmtCache, log Logger, c MigrateConfig, runAlways []schemaFile, res Result) (Result, error) {
+	res, err := syncObjects(db, log, c, res)
+	if err != nil {
+		return res, err
+	}
+	return runAlwaysFiles(db, meta, log, c, runAlways, res)
+}
+
+// Run the scripts marked with '-- svc:run-always', these are executed on every migration after the versioned scripts.
+func runAlwaysFiles(db *gorm.DB, meta *stmtCache, log Logger, c MigrateConfig, files []schemaFile, res Result) (Result, error) {
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	for _, sf := range files {
+		sr, err := runSQLFile(db, meta, log, c, sf)
+		res.add(sr)
+		if err != nil {
+			if c.continuesOnFailure(sf, err) {
+				if err := continueAfterFailure(meta, log, c, sf, err, &res); err != nil {
+					return res, err
+				}
+				continue
+			}
+			return res, fmt.Errorf("failed to exec sql file %v, %w", sf.Name, err)
+		}
+	}
+	return res, nil
 }
 
+// Sort schema files by versions, the versions are parsed once before sorting.
 func sortSchemaFile(entries []schemaFile) {
-	sort.Slice(entries, func(i, j int) bool {
-		fi := entries[i]
-		fj := entries[j]
-		return VerAfter(fj.Name, fi.Name)
-	})
+	sort.Sort(schemaFilesByVer{entries: entries, vers: parseVers(entries)})
+}
+
+func parseVers(entries []schemaFile) []Version {
+	vers := make([]Version, len(entries))
+	for i, sf := range entries {
+		vers[i] = ParseVer(sf.Name)
+	}
+	return vers
+}
+
+// Schema files sorted by the pre-parsed versions.
+type schemaFilesByVer struct {
+	entries []schemaFile
+	vers    []Version
+}
+
+func (s schemaFilesByVer) Len() int           { return len(s.entries) }
+func (s schemaFilesByVer) Less(i, j int) bool { return s.vers[i].Compare(s.vers[j]) < 0 }
+func (s schemaFilesByVer) Swap(i, j int) {
+	s.entries[i], s.entries[j] = s.entries[j], s.entries[i]
+	s.vers[i], s.vers[j] = s.vers[j], s.vers[i]
 }
 
 type schemaFile struct {
-	Name string
-	Path string
-	SQLs []string
+	Name    string
+	Path    string
+	SQLs    []string
+	Asserts []assertion
+
+	// Preconditions checked before the statements are executed, declared with '-- svc:check'.
+	Checks []check
+
+	// Script is executed on every migration, marked with '-- svc:run-always'.
+	RunAlways bool
+
+	// Names of the objects that the repeatable object depends on, declared with '-- svc:depends-on'.
+	DependsOn []string
+
+	// Database that the statements are executed in, declared with '-- svc:database'. If absent, the statements
+	// are executed in the current database.
+	Database string
+
+	// Positions of the statements in SQLs.
+	Pos []stmtPos
+
+	// Clauses appended to the ALTER TABLE statements, declared with '-- svc:online-alter [clauses]'.
+	OnlineAlter string
+
+	// Batching of the statements, declared with '-- svc:resumable'.
+	Resumable *resumable
+
+	// Failure of the script doesn't stop the migration, declared with '-- svc:optional'.
+	Optional bool
+
+	// Table family that the statements are expanded for, declared with '-- svc:canary'.
+	Canary *canary
+
+	// Table families that the statements are expanded for, declared with '-- svc:foreach'.
+	Foreach []foreach
+
+	// Statements in the '-- migrate:down' section, used for rollback.
+	Down []string
+
+	// Version that the baseline script bootstraps, declared with '-- svc:baseline'.
+	Baseline string
+
+	// Version in which the dropped or renamed columns / tables were deprecated, declared with '-- svc:deprecated'.
+	Deprecated string
+
+	// Author and ticket of the script, declared with the '-- author:' and '-- ticket:' header comments.
+	Author string
+	Ticket string
+
+	// The script contains no statement, e.g., the content is whitespace only, or it's all commented out. It's
+	// determined when the script is parsed, the statements skipped by the checkpoint don't make the script empty.
+	Empty bool
 }
 
-func convertSchemaFiles(last string, files []fs.DirEntry, baseDir string, fs ReadFS) ([]schemaFile, error) {
-	filtered := make([]schemaFile, 0, len(files))
-	for _, f := range files {
-		if !f.Type().IsRegular() {
-			continue
-		}
-		name := strings.ToLower(f.Name())
-		if !strings.HasSuffix(name, ".sql") {
-			continue
-		}
-		if isExcluded(name) {
-			continue
-		}
+// Position of the i-th statement in SQLs.
+func (sf schemaFile) pos(i int) stmtPos {
+	if i < len(sf.Pos) {
+		return sf.Pos[i]
+	}
+	return stmtPos{Index: i + 1}
+}
 
-		if last != "" && !VerAfterEq(name, last) {
-			continue
-		}
+func (sf schemaFile) kind() string {
+	if sf.RunAlways {
+		return kindRunAlways
+	}
+	return kindVersioned
+}
 
-		path := baseDir + "/" + name
-		buf, err := fs.ReadFile(path)
+// Read schema files, returns the versioned ones that are after (or equal to) the last version, and the run-always ones.
+func convertSchemaFiles(last string, files []sourceFile, fs ReadFS, s server) (versioned []schemaFile, runAlways []schemaFile, err error) {
+	versioned = make([]schemaFile, 0, len(files))
+	for _, f := range files {
+		sf, err := readSchemaFile(fs, f, s)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fs.ReadFile, %v, %w", path, err)
+			return nil, nil, err
 		}
-
-		contentStr := string(buf)
-		segments := strings.Split(contentStr, ";")
-
-		sqls := []string{}
-		for _, seg := range segments {
-			seg = strings.TrimSpace(seg)
-			if seg == "" {
-				continue
-			}
-			sqls = append(sqls, seg)
+		if sf.RunAlways {
+			runAlways = append(runAlways, sf)
+			continue
 		}
-		if len(sqls) < 1 {
+		if last != "" && !VerAfterEq(sf.Name, last) {
 			continue
 		}
+		versioned = append(versioned, sf)
+	}
+	return versioned, runAlways, nil
+}
 
-		filtered = append(filtered, schemaFile{
-			Name: name,
-			Path: path,
-			SQLs: sqls,
-		})
+func readSchemaFile(fs ReadFS, f sourceFile, s server) (schemaFile, error) {
+	path := f.Path
+	buf, err := fs.ReadFile(path)
+	if err != nil {
+		return schemaFile{}, fmt.Errorf("failed to fs.ReadFile, %v, %w", path, err)
 	}
-	return filtered, nil
+
+	sf, err := parseScript(string(buf), s)
+	if err != nil {
+		return schemaFile{}, fmt.Errorf("failed to parse %v, %w", path, err)
+	}
+	sf.Name = f.Name
+	sf.Path = path
+	sf.Author, sf.Ticket = parseHeader(string(buf))
+	return sf, nil
 }
 
 type schemaVersion struct {
@@ -270,55 +993,437 @@ type schemaVersion struct {
 	Remark  string
 }
 
-func runSQLFile(db *gorm.DB, log Logger, app string, segments []string, fname string) error {
-	total := 0
-	for i, sql := range segments {
+// Execute the script, the progress is reported to MigrateConfig.Reporter.
+func runSQLFile(db *gorm.DB, meta *stmtCache, log Logger, c MigrateConfig, sf schemaFile) (ScriptResult, error) {
+	if c.Reporter == nil {
+		return execAuthoredSQLFile(db, meta, log, c, sf)
+	}
+	clock := clockOrDefault(c.Clock)
+	start := clock.Now()
+	c.Reporter.ScriptStarted(sf.Name)
+	sr, err := execAuthoredSQLFile(db, meta, log, c, sf)
+	c.Reporter.ScriptFinished(sr, clock.Now().Sub(start), err)
+	return sr, err
+}
+
+// Execute the script, and then record its author and ticket in schema_version regardless of the result.
+func execAuthoredSQLFile(db *gorm.DB, meta *stmtCache, log Logger, c MigrateConfig, sf schemaFile) (ScriptResult, error) {
+	sr, err := execSQLFile(db, meta, log, c, sf)
+	if !c.DryRun {
+		if er := saveAuthorship(meta, c.App, sf); er != nil {
+			log.Errorf("failed to save author of %v, %v", sf.Name, er)
+		}
+	}
+	return sr, err
+}
+
+// Statement to be executed, i.e., with the placeholders resolved, and rewritten by RewriteIfNotExists and the
+// online alter clauses.
+func (c MigrateConfig) rewriteStmt(sql string, placeholders map[string]string, online string) string {
+	stmt := resolvePlaceholders(sql, placeholders)
+	if c.RewriteIfNotExists {
+		stmt = rewriteIfNotExists(stmt, c.srv.flavor)
+	}
+	if online != "" {
+		stmt = appendAlterClauses(stmt, online)
+	}
+	return stmt
+}
+
+func execSQLFile(db *gorm.DB, meta *stmtCache, log Logger, c MigrateConfig, sf schemaFile) (ScriptResult, error) {
+	app, fname, kind := c.App, sf.Name, sf.kind()
+	sr := ScriptResult{Script: fname, Empty: sf.Empty}
+	if _, ok := FileVer(fname); ok && !sf.RunAlways {
+		sr.Version = ParseVer(fname)
+	}
+	if sr.Empty {
+		log.Infof("Script %v contains no statement, it's recorded as applied", fname)
+	}
+	if c.DryRun {
+		for i, sql := range sf.SQLs {
+			stmt := resolvePlaceholders(sql, c.Placeholders)
+			sr.Planned = append(sr.Planned, planStatement(stmt))
+			log.Infof("[dry-run] '%v' - pending [%v] (%v): \n\n%v\n", fname, i+1, sr.Planned[i].Category, stmt)
+		}
+		sr.Statements = len(sf.SQLs)
+		return sr, nil
+	}
+
+	database := resolvePlaceholders(sf.Database, c.Placeholders)
+	online := c.OnlineAlter
+	if sf.OnlineAlter != "" {
+		online = sf.OnlineAlter
+	}
+	if online != "" && c.srv.dialect.Name() != DialectMySQL {
+		log.Infof("'%v' - online ALTER is not supported by %v dialect, ignored", fname, c.srv.dialect.Name())
+		online = ""
+	}
+	cph, err := c.stmtCipher()
+	if err != nil {
+		return sr, err
+	}
+
+	// nothing is executed if the checks fail, the script remains pending
+	for _, ck := range sf.Checks {
+		if err := runScriptCheck(db, log, c, c.srv.dialect, sf, ck); err != nil {
+			return sr, err
+		}
+	}
+	for i, sql := range sf.SQLs {
+		if err := c.Throttle.wait(meta.ctx, log, fname, i+1); err != nil {
+			se := newScriptError(sf, i, resolvePlaceholders(sql, c.Placeholders), err)
+			if er := saveSchemaVerThrottled(meta, app, fname, kind, err); er != nil {
+				log.Errorf("failed to save schema_version, %v", er)
+			}
+			return sr, se
+		}
+
+		stmt := c.rewriteStmt(sql, c.Placeholders, online)
 
 		// record the sql has been executed regardless of the result, if this statement fails
 		// the simplest way to fix the migration is to fix this specific statment manully,
 		// and update schema_version.success to '1', and then continue
-		if err := db.Exec(`INSERT INTO schema_script_sql (app, script, stmt) VALUES (?,?,?)`,
-			app, fname, sql).Error; err != nil {
-			return fmt.Errorf("failed to save schema_script_sql, %v", err)
+		undo, reversible := deriveUndo(c.srv.dialect, sql)
+		if reversible && undoIfNotExistsRegex.MatchString(stmt) {
+			// rewritten to IF NOT EXISTS, the object may have existed before
+			reversible = false
+		}
+		var undoStmt any
+		if reversible {
+			undoStmt = undo
+		}
+		recorded, err := cph.encrypt(app, fname, sql)
+		if err != nil {
+			return sr, err
+		}
+		r, err := meta.exec(`INSERT INTO schema_script_sql (app, script, stmt, undo_stmt, reversible, created_at) VALUES (?,?,?,?,?,?)`,
+			meta.app(app), fname, recorded, undoStmt, reversible, meta.timeArg(meta.now()))
+		if err != nil {
+			return sr, fmt.Errorf("failed to save schema_script_sql, %v", err)
+		}
+		sqlId, err := r.LastInsertId()
+		if err != nil {
+			// LastInsertId is not supported by some drivers (e.g., Oracle), svc runs the migration sequentially,
+			// the latest record is the one just inserted
+			if _, er := meta.queryRow(`SELECT MAX(id) FROM schema_script_sql WHERE app = ? and script = ?`+meta.forUpdate(),
+				[]any{meta.app(app), fname}, &sqlId); er != nil {
+				return sr, fmt.Errorf("failed to obtain schema_script_sql id, %v", er)
+			}
 		}
 
-		if err := db.Exec(sql).Error; err != nil {
-			if er := saveSchemaVer(db, app, fname, false, err.Error()); er != nil {
+		// the statement (and SHOW WARNINGS) runs in the script's database, svc's own tables are in the original one
+		var rowsAffected int64
+		var w []sqlWarning
+		var warnErr error
+		var satisfied bool
+		var existing createdObject
+		err = withDatabase(db, c.srv.dialect, database, func(conn *gorm.DB) error {
+			var err error
+			if c.SmartSkip {
+				if existing, satisfied, err = alreadySatisfied(conn, stmt); err != nil || satisfied {
+					return err
+				}
+			}
+			if sf.Resumable != nil && isBatched(stmt) {
+				rowsAffected, err = runBatches(conn, meta, log, c, sf, i, stmt)
+			} else {
+				rowsAffected, err = executorOrDefault(c.Executor).Exec(conn, Statement{Script: fname, Index: sf.pos(i).Index, SQL: stmt})
+			}
+			if err == nil && (c.CaptureWarnings || c.StrictSQLMode) {
+				w, warnErr = queryWarnings(conn)
+			}
+			return err
+		})
+		if err != nil {
+			if c.Adopt && c.srv.dialect.IsAlreadyApplied(err) {
+				log.Infof("'%v' - [%v] already applied, treated as no-op (adopt mode): %v", fname, i+1, err)
+
+				// the object existed before, it's not undone in rollback
+				if _, err := meta.exec(`UPDATE schema_script_sql SET rows_affected = ?, undo_stmt = NULL, reversible = ? WHERE id = ?`,
+					0, false, sqlId); err != nil {
+					log.Errorf("failed to update schema_script_sql, %v", err)
+				}
+				sr.Statements += 1
+				continue
+			}
+			if online != "" && isOnlineAlterRejected(err) {
+				err = fmt.Errorf("online ALTER (%v) rejected by the server, %w", online, err)
+			}
+			if sf.Canary.isCanary(sql) {
+				err = fmt.Errorf("canary %v failed, the other %d tables are not changed, %w", sf.Canary.Table, len(sf.Canary.Tables)-1, err)
+			}
+			se := newScriptError(sf, i, stmt, err)
+			if isThrottleFailure(err) {
+				// the batches executed are saved in the cursor
+				if er := saveSchemaVerThrottled(meta, app, fname, kind, err); er != nil {
+					log.Errorf("failed to save schema_version, %v", er)
+				}
+				return sr, se
+			}
+			if er := saveSchemaVerFailure(meta, app, fname, kind, se.Index, err); er != nil {
 				log.Errorf("failed to save schema_version, %v", er)
 			}
-			return fmt.Errorf("failed to execute script, '%v', %w", sql, err)
+			return sr, se
+		} else if satisfied {
+			log.Infof("'%v' - [%v] skipped, %v already exists (smart-skip)", fname, i+1, existing)
+
+			// the object existed before, it's not undone in rollback
+			if _, err := meta.exec(`UPDATE schema_script_sql SET rows_affected = ?, skipped = ?, undo_stmt = NULL, reversible = ? WHERE id = ?`,
+				0, true, false, sqlId); err != nil {
+				log.Errorf("failed to update schema_script_sql, %v", err)
+			}
+			sr.Statements += 1
+			sr.Skipped += 1
+			continue
 		} else {
-			log.Infof("'%v' - executed [%v]: \n\n%v\n", fname, i+1, sql)
+			log.Infof("'%v' - executed [%v], rows affected: %v \n\n%v\n", fname, i+1, rowsAffected, stmt)
+			if c.Publisher != nil {
+				if ch, ok := newSchemaChange(fname, c.rewriteStmt(sql, c.recordedPlaceholders(), online)); ok {
+					sr.changes = append(sr.changes, ch)
+				}
+			}
+			if sf.Canary.isCanary(sql) {
+				log.Infof("'%v' - canary %v succeeded, fanning out to the other %d tables", fname, sf.Canary.Table, len(sf.Canary.Tables)-1)
+			}
+		}
+
+		var warnings any
+		if c.CaptureWarnings || c.StrictSQLMode {
+			if warnErr != nil {
+				return sr, warnErr
+			}
+			if c.CaptureWarnings {
+				ws := make([]string, 0, len(w))
+				for _, s := range w {
+					log.Infof("'%v' - [%v] %v", fname, i+1, s)
+					ws = append(ws, s.String())
+				}
+				if len(ws) > 0 {
+					warnings = strings.Join(ws, "\n")
+					sr.Warnings = append(sr.Warnings, ws...)
+				}
+			}
+			if c.StrictSQLMode {
+				if tw, ok := findTruncation(w); ok {
+					err := fmt.Errorf("data truncated (strict sql_mode), %v", tw)
+					se := newScriptError(sf, i, stmt, err)
+					if er := saveSchemaVerFailure(meta, app, fname, kind, se.Index, err); er != nil {
+						log.Errorf("failed to save schema_version, %v", er)
+					}
+					return sr, se
+				}
+			}
+		}
+
+		if _, err := meta.exec(`UPDATE schema_script_sql SET rows_affected = ?, warnings = ? WHERE id = ?`,
+			rowsAffected, warnings, sqlId); err != nil {
+			log.Errorf("failed to update schema_script_sql, %v", err)
+		}
+		sr.Statements += 1
+		sr.RowsAffected += rowsAffected
+	}
+
+	for _, a := range sf.Asserts {
+		a.Query = resolvePlaceholders(a.Query, c.Placeholders)
+		if err := withDatabase(db, c.srv.dialect, database, func(conn *gorm.DB) error { return runAssertion(conn, a) }); err != nil {
+			if er := saveSchemaVerFailure(meta, app, fname, kind, 0, err); er != nil {
+				log.Errorf("failed to save schema_version, %v", er)
+			}
+			return sr, err
+		}
+		log.Infof("'%v' - assertion passed: '%v' = %v", fname, a.Query, a.Expected)
+	}
+
+	if c.RequiredCharset != "" || c.RequiredCollation != "" {
+		resolved := make([]string, 0, len(sf.SQLs))
+		for _, sql := range sf.SQLs {
+			resolved = append(resolved, resolvePlaceholders(sql, c.Placeholders))
+		}
+		if err := checkTableCharset(db, c, database, resolved); err != nil {
+			if er := saveSchemaVerFailure(meta, app, fname, kind, 0, err); er != nil {
+				log.Errorf("failed to save schema_version, %v", er)
+			}
+			return sr, err
+		}
+	}
+	if sf.Resumable != nil {
+		if err := clearCursors(meta, app, fname); err != nil {
+			log.Errorf("%v", err)
 		}
-		total += 1
 	}
 	log.Infof("Script %v completed", fname)
 
-	if er := saveSchemaVer(db, app, fname, true, "Executed"); er != nil {
+	remark := c.remark(RemarkExecuted, sf, "Executed")
+	if sr.Empty {
+		remark = c.remark(RemarkEmpty, sf, emptyScriptRemark)
+	}
+	if er := saveSchemaVer(meta, app, fname, kind, true, remark); er != nil {
 		log.Errorf("failed to save schema_version, %v, %v", fname, er)
 	}
+	return sr, nil
+}
+
+func runAssertion(db *gorm.DB, a assertion) error {
+	var actual string
+	t := db.Raw(a.Query).Scan(&actual)
+	if t.Error != nil {
+		return fmt.Errorf("failed to execute assertion '%v', %w", a.Query, t.Error)
+	}
+	if t.RowsAffected < 1 {
+		return fmt.Errorf("assertion failed, '%v' returned no rows, expected: %v", a.Query, a.Expected)
+	}
+	if actual != a.Expected {
+		return fmt.Errorf("assertion failed, '%v' returned %v, expected: %v", a.Query, actual, a.Expected)
+	}
 	return nil
 }
 
-func saveSchemaVer(db *gorm.DB, app string, script string, success bool, remark string) error {
+func saveSchemaVer(meta *stmtCache, app string, script string, kind string, success bool, remark string) error {
+	return saveSchemaVerDetail(meta, app, script, kind, success, remark, "", 0)
+}
+
+// Save failed schema_version, the remark is truncated, but the full error is kept in error_detail.
+//
+// stmt is the 1-based index of the failed statement, 0 if the failure is not caused by a statement, e.g., assertion.
+func saveSchemaVerFailure(meta *stmtCache, app string, script string, kind string, stmt int, err error) error {
+	return saveSchemaVerDetail(meta, app, script, kind, false, err.Error(), err.Error(), stmt)
+}
+
+// Truncate remark to fit in schema_version.remark.
+func truncateRemark(remark string) string {
 	rrm := []rune(remark)
 	if len(rrm) > 255 {
 		rrm = rrm[:255]
 	}
+	return string(rrm)
+}
+
+func saveSchemaVerDetail(meta *stmtCache, app string, script string, kind string, success bool, remark string,
+	detail string, stmt int) error {
 
-	// update schema_verion
-	var id int
-	t := db.Raw(`SELECT id FROM schema_version WHERE app = ? and script = ? LIMIT 1`, app, script).Scan(&id)
-	if err := t.Error; err != nil {
+	rrm := truncateRemark(remark)
+	var errorDetail, failedStmt any
+	if detail != "" {
+		errorDetail = detail
+	}
+	if stmt > 0 {
+		failedStmt = stmt
+	}
+
+	// run-always scripts have their own history entry for each execution
+	if kind == kindRunAlways {
+		now := meta.now()
+		_, err := meta.exec(`INSERT INTO schema_version (app, script, kind, success, remark, error_detail, failed_stmt, created_at, record_uid)
+			VALUES (?,?,?,?,?,?,?,?,?)`, meta.app(app), script, kind, success, rrm, errorDetail, failedStmt, meta.timeArg(now), meta.ids.NewID(now))
 		return err
 	}
-	if t.RowsAffected > 0 {
-		return db.Exec(`UPDATE schema_version SET success = ?, remark = ? WHERE id = ?`, success, string(rrm), id).Error
+
+	// update schema_verion, the id of the record saved in this run is cached, it's not read again
+	key := app + "/" + script + "/" + kind
+	id, found := meta.versionIds[key]
+	if !found {
+		var err error
+		found, err = meta.queryRow(`SELECT id FROM schema_version WHERE app = ? and script = ? and kind = ? `+
+			meta.dialect.LimitOne()+meta.forUpdate(), []any{meta.app(app), script, kind}, &id)
+		if err != nil {
+			return err
+		}
+	}
+	if found {
+		meta.versionIds[key] = id
+		_, err := meta.exec(`UPDATE schema_version SET success = ?, remark = ?, error_detail = ?, failed_stmt = ? WHERE id = ?`,
+			success, rrm, errorDetail, failedStmt, id)
+		return err
 	}
 
 	// save new schema_verion
-	return db.Exec(`INSERT INTO schema_version (app, script, success, remark) VALUES (?,?,?,?)`,
-		app, script, success, string(rrm)).Error
+	now := meta.now()
+	r, err := meta.exec(`INSERT INTO schema_version (app, script, kind, success, remark, error_detail, failed_stmt, created_at, record_uid)
+		VALUES (?,?,?,?,?,?,?,?,?)`, meta.app(app), script, kind, success, rrm, errorDetail, failedStmt, meta.timeArg(now), meta.ids.NewID(now))
+	if err != nil {
+		return err
+	}
+	if id, err := r.LastInsertId(); err == nil {
+		meta.versionIds[key] = id
+	}
+	return nil
+}
+
+// Script file found in BaseDir.
+type sourceFile struct {
+	// Lower-cased file name, e.g., v0.0.1.sql.
+	Name string
+
+	// Path of the file in ReadFS.
+	Path string
+}
+
+// Discover the .sql files that are not excluded, the error of reading dir is returned as is.
+func discoverFiles(fsys ReadFS, dir string, recursive bool, excluded func(name string) bool) ([]sourceFile, error) {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []sourceFile{}
+	seen := map[string]string{}
+	var walk func(dir string, entries []fs.DirEntry, top bool) error
+	walk = func(dir string, entries []fs.DirEntry, top bool) error {
+		for _, e := range entries {
+			fpath := path.Join(dir, e.Name())
+			if top && strings.EqualFold(e.Name(), baselineFile) {
+				continue
+			}
+			if e.IsDir() {
+				if !recursive || (top && isObjectDir(e.Name())) {
+					continue
+				}
+				sub, err := fsys.ReadDir(fpath)
+				if err != nil {
+					return fmt.Errorf("failed to open %v folders, %w", fpath, err)
+				}
+				if err := walk(fpath, sub, false); err != nil {
+					return err
+				}
+				continue
+			}
+			if !e.Type().IsRegular() {
+				continue
+			}
+			name := strings.ToLower(e.Name())
+			if !strings.HasSuffix(name, ".sql") || excluded(name) {
+				continue
+			}
+			if prev, ok := seen[name]; ok {
+				return fmt.Errorf("found duplicate script name %v, %v and %v", name, prev, fpath)
+			}
+			seen[name] = fpath
+			files = append(files, sourceFile{Name: name, Path: fpath})
+		}
+		return nil
+	}
+	if err := walk(dir, entries, true); err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// List names of the discovered files.
+func listSQLFiles(files []sourceFile) []string {
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Path)
+	}
+	return names
+}
+
+// Change the default value of MigrateConfig.RequireSource.
+//
+// If enabled, the migration fails when BaseDir is missing or contains zero .sql files, unless
+// MigrateConfig.RequireSource is set to false explicitly.
+func SetRequireSource(require bool) {
+	requireSource = require
 }
 
 func ExcludeFile(name string) {
@@ -330,3 +1435,65 @@ func isExcluded(name string) bool {
 	_, ok := excluded[name]
 	return ok
 }
+
+// Statements of the last script that have a saved cursor, nil if the script has been recorded in schema_version,
+// i.e., it's not interrupted, the failed ones may have been fixed manually.
+func unfinishedStmts(db *gorm.DB, c MigrateConfig, sf schemaFile, lastVer *schemaVersion) (map[string]struct{}, error) {
+	if lastVer != nil && VerEq(lastVer.Script, sf.Name) {
+		return nil, nil
+	}
+	var cursors []int
+	if err := db.Raw(c.ns().rewrite(`SELECT stmt_index FROM schema_cursor WHERE app = ? AND script = ?`+c.forUpdate()),
+		c.appArg(db), sf.Name).Scan(&cursors).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schema_cursor, %w", err)
+	}
+	saved := map[int]struct{}{}
+	for _, idx := range cursors {
+		saved[idx] = struct{}{}
+	}
+	unfinished := map[string]struct{}{}
+	for j, s := range sf.SQLs {
+		if _, ok := saved[sf.pos(j).Index]; ok {
+			unfinished[s] = struct{}{}
+		}
+	}
+	return unfinished, nil
+}
+
+// Skip the statements of the failed script that were executed successfully (rows_affected is recorded), the records of
+// the failed statements are removed, they are recorded again when retried.
+func checkpoint(db *gorm.DB, c MigrateConfig, sf schemaFile) (schemaFile, error) {
+	var executed []string
+	if err := db.Raw(c.ns().rewrite(`SELECT stmt FROM schema_script_sql WHERE app = ? AND script = ? AND rows_affected IS NOT NULL`+c.forUpdate()),
+		c.appArg(db), sf.Name).Scan(&executed).Error; err != nil {
+		return sf, fmt.Errorf("failed to list schema_script_sql, %w", err)
+	}
+	cph, err := c.stmtCipher()
+	if err != nil {
+		return sf, err
+	}
+	if err := cph.decryptAll(c.App, sf.Name, executed); err != nil {
+		return sf, err
+	}
+	if !c.DryRun {
+		if err := db.Exec(c.ns().rewrite(`DELETE FROM schema_script_sql WHERE app = ? AND script = ? AND rows_affected IS NULL`),
+			c.appArg(db), sf.Name).Error; err != nil {
+			return sf, fmt.Errorf("failed to delete schema_script_sql, %w", err)
+		}
+	}
+	done := map[string]struct{}{}
+	for _, s := range executed {
+		done[s] = struct{}{}
+	}
+	sqls := make([]string, 0, len(sf.SQLs))
+	pos := make([]stmtPos, 0, len(sf.SQLs))
+	for i, s := range sf.SQLs {
+		if _, ok := done[s]; ok {
+			continue
+		}
+		sqls = append(sqls, s)
+		pos = append(pos, sf.pos(i))
+	}
+	sf.SQLs, sf.Pos = sqls, pos
+	return sf, nil
+}
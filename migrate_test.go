@@ -1,10 +1,14 @@
 package svc
 
 import (
+	"database/sql/driver"
 	"embed"
 	"fmt"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -38,3 +42,150 @@ func TestMigrate(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestIsAlreadyApplied(t *testing.T) {
+	if !isAlreadyApplied(fmt.Errorf("wrapped, %w", &mysqldriver.MySQLError{Number: 1060, Message: "Duplicate column name 'name'"})) {
+		t.Fatal("should return true")
+	}
+	if isAlreadyApplied(&mysqldriver.MySQLError{Number: 1146, Message: "Table 'tt.t' doesn't exist"}) {
+		t.Fatal("should return false")
+	}
+}
+
+func TestDiscoverFiles(t *testing.T) {
+	mfs := fstest.MapFS{
+		"schema/v0.0.1.sql":             {Data: []byte("SELECT 1;")},
+		"schema/2024/v0.0.2.sql":        {Data: []byte("SELECT 2;")},
+		"schema/2024/module/V0.0.3.SQL": {Data: []byte("SELECT 3;")},
+		"schema/views/v_user.sql":       {Data: []byte("CREATE VIEW v_user AS SELECT 1;")},
+		"schema/readme.md":              {Data: []byte("")},
+	}
+
+	files, err := discoverFiles(mfs, "schema", false, isExcluded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "v0.0.1.sql" {
+		t.Fatalf("incorrect files, %+v", files)
+	}
+
+	files, err = discoverFiles(mfs, "schema", true, isExcluded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("should be 3, %+v", files)
+	}
+	for _, f := range files {
+		if f.Name == "v0.0.3.sql" && f.Path != "schema/2024/module/V0.0.3.SQL" {
+			t.Fatalf("incorrect path, %+v", f)
+		}
+	}
+
+	mfs["schema/2025/v0.0.1.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	if _, err := discoverFiles(mfs, "schema", true, isExcluded); err == nil {
+		t.Fatal("should return error")
+	}
+}
+
+func TestForUpdate(t *testing.T) {
+	if s := (MigrateConfig{}).forUpdate(); s != "" {
+		t.Fatalf("should be empty, %v", s)
+	}
+	if s := (MigrateConfig{PrimaryReads: true}).forUpdate(); s != " FOR UPDATE" {
+		t.Fatalf("should be FOR UPDATE, %v", s)
+	}
+}
+
+func TestGuardPoolDatabaseClock(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT UTC_TIMESTAMP\(3\)$`, []string{"now"}, []driver.Value{"2024-03-01 08:30:00"})
+	db := f.open(t)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Run(db, PrintLogger{}, MigrateConfig{
+			App:       "test",
+			Fs:        fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);")}},
+			BaseDir:   "schema",
+			Clock:     DatabaseClock(db),
+			GuardPool: true,
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("migration should not wait for the guarded pool")
+	}
+	want := time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)
+	if run := f.executed(`^INSERT INTO schema_run`); len(run) != 1 || run[0].Args[2] != want.Format(bindTimeLayout) {
+		t.Fatalf("ended_at should be the time of the database, %+v", run)
+	}
+	sqlDb, _ := db.DB()
+	if n := sqlDb.Stats().MaxOpenConnections; n != 0 {
+		t.Fatalf("pool should be restored, max open connections: %v", n)
+	}
+}
+
+func TestRequireSource(t *testing.T) {
+	SetRequireSource(true)
+	defer SetRequireSource(false)
+
+	run := func(opts ...Option) error {
+		f := &fakeDB{}
+		f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+		c := MigrateConfig{App: "test", Fs: fstest.MapFS{}, BaseDir: "schema"}
+		for _, op := range opts {
+			op(&c)
+		}
+		_, err := Run(f.open(t), PrintLogger{}, c)
+		return err
+	}
+	if err := run(); err == nil {
+		t.Fatal("should fail, schema directory is required by default")
+	}
+	if err := run(WithRequireSource(false)); err != nil {
+		t.Fatalf("config should override the default, %v", err)
+	}
+	SetRequireSource(false)
+	if err := run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := run(WithRequireSource(true)); err == nil {
+		t.Fatal("should fail, schema directory is required")
+	}
+}
+
+func TestSkipFailedLastScript(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT id, script, success, remark FROM schema_version`, []string{"id", "script", "success", "remark"},
+		[]driver.Value{int64(5), "v0.0.2.sql", false, "Table 'b' already exists"})
+	f.reply(`^SELECT stmt FROM schema_script_sql`, []string{"stmt"}, []driver.Value{"CREATE TABLE a (id INT)"})
+	res, err := Run(f.open(t), PrintLogger{}, MigrateConfig{
+		App: "test",
+		Fs: fstest.MapFS{
+			"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+			"schema/v0.0.2.sql": {Data: []byte("CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\nCREATE TABLE c (id INT);")},
+		},
+		BaseDir:           "schema",
+		OnPreviousFailure: SkipFailed,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^CREATE TABLE [abc] `); len(q) > 0 {
+		t.Fatalf("statements of the skipped script should not be executed, %+v", q)
+	}
+	if len(res.Scripts) > 0 {
+		t.Fatalf("no script should be executed, %+v", res.Scripts)
+	}
+	if q := f.executed(`^UPDATE schema_version SET success = \?, remark = \? WHERE id = \?`); len(q) != 1 || q[0].Args[2] != int64(5) {
+		t.Fatalf("failed script should be marked as skipped, %+v", q)
+	}
+}
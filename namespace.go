@@ -0,0 +1,87 @@
+package svc
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultNamespace = "svc"
+)
+
+var (
+	// default value of MigrateConfig.Namespace
+	namespace = Namespace(defaultNamespace)
+
+	metaTableNames = []string{"schema_version", "schema_script_sql", "schema_object", "schema_run", "schema_cursor"}
+
+	// names of svc's own tables in the queries, including the derived names, e.g., schema_run_app_idx
+	metaTableRegex = regexp.MustCompile(`\bschema_(?:version|script_sql|object|run|cursor)`)
+)
+
+// Namespace of the identifiers owned by svc, so that independent tools embedding svc in the same database
+// don't collide with each other.
+//
+// For the default namespace 'svc', the tables are named schema_version, schema_script_sql, etc., the migration
+// lock is named 'svc:<app>' and the directives are prefixed with 'svc:', e.g., '-- svc:assert'. For other
+// namespaces, e.g., 'billing', the tables are prefixed with the namespace (billing_schema_version), the lock is
+// named 'billing:<app>' and the directives are prefixed with 'billing:', e.g., '-- billing:assert'.
+type Namespace string
+
+func (ns Namespace) name() string {
+	if ns == "" {
+		return defaultNamespace
+	}
+	return strings.ToLower(string(ns))
+}
+
+// Name of svc's own table in the namespace, e.g., Namespace("billing").Table("schema_version") returns
+// billing_schema_version.
+func (ns Namespace) Table(name string) string {
+	if ns.name() == defaultNamespace {
+		return name
+	}
+	return ns.name() + "_" + name
+}
+
+// Rewrite the names of svc's own tables in the query.
+func (ns Namespace) rewrite(query string) string {
+	if ns.name() == defaultNamespace {
+		return query
+	}
+	return metaTableRegex.ReplaceAllStringFunc(query, ns.Table)
+}
+
+// Name of the migration lock of the app.
+func (ns Namespace) lockName(app string) string {
+	return ns.name() + ":" + app
+}
+
+// Prefix of the directives, e.g., 'svc:'.
+func (ns Namespace) directivePrefix() string {
+	return ns.name() + ":"
+}
+
+// Namespace used, it's the one set by SetNamespace if MigrateConfig.Namespace is absent.
+func (c MigrateConfig) ns() Namespace {
+	if c.Namespace != "" {
+		return c.Namespace
+	}
+	return namespace
+}
+
+// Change the default namespace, see Namespace. It's also used by the functions that don't take MigrateConfig,
+// e.g., History, LastRun and IgnoreScript.
+func SetNamespace(ns string) {
+	namespace = Namespace(ns)
+}
+
+// Check if the table is one of svc's own tables (in any namespace).
+func isMetaTable(name string) bool {
+	for _, t := range metaTableNames {
+		if name == t || strings.HasSuffix(name, "_"+t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package svc
+
+import "testing"
+
+func TestNamespace(t *testing.T) {
+	var def Namespace
+	if def.Table("schema_version") != "schema_version" || def.lockName("myapp") != "svc:myapp" || def.directivePrefix() != "svc:" {
+		t.Fatal("incorrect default namespace")
+	}
+	q := `SELECT id FROM schema_version WHERE app = ?`
+	if def.rewrite(q) != q {
+		t.Fatalf("query should not be rewritten, %v", def.rewrite(q))
+	}
+
+	ns := Namespace("billing")
+	if ns.Table("schema_version") != "billing_schema_version" || ns.lockName("myapp") != "billing:myapp" {
+		t.Fatal("incorrect namespace")
+	}
+	if s := ns.rewrite(q); s != `SELECT id FROM billing_schema_version WHERE app = ?` {
+		t.Fatalf("incorrect rewrite, %v", s)
+	}
+	if s := ns.rewrite(`CREATE INDEX schema_run_app_idx ON schema_run (app, started_at)`); s != `CREATE INDEX billing_schema_run_app_idx ON billing_schema_run (app, started_at)` {
+		t.Fatalf("incorrect rewrite, %v", s)
+	}
+	if s := ns.rewrite(`SELECT * FROM information_schema.columns`); s != `SELECT * FROM information_schema.columns` {
+		t.Fatalf("incorrect rewrite, %v", s)
+	}
+	if !isMetaTable("billing_schema_version") || !isMetaTable("schema_run") || isMetaTable("orders") {
+		t.Fatal("incorrect isMetaTable")
+	}
+
+	sf, err := parseScript("-- billing:run-always\n-- svc:assert SELECT 1 = 2\nSELECT 1;", server{ns: ns})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sf.RunAlways || len(sf.Asserts) != 0 {
+		t.Fatalf("directives of other namespaces should be ignored, %+v", sf)
+	}
+}
@@ -0,0 +1,250 @@
+package svc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	objectTypeView    = "view"
+	objectTypeRoutine = "routine"
+)
+
+// Repeatable objects managed by svc, each file in the directory defines one object, the object is dropped
+// and recreated whenever the content of the file is changed.
+type objectType struct {
+	Type string
+
+	// Directory relative to MigrateConfig.BaseDir.
+	Dir string
+
+	// Build the statement that drops the object.
+	Drop func(sf schemaFile) string
+}
+
+var (
+	objectTypes = []objectType{
+		{
+			Type: objectTypeView,
+			Dir:  "views",
+			Drop: func(sf schemaFile) string { return "DROP VIEW IF EXISTS " + objectName(sf.Name) },
+		},
+		{
+			Type: objectTypeRoutine,
+			Dir:  "routines",
+			Drop: dropRoutine,
+		},
+	}
+
+	createRoutineRegex = regexp.MustCompile(`(?is)^CREATE\s+(?:DEFINER\s*=\s*\S+\s+)?(PROCEDURE|FUNCTION)\s+(` + identPat + `)`)
+)
+
+// Build DROP PROCEDURE / DROP FUNCTION statement based on the CREATE statement in the file.
+func dropRoutine(sf schemaFile) string {
+	for _, sql := range sf.SQLs {
+		_, rest := splitLeadingComments(sql)
+		if m := createRoutineRegex.FindStringSubmatch(rest); m != nil {
+			return fmt.Sprintf("DROP %s IF EXISTS %s", strings.ToUpper(m[1]), m[2])
+		}
+	}
+	return "DROP PROCEDURE IF EXISTS " + objectName(sf.Name)
+}
+
+// Check if the directory (directly under BaseDir) is for repeatable objects.
+func isObjectDir(name string) bool {
+	for _, ot := range objectTypes {
+		if strings.EqualFold(ot.Dir, name) {
+			return true
+		}
+	}
+	return false
+}
+
+type repeatableObject struct {
+	schemaFile
+	Checksum string
+}
+
+// Name of the object, i.e., the file name without the .sql suffix.
+func objectName(fname string) string {
+	return strings.TrimSuffix(strings.ToLower(fname), ".sql")
+}
+
+func checksum(buf []byte) string {
+	h := sha256.Sum256(buf)
+	return hex.EncodeToString(h[:])
+}
+
+// Sync repeatable objects, objects that are changed (and objects that depend on them) are dropped and recreated.
+func syncObjects(db *gorm.DB, log Logger, c MigrateConfig, res Result) (Result, error) {
+	for _, ot := range objectTypes {
+		var err error
+		res, err = syncObjectType(db, log, c, ot, res)
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func syncObjectType(db *gorm.DB, log Logger, c MigrateConfig, ot objectType, res Result) (Result, error) {
+	dir := path.Join(c.BaseDir, ot.Dir)
+	entries, err := c.Fs.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return res, nil
+		}
+		return res, fmt.Errorf("failed to open %v folders, %w", dir, err)
+	}
+
+	objects := map[string]repeatableObject{}
+	for _, e := range entries {
+		name := strings.ToLower(e.Name())
+		if !e.Type().IsRegular() || !strings.HasSuffix(name, ".sql") || c.isExcluded(name) {
+			continue
+		}
+		fpath := path.Join(dir, e.Name())
+		buf, err := c.Fs.ReadFile(fpath)
+		if err != nil {
+			return res, fmt.Errorf("failed to fs.ReadFile, %v, %w", fpath, err)
+		}
+		sf, err := parseScript(string(buf), c.srv)
+		if err != nil {
+			return res, fmt.Errorf("failed to parse %v, %w", fpath, err)
+		}
+		sf.Name = name
+		sf.Path = fpath
+		for i, sql := range sf.SQLs {
+			sf.SQLs[i] = resolvePlaceholders(sql, c.Placeholders)
+		}
+		// the checksum covers the values of the placeholders, the object is recreated if they are changed
+		content := resolvePlaceholders(string(buf), c.Placeholders)
+		objects[objectName(name)] = repeatableObject{schemaFile: sf, Checksum: checksum([]byte(content))}
+	}
+	if len(objects) < 1 {
+		return res, nil
+	}
+
+	ordered, err := sortObjects(objects)
+	if err != nil {
+		return res, fmt.Errorf("failed to sort %v, %w", ot.Dir, err)
+	}
+
+	var saved []struct {
+		Name     string
+		Checksum string
+	}
+	if err := db.Raw(c.ns().rewrite(`SELECT name, checksum FROM schema_object WHERE app = ? AND type = ?`+c.forUpdate()), c.appArg(db), ot.Type).
+		Scan(&saved).Error; err != nil && !c.DryRun {
+		return res, fmt.Errorf("failed to list schema_object, %w", err)
+	}
+	savedChecksum := map[string]string{}
+	for _, s := range saved {
+		savedChecksum[s.Name] = s.Checksum
+	}
+
+	// objects are ordered by dependencies, if an object is recreated, those depend on it are recreated as well
+	recreated := map[string]struct{}{}
+	for _, name := range ordered {
+		o := objects[name]
+		changed := savedChecksum[name] != o.Checksum
+		for _, d := range o.DependsOn {
+			if _, ok := recreated[d]; ok {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+
+		sr := ScriptResult{Script: path.Join(ot.Dir, o.Name)}
+		if c.DryRun {
+			log.Infof("[dry-run] %v %v would be recreated (%v)", ot.Type, name, o.Path)
+			sr.Statements = len(o.SQLs) + 1
+			for _, sql := range append([]string{ot.Drop(o.schemaFile)}, o.SQLs...) {
+				sr.Planned = append(sr.Planned, planStatement(sql))
+			}
+			res.add(sr)
+			recreated[name] = struct{}{}
+			continue
+		}
+		stmts := append([]string{ot.Drop(o.schemaFile)}, o.SQLs...)
+		for i, sql := range stmts {
+			if _, err := executorOrDefault(c.Executor).Exec(db, Statement{Script: sr.Script, Index: i + 1, SQL: sql}); err != nil {
+				res.add(sr)
+				return res, fmt.Errorf("failed to recreate %v %v, '%v', %w", ot.Type, name, sql, err)
+			}
+			sr.Statements += 1
+		}
+		var err error
+		now := timeArg(dialectOf(db, c.Dialect), clockOrDefault(c.Clock).Now())
+		if _, ok := savedChecksum[name]; ok {
+			err = db.Exec(c.ns().rewrite(`UPDATE schema_object SET checksum = ?, updated_at = ? WHERE app = ? AND type = ? AND name = ?`),
+				o.Checksum, now, c.appArg(db), ot.Type, name).Error
+		} else {
+			err = db.Exec(c.ns().rewrite(`INSERT INTO schema_object (app, type, name, checksum, created_at, updated_at) VALUES (?,?,?,?,?,?)`),
+				c.appArg(db), ot.Type, name, o.Checksum, now, now).Error
+		}
+		if err != nil {
+			res.add(sr)
+			return res, fmt.Errorf("failed to save schema_object, %w", err)
+		}
+		res.add(sr)
+		recreated[name] = struct{}{}
+		log.Infof("Recreated %v %v (%v)", ot.Type, name, o.Path)
+	}
+	return res, nil
+}
+
+// Sort objects by their declared dependencies, dependencies that are not managed by svc are ignored.
+func sortObjects(objects map[string]repeatableObject) ([]string, error) {
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	ordered := make([]string, 0, len(names))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("cyclic dependencies: %v", strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+		for _, d := range objects[name].DependsOn {
+			if _, ok := objects[d]; !ok {
+				continue
+			}
+			if err := visit(d, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		ordered = append(ordered, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,35 @@
+package svc
+
+import (
+	"testing"
+)
+
+func TestSortObjects(t *testing.T) {
+	objects := map[string]repeatableObject{
+		"v_a": {schemaFile: schemaFile{DependsOn: []string{"v_c", "user"}}},
+		"v_b": {},
+		"v_c": {schemaFile: schemaFile{DependsOn: []string{"v_b"}}},
+	}
+	ordered, err := sortObjects(objects)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"v_b", "v_c", "v_a"}
+	for i := range expected {
+		if ordered[i] != expected[i] {
+			t.Fatalf("should be %v, but got %v", expected, ordered)
+		}
+	}
+
+	objects["v_b"] = repeatableObject{schemaFile: schemaFile{DependsOn: []string{"v_a"}}}
+	if _, err := sortObjects(objects); err == nil {
+		t.Fatal("should return error")
+	}
+}
+
+func TestDropRoutine(t *testing.T) {
+	sf := schemaFile{Name: "f_total.sql", SQLs: []string{"CREATE DEFINER=`root`@`%` FUNCTION `f_total`(a INT) RETURNS INT DETERMINISTIC RETURN a"}}
+	if v := dropRoutine(sf); v != "DROP FUNCTION IF EXISTS `f_total`" {
+		t.Fatalf("incorrect drop statement, %v", v)
+	}
+}
@@ -0,0 +1,286 @@
+package svc
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Option for Migrate.
+type Option func(c *MigrateConfig)
+
+// Migrate schema with options, it's equivalent to Run with the MigrateConfig built by the options.
+//
+// e.g.,
+//
+//	res, err := Migrate(conn, PrintLogger{}, WithApp("myapp"), WithFS(schemaFs), WithBaseDir("schema"), WithDryRun())
+func Migrate(db *gorm.DB, log Logger, opts ...Option) (Result, error) {
+	var c MigrateConfig
+	for _, op := range opts {
+		op(&c)
+	}
+	return Run(db, log, c)
+}
+
+// Start with the provided MigrateConfig, the following options are applied on top of it.
+func WithConfig(mc MigrateConfig) Option {
+	return func(c *MigrateConfig) {
+		*c = mc
+	}
+}
+
+func WithApp(app string) Option {
+	return func(c *MigrateConfig) {
+		c.App = app
+	}
+}
+
+func WithFS(fs ReadFS) Option {
+	return func(c *MigrateConfig) {
+		c.Fs = fs
+	}
+}
+
+func WithBaseDir(dir string) Option {
+	return func(c *MigrateConfig) {
+		c.BaseDir = dir
+	}
+}
+
+func WithStartingVersion(ver string) Option {
+	return func(c *MigrateConfig) {
+		c.StartingVersion = ver
+	}
+}
+
+func WithClock(clock Clock) Option {
+	return func(c *MigrateConfig) {
+		c.Clock = clock
+	}
+}
+
+// Generate the identifiers recorded in schema_version and schema_run using g, see MigrateConfig.IDGenerator.
+func WithIDGenerator(g IDGenerator) Option {
+	return func(c *MigrateConfig) {
+		c.IDGenerator = g
+	}
+}
+
+func WithExclude(names ...string) Option {
+	return func(c *MigrateConfig) {
+		c.Exclude = append(c.Exclude, names...)
+	}
+}
+
+func WithPlaceholder(name string, value string) Option {
+	return func(c *MigrateConfig) {
+		if c.Placeholders == nil {
+			c.Placeholders = map[string]string{}
+		}
+		c.Placeholders[name] = value
+	}
+}
+
+func WithSecretPlaceholders(names ...string) Option {
+	return func(c *MigrateConfig) {
+		c.SecretPlaceholders = append(c.SecretPlaceholders, names...)
+	}
+}
+
+// Encrypt the statements recorded in schema_script_sql using AES-GCM, see MigrateConfig.StmtKey.
+func WithStmtKey(key []byte) Option {
+	return func(c *MigrateConfig) {
+		c.StmtKey = key
+	}
+}
+
+// Append the clauses to ALTER TABLE statements, DefaultOnlineAlter is used if clauses is empty.
+func WithOnlineAlter(clauses string) Option {
+	return func(c *MigrateConfig) {
+		if clauses == "" {
+			clauses = DefaultOnlineAlter
+		}
+		c.OnlineAlter = clauses
+	}
+}
+
+func WithReporter(r Reporter) Option {
+	return func(c *MigrateConfig) {
+		c.Reporter = r
+	}
+}
+
+// Publish schema change event after the migration succeeded.
+func WithPublisher(p Publisher) Option {
+	return func(c *MigrateConfig) {
+		c.Publisher = p
+	}
+}
+
+func WithNamespace(ns string) Option {
+	return func(c *MigrateConfig) {
+		c.Namespace = Namespace(ns)
+	}
+}
+
+func WithEnv(env string) Option {
+	return func(c *MigrateConfig) {
+		c.Env = env
+	}
+}
+
+func WithDeterministic() Option {
+	return func(c *MigrateConfig) {
+		c.Deterministic = true
+	}
+}
+
+func WithGuardPool() Option {
+	return func(c *MigrateConfig) {
+		c.GuardPool = true
+	}
+}
+
+func WithAdopt() Option {
+	return func(c *MigrateConfig) {
+		c.Adopt = true
+	}
+}
+
+// Skip the statements whose table, column or index already exists, see MigrateConfig.SmartSkip.
+func WithSmartSkip() Option {
+	return func(c *MigrateConfig) {
+		c.SmartSkip = true
+	}
+}
+
+func WithRewriteIfNotExists() Option {
+	return func(c *MigrateConfig) {
+		c.RewriteIfNotExists = true
+	}
+}
+
+func WithCaptureWarnings() Option {
+	return func(c *MigrateConfig) {
+		c.CaptureWarnings = true
+	}
+}
+
+func WithStrictSQLMode() Option {
+	return func(c *MigrateConfig) {
+		c.StrictSQLMode = true
+	}
+}
+
+// Require BaseDir to exist and contain at least one .sql file, it overrides the default set by SetRequireSource.
+func WithRequireSource(require bool) Option {
+	return func(c *MigrateConfig) {
+		c.RequireSource = &require
+	}
+}
+
+func WithRecursive() Option {
+	return func(c *MigrateConfig) {
+		c.Recursive = true
+	}
+}
+
+// Require the tables created by the scripts to use the charset and collation, collation is optional.
+func WithRequiredCharset(charset string, collation string) Option {
+	return func(c *MigrateConfig) {
+		c.RequiredCharset = charset
+		c.RequiredCollation = collation
+	}
+}
+
+func WithReorderForeignKeys() Option {
+	return func(c *MigrateConfig) {
+		c.ReorderForeignKeys = true
+	}
+}
+
+func WithPrimaryReads() Option {
+	return func(c *MigrateConfig) {
+		c.PrimaryReads = true
+	}
+}
+
+func WithDryRun() Option {
+	return func(c *MigrateConfig) {
+		c.DryRun = true
+	}
+}
+
+// Run the migration on a dedicated connection opened using the DSN, e.g., a DDL-privileged user.
+func WithDSN(dsn string) Option {
+	return func(c *MigrateConfig) {
+		c.DSN = dsn
+	}
+}
+
+// Run the migration on a dedicated connection opened by the factory.
+func WithConnect(connect ConnectFunc) Option {
+	return func(c *MigrateConfig) {
+		c.Connect = connect
+	}
+}
+
+// Format the remarks recorded in schema_version, see MigrateConfig.RemarkFormatter.
+func WithRemarkFormatter(f RemarkFormatter) Option {
+	return func(c *MigrateConfig) {
+		c.RemarkFormatter = f
+	}
+}
+
+// Throttle the statements, see MigrateConfig.Throttle.
+func WithThrottle(t Throttle) Option {
+	return func(c *MigrateConfig) {
+		c.Throttle = &t
+	}
+}
+
+// Fail the migration if the pending scripts contain no statement, see MigrateConfig.StrictEmptyScripts.
+func WithStrictEmptyScripts() Option {
+	return func(c *MigrateConfig) {
+		c.StrictEmptyScripts = true
+	}
+}
+
+// Continue with the next scripts if a script fails, see MigrateConfig.ContinueOnError.
+func WithContinueOnError() Option {
+	return func(c *MigrateConfig) {
+		c.ContinueOnError = true
+	}
+}
+
+func WithFailurePolicy(p FailurePolicy) Option {
+	return func(c *MigrateConfig) {
+		c.OnPreviousFailure = p
+	}
+}
+
+func WithExecutor(e Executor) Option {
+	return func(c *MigrateConfig) {
+		c.Executor = e
+	}
+}
+
+func WithDialect(d Dialect) Option {
+	return func(c *MigrateConfig) {
+		c.Dialect = d
+	}
+}
+
+// Acquire the migration lock, waiting for at most timeout (defaults to 1 minute if it's zero).
+func WithLock(timeout time.Duration) Option {
+	return func(c *MigrateConfig) {
+		c.Lock = true
+		c.LockTimeout = timeout
+	}
+}
+
+func WithCompatPolicy(p *CompatPolicy) Option {
+	return func(c *MigrateConfig) {
+		c.CompatPolicy = p
+	}
+}
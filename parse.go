@@ -0,0 +1,314 @@
+package svc
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// dbmate-style section markers, e.g., '-- migrate:up'
+	sectionPrefix = "migrate:"
+	sectionUp     = "up"
+	sectionDown   = "down"
+
+	directiveAssert    = "assert"
+	directiveRunAlways = "run-always"
+	directiveDependsOn = "depends-on"
+	directiveIf        = "if"
+	directiveElse      = "else"
+	directiveEndIf     = "endif"
+	directiveDeprecate = "deprecated"
+	directiveBaseline  = "baseline"
+	directiveDatabase  = "database"
+	directiveResumable = "resumable"
+	directiveOnline    = "online-alter"
+	directiveCanary    = "canary"
+	directiveForeach   = "foreach"
+	directiveOptional  = "optional"
+	directiveCheck     = "check"
+)
+
+// Assertion declared in script using '-- svc:assert <query> = <expected>'.
+//
+// The query should return a single value, e.g., '-- svc:assert SELECT COUNT(*) FROM t WHERE deleted = 1 = 0'.
+type assertion struct {
+	Query    string
+	Expected string
+}
+
+// Parse directive line, e.g., '-- svc:assert SELECT 1 = 1', returns the directive name and the argument.
+//
+// The prefix of the directives is derived from the namespace, e.g., 'svc:' for the default namespace.
+func parseDirective(line string, prefix string) (name string, arg string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "--") {
+		return "", "", false
+	}
+	line = strings.TrimSpace(strings.TrimPrefix(line, "--"))
+	if !strings.HasPrefix(line, prefix) {
+		return "", "", false
+	}
+	line = strings.TrimPrefix(line, prefix)
+	name, arg, _ = strings.Cut(line, " ")
+	return strings.ToLower(name), strings.TrimSpace(arg), true
+}
+
+// Parse dbmate-style section marker, e.g., '-- migrate:up', '-- migrate:down transaction:false'.
+func parseSection(line string) (section string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "--") {
+		return "", false
+	}
+	line = strings.TrimSpace(strings.TrimPrefix(line, "--"))
+	if !strings.HasPrefix(line, sectionPrefix) {
+		return "", false
+	}
+	section, _, _ = strings.Cut(strings.TrimPrefix(line, sectionPrefix), " ")
+	section = strings.ToLower(section)
+	if section != sectionUp && section != sectionDown {
+		return "", false
+	}
+	return section, true
+}
+
+func parseAssertion(arg string) (assertion, error) {
+	i := strings.LastIndex(arg, "=")
+	if i < 0 {
+		return assertion{}, fmt.Errorf("missing expected value in assertion '%v'", arg)
+	}
+	a := assertion{
+		Query:    strings.TrimSpace(arg[:i]),
+		Expected: strings.TrimSpace(arg[i+1:]),
+	}
+	if a.Query == "" || a.Expected == "" {
+		return assertion{}, fmt.Errorf("malformed assertion '%v'", arg)
+	}
+	return a, nil
+}
+
+// Parse script content, directives are extracted and the rest is split into sql statements.
+//
+// If the script contains dbmate-style sections, the statements in '-- migrate:up' section are executed in migration,
+// and the statements in '-- migrate:down' section are used for rollback.
+//
+// Lines in the conditional blocks ('-- svc:if <cond>', '-- svc:else', '-- svc:endif') are only included if the
+// condition is evaluated true for the server flavor, e.g., '-- svc:if mariadb'.
+func parseScript(content string, s server) (schemaFile, error) {
+	var sf schemaFile
+	lines := strings.Split(content, "\n")
+	downLines := make([]string, len(lines))
+	section := sectionUp
+
+	// stack of conditional blocks, whether the lines in the block are included
+	conds := []bool{}
+	included := func() bool {
+		for _, c := range conds {
+			if !c {
+				return false
+			}
+		}
+		return true
+	}
+
+	for i, l := range lines {
+		if name, arg, ok := parseDirective(l, s.ns.directivePrefix()); ok {
+			switch name {
+			case directiveIf:
+				if arg == "" {
+					return sf, fmt.Errorf("line %d, missing condition in '%v'", i+1, directiveIf)
+				}
+				conds = append(conds, s.flavor.eval(arg))
+				lines[i] = ""
+				continue
+			case directiveElse:
+				if len(conds) < 1 {
+					return sf, fmt.Errorf("line %d, '%v' without '%v'", i+1, directiveElse, directiveIf)
+				}
+				conds[len(conds)-1] = !conds[len(conds)-1]
+				lines[i] = ""
+				continue
+			case directiveEndIf:
+				if len(conds) < 1 {
+					return sf, fmt.Errorf("line %d, '%v' without '%v'", i+1, directiveEndIf, directiveIf)
+				}
+				conds = conds[:len(conds)-1]
+				lines[i] = ""
+				continue
+			}
+		}
+		if !included() {
+			lines[i] = ""
+			continue
+		}
+
+		if sec, ok := parseSection(l); ok {
+			section = sec
+			lines[i] = ""
+			continue
+		}
+		if section == sectionDown {
+			downLines[i] = l
+			lines[i] = ""
+			continue
+		}
+
+		name, arg, ok := parseDirective(l, s.ns.directivePrefix())
+		if !ok {
+			continue
+		}
+		switch name {
+		case directiveAssert:
+			a, err := parseAssertion(arg)
+			if err != nil {
+				return sf, fmt.Errorf("line %d, %w", i+1, err)
+			}
+			sf.Asserts = append(sf.Asserts, a)
+		case directiveCheck:
+			ck, err := parseCheck(arg)
+			if err != nil {
+				return sf, fmt.Errorf("line %d, %w", i+1, err)
+			}
+			sf.Checks = append(sf.Checks, ck)
+		case directiveRunAlways:
+			sf.RunAlways = true
+		case directiveOptional:
+			sf.Optional = true
+		case directiveDependsOn:
+			for _, d := range strings.FieldsFunc(arg, func(r rune) bool { return r == ',' || r == ' ' }) {
+				sf.DependsOn = append(sf.DependsOn, strings.ToLower(d))
+			}
+		case directiveDatabase:
+			if arg == "" {
+				return sf, fmt.Errorf("line %d, missing database name in '%v'", i+1, directiveDatabase)
+			}
+			sf.Database = arg
+		case directiveResumable:
+			r, err := parseResumable(arg)
+			if err != nil {
+				return sf, fmt.Errorf("line %d, %w", i+1, err)
+			}
+			sf.Resumable = r
+		case directiveCanary:
+			cn, err := parseCanary(arg)
+			if err != nil {
+				return sf, fmt.Errorf("line %d, %w", i+1, err)
+			}
+			sf.Canary = cn
+		case directiveForeach:
+			f, err := parseForeach(arg)
+			if err != nil {
+				return sf, fmt.Errorf("line %d, %w", i+1, err)
+			}
+			for _, prev := range sf.Foreach {
+				if prev.Name == f.Name {
+					return sf, fmt.Errorf("line %d, duplicate foreach '%v'", i+1, f.Name)
+				}
+			}
+			sf.Foreach = append(sf.Foreach, f)
+		case directiveOnline:
+			sf.OnlineAlter = arg
+			if arg == "" {
+				sf.OnlineAlter = DefaultOnlineAlter
+			}
+		case directiveBaseline:
+			if arg == "" {
+				return sf, fmt.Errorf("line %d, missing version in '%v'", i+1, directiveBaseline)
+			}
+			sf.Baseline = arg
+		case directiveDeprecate:
+			if arg == "" {
+				return sf, fmt.Errorf("line %d, missing version in '%v'", i+1, directiveDeprecate)
+			}
+			sf.Deprecated = arg
+		default:
+			return sf, fmt.Errorf("line %d, unknown directive '%v'", i+1, name)
+		}
+		lines[i] = ""
+	}
+
+	if len(conds) > 0 {
+		return sf, fmt.Errorf("missing '%v'", directiveEndIf)
+	}
+
+	sf.SQLs = s.splitStatements(lines)
+	sf.Pos = locateStatements(lines, sf.SQLs)
+	sf.Down = s.splitStatements(downLines)
+	if sf.Empty = isCommentOnly(sf.SQLs); sf.Empty {
+		sf.SQLs, sf.Pos = nil, nil
+	}
+	if sf.Resumable != nil && (sf.Canary != nil || len(sf.Foreach) > 0) {
+		return sf, fmt.Errorf("'%v' and '%v' can't be used together with '%v'", directiveCanary, directiveForeach, directiveResumable)
+	}
+	for _, f := range sf.Foreach {
+		var err error
+		if sf.SQLs, sf.Pos, err = f.expand(sf.SQLs, sf.Pos); err != nil {
+			return sf, err
+		}
+	}
+	if sf.Canary != nil {
+		var err error
+		if sf.SQLs, sf.Pos, err = sf.Canary.expand(sf.SQLs, sf.Pos); err != nil {
+			return sf, err
+		}
+	}
+	return sf, nil
+}
+
+// Split lines into sql statements, statements are terminated by ';' or the delimiter declared
+// using 'DELIMITER', e.g., 'DELIMITER $$' (the DELIMITER lines are client commands, they are not executed).
+func splitStatements(lines []string) []string {
+	sqls := []string{}
+	split := func(chunk []string, delim string) {
+		for _, seg := range strings.Split(strings.Join(chunk, "\n"), delim) {
+			seg = strings.TrimSpace(seg)
+			if seg == "" {
+				continue
+			}
+			sqls = append(sqls, seg)
+		}
+	}
+
+	delim := ";"
+	chunk := []string{}
+	for _, l := range lines {
+		fields := strings.Fields(l)
+		if len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER") {
+			split(chunk, delim)
+			chunk = chunk[:0]
+			delim = fields[1]
+			continue
+		}
+		chunk = append(chunk, l)
+	}
+	split(chunk, delim)
+	return sqls
+}
+
+// Position of the statement in the script.
+type stmtPos struct {
+	// 1-based index of the statement in the script.
+	Index int
+
+	// Approximate 1-based line number of the statement in the script, 0 if unknown.
+	Line int
+}
+
+// Locate the statements in the script lines, the statements must be in the order as they appear in the script.
+func locateStatements(lines []string, sqls []string) []stmtPos {
+	pos := make([]stmtPos, 0, len(sqls))
+	cursor := 0
+	for i, sql := range sqls {
+		p := stmtPos{Index: i + 1}
+		first, _, _ := strings.Cut(strings.TrimSpace(sql), "\n")
+		first = strings.TrimSpace(first)
+		for j := cursor; j < len(lines) && first != ""; j++ {
+			if strings.Contains(lines[j], first) {
+				p.Line = j + 1
+				cursor = j + strings.Count(sql, "\n")
+				break
+			}
+		}
+		pos = append(pos, p)
+	}
+	return pos
+}
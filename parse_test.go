@@ -0,0 +1,137 @@
+package svc
+
+import (
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	sf, err := parseScript(`
+	ALTER TABLE t ADD COLUMN deleted TINYINT(1) NOT NULL DEFAULT 0;
+	UPDATE t SET deleted = 1 WHERE removed_at IS NOT NULL;
+
+	-- svc:assert SELECT COUNT(*) FROM t WHERE removed_at IS NOT NULL AND deleted = 0 = 0
+	`, server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqls, asserts := sf.SQLs, sf.Asserts
+	if len(sqls) != 2 {
+		t.Fatalf("should be 2, %v", len(sqls))
+	}
+	if len(asserts) != 1 {
+		t.Fatalf("should be 1, %v", len(asserts))
+	}
+	if asserts[0].Query != "SELECT COUNT(*) FROM t WHERE removed_at IS NOT NULL AND deleted = 0" {
+		t.Fatalf("incorrect query, %v", asserts[0].Query)
+	}
+	if asserts[0].Expected != "0" {
+		t.Fatalf("incorrect expected value, %v", asserts[0].Expected)
+	}
+
+	if _, err := parseScript(`-- svc:assert SELECT 1`, server{}); err == nil {
+		t.Fatal("should return error")
+	}
+
+	sf, err = parseScript("-- svc:run-always\nGRANT SELECT ON tt.* TO 'reader'@'%'", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sf.RunAlways {
+		t.Fatal("should be run-always")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	sf, err := parseScript(`
+DROP PROCEDURE IF EXISTS p_cleanup;
+
+DELIMITER $$
+CREATE PROCEDURE p_cleanup()
+BEGIN
+	DELETE FROM t WHERE deleted = 1;
+	DELETE FROM r WHERE deleted = 1;
+END $$
+DELIMITER ;
+
+SELECT 1;`, server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sf.SQLs) != 3 {
+		t.Fatalf("should be 3, %v", sf.SQLs)
+	}
+	if sf.SQLs[1] != "CREATE PROCEDURE p_cleanup()\nBEGIN\n\tDELETE FROM t WHERE deleted = 1;\n\tDELETE FROM r WHERE deleted = 1;\nEND" {
+		t.Fatalf("incorrect statement, %v", sf.SQLs[1])
+	}
+}
+
+func TestParseSections(t *testing.T) {
+	sf, err := parseScript(`-- migrate:up
+CREATE TABLE t (id INT);
+ALTER TABLE t ADD COLUMN name VARCHAR(10);
+
+-- migrate:down
+DROP TABLE t;
+`, server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sf.SQLs) != 2 {
+		t.Fatalf("should be 2, %v", sf.SQLs)
+	}
+	if len(sf.Down) != 1 || sf.Down[0] != "DROP TABLE t" {
+		t.Fatalf("incorrect down statements, %v", sf.Down)
+	}
+}
+
+func TestParseDatabase(t *testing.T) {
+	sf, err := parseScript("-- svc:database ${archive}\nCREATE TABLE t_archive (id INT);", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.Database != "${archive}" || len(sf.SQLs) != 1 {
+		t.Fatalf("incorrect script, %+v", sf)
+	}
+	if _, err := parseScript("-- svc:database\nSELECT 1;", server{}); err == nil {
+		t.Fatal("should fail")
+	}
+	if q := quoteIdent("arch`ive"); q != "`arch``ive`" {
+		t.Fatalf("incorrect quote, %v", q)
+	}
+}
+
+func TestParseResumable(t *testing.T) {
+	sf, err := parseScript("-- svc:resumable orders.id 1000\nUPDATE orders SET status = 1 WHERE id > ${svc.from} AND id <= ${svc.to};\nSELECT 1;", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.Resumable == nil || sf.Resumable.Table != "orders" || sf.Resumable.Column != "id" || sf.Resumable.BatchSize != 1000 {
+		t.Fatalf("incorrect resumable, %+v", sf.Resumable)
+	}
+	if !isBatched(sf.SQLs[0]) || isBatched(sf.SQLs[1]) {
+		t.Fatalf("incorrect batched statements, %v", sf.SQLs)
+	}
+	if s := resolveBatch(sf.SQLs[0], 0, 1000); s != "UPDATE orders SET status = 1 WHERE id > 0 AND id <= 1000" {
+		t.Fatalf("incorrect batch, %v", s)
+	}
+	for _, arg := range []string{"", "orders 1000", "orders.id", "orders.id 0", "orders.id x"} {
+		if _, err := parseScript("-- svc:resumable "+arg+"\nSELECT 1;", server{}); err == nil {
+			t.Fatalf("'%v' should fail", arg)
+		}
+	}
+}
+
+func TestParseCheck(t *testing.T) {
+	sf, err := parseScript("-- svc:check SELECT id FROM t WHERE remark IS NULL LIMIT 1\nALTER TABLE t MODIFY COLUMN remark VARCHAR(255) NOT NULL;", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sf.Checks) != 1 || sf.Checks[0].Query != "SELECT id FROM t WHERE remark IS NULL LIMIT 1" || len(sf.SQLs) != 1 {
+		t.Fatalf("incorrect script, %+v", sf)
+	}
+	for _, arg := range []string{"", "DELETE FROM t WHERE remark IS NULL", "SELECTION"} {
+		if _, err := parseScript("-- svc:check "+arg+"\nSELECT 1;", server{}); err == nil {
+			t.Fatalf("'%v' should fail", arg)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Read the pending versioned scripts (see Status) in the order of versions.
+func pendingScripts(db *gorm.DB, c MigrateConfig) ([]schemaFile, server, error) {
+	if c.Fs == nil {
+		return nil, server{}, errors.New("fs is nil")
+	}
+	if db == nil {
+		return nil, server{}, errors.New("db is nil")
+	}
+	srv, err := detectServer(db, c.Dialect)
+	if err != nil {
+		return nil, server{}, err
+	}
+	srv.ns = c.ns()
+	c.Dialect = srv.dialect
+
+	statuses, err := Status(db, c)
+	if err != nil {
+		return nil, srv, err
+	}
+	files, err := discoverFiles(c.Fs, c.BaseDir, c.Recursive, c.isExcluded)
+	if err != nil {
+		return nil, srv, fmt.Errorf("failed to discover scripts, %w", err)
+	}
+	byName := map[string]sourceFile{}
+	for _, f := range files {
+		byName[f.Name] = f
+	}
+
+	pending := []schemaFile{}
+	for _, st := range statuses {
+		if st.Status != StatusPending {
+			continue
+		}
+		sf, err := readSchemaFile(c.Fs, byName[st.Script], srv)
+		if err != nil {
+			return nil, srv, err
+		}
+		if sf.RunAlways {
+			continue
+		}
+		pending = append(pending, sf)
+	}
+	return pending, srv, nil
+}
+
+// Terminate the statement, so that it can be executed by the database client.
+func terminateStatement(s server, sql string) string {
+	if s.dialect != nil && s.dialect.Name() == DialectOracle {
+		if plsqlBlockRegex.MatchString(sql) {
+			return sql + "\n/"
+		}
+		return sql + ";"
+	}
+	if strings.Contains(sql, ";") {
+		return "DELIMITER $$\n" + sql + "$$\nDELIMITER ;"
+	}
+	return sql + ";"
+}
+
+// Write the statements of all pending versioned scripts into a single .sql file, nothing is executed.
+//
+// Each script is preceded by a header comment, the placeholders are replaced, and the assertions are written as
+// comments. It's mainly used when the changes must be applied manually, e.g., by a DBA using their own tooling,
+// svc's history can be synced afterwards using MarkPendingApplied.
+func PendingSQL(db *gorm.DB, c MigrateConfig, w io.Writer) error {
+	pending, srv, err := pendingScripts(db, c)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Pending scripts of %v: %d\n", c.App, len(pending))
+	for _, sf := range pending {
+		fmt.Fprintf(&b, "\n-- ------------------------------------------------------------\n")
+		fmt.Fprintf(&b, "-- %v (%v)\n", sf.Name, sf.Path)
+		if sf.Database != "" {
+			fmt.Fprintf(&b, "-- database: %v\n", resolvePlaceholders(sf.Database, c.Placeholders))
+		}
+		fmt.Fprintf(&b, "-- ------------------------------------------------------------\n\n")
+		for _, ck := range sf.Checks {
+			fmt.Fprintf(&b, "-- check (should return no rows): %v\n\n", resolvePlaceholders(ck.Query, c.Placeholders))
+		}
+		if sf.Empty {
+			b.WriteString("-- empty script (no statement found)\n\n")
+		}
+		for _, sql := range sf.SQLs {
+			b.WriteString(terminateStatement(srv, resolvePlaceholders(sql, c.Placeholders)))
+			b.WriteString("\n\n")
+		}
+		for _, a := range sf.Asserts {
+			fmt.Fprintf(&b, "-- assert: %v = %v\n\n", resolvePlaceholders(a.Query, c.Placeholders), a.Expected)
+		}
+	}
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write pending sql, %w", err)
+	}
+	return nil
+}
+
+// Record the script and its statements as executed without running them, the empty script is recorded with the
+// same remark as Run does. The meta should be bound to a transaction, so that a failure partway doesn't leave
+// statements recorded without the schema_version row.
+func markApplied(meta *stmtCache, c MigrateConfig, sf schemaFile, remark string) error {
+	cph, err := c.stmtCipher()
+	if err != nil {
+		return err
+	}
+	for _, sql := range sf.SQLs {
+		undo, reversible := deriveUndo(meta.dialect, sql)
+		var undoStmt any
+		if reversible {
+			undoStmt = undo
+		}
+		recorded, err := cph.encrypt(c.App, sf.Name, sql)
+		if err != nil {
+			return err
+		}
+		if _, err := meta.exec(`INSERT INTO schema_script_sql (app, script, stmt, undo_stmt, reversible, rows_affected, created_at)
+			VALUES (?,?,?,?,?,?,?)`, meta.app(c.App), sf.Name, recorded, undoStmt, reversible, 0, meta.timeArg(meta.now())); err != nil {
+			return fmt.Errorf("failed to save schema_script_sql, %w", err)
+		}
+	}
+	reason := RemarkApplied
+	if sf.Empty {
+		reason, remark = RemarkEmpty, emptyScriptRemark
+	}
+	if err := saveSchemaVer(meta, c.App, sf.Name, kindVersioned, true, c.remark(reason, sf, remark)); err != nil {
+		return fmt.Errorf("failed to save schema_version, %w", err)
+	}
+	if err := saveAuthorship(meta, c.App, sf); err != nil {
+		return fmt.Errorf("failed to save schema_version, %w", err)
+	}
+	return nil
+}
+
+// Record all pending versioned scripts as executed without running them, it's the companion of PendingSQL,
+// it's called after the statements written by PendingSQL are applied manually. Returns the names of the scripts.
+func MarkPendingApplied(db *gorm.DB, c MigrateConfig) ([]string, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	if err := dialectOf(db, c.Dialect).InitMetaTables(db, c.ns()); err != nil {
+		return nil, err
+	}
+	pending, srv, err := pendingScripts(db, c)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(pending))
+	for _, sf := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			meta := newStmtCache(tx, srv.dialect, c.Clock, c.IDGenerator, c.ns())
+			defer meta.close()
+			return markApplied(meta, c, sf, "Applied manually")
+		})
+		if err != nil {
+			return names, err
+		}
+		names = append(names, sf.Name)
+	}
+	return names, nil
+}
+
+// Record the versioned script (and its statements) as executed without running it, e.g., the script was applied
+// out-of-band by a DBA. The script is read from c.Fs, e.g., MarkApplied(db, c, "v0.0.3").
+//
+// The statements recorded for the previous failed attempt of the script are replaced. It fails if the script
+// has been applied already.
+func MarkApplied(db *gorm.DB, c MigrateConfig, script string) error {
+	if c.Fs == nil {
+		return errors.New("fs is nil")
+	}
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	srv, err := detectServer(db, c.Dialect)
+	if err != nil {
+		return err
+	}
+	srv.ns = c.ns()
+	if err := srv.dialect.InitMetaTables(db, c.ns()); err != nil {
+		return err
+	}
+
+	name := scriptName(script)
+	files, err := discoverFiles(c.Fs, c.BaseDir, c.Recursive, c.isExcluded)
+	if err != nil {
+		return fmt.Errorf("failed to discover scripts, %w", err)
+	}
+	var sf schemaFile
+	found := false
+	for _, f := range files {
+		if f.Name == name {
+			if sf, err = readSchemaFile(c.Fs, f, srv); err != nil {
+				return err
+			}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("script %v not found in %v", name, c.BaseDir)
+	}
+	if sf.RunAlways {
+		return fmt.Errorf("script %v is run-always, it can't be marked as applied", name)
+	}
+
+	var applied int
+	if err := db.Raw(c.ns().rewrite(`SELECT COUNT(*) FROM schema_version WHERE app = ? AND script = ? AND kind = ? AND success = ?`),
+		c.appArg(db), name, kindVersioned, true).Scan(&applied).Error; err != nil {
+		return fmt.Errorf("failed to query schema_version, %w", err)
+	}
+	if applied > 0 {
+		return fmt.Errorf("script %v has been applied already", name)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(c.ns().rewrite(`DELETE FROM schema_script_sql WHERE app = ? AND script = ?`), c.appArg(tx), name).Error; err != nil {
+			return fmt.Errorf("failed to delete schema_script_sql, %w", err)
+		}
+		meta := newStmtCache(tx, srv.dialect, c.Clock, c.IDGenerator, c.ns())
+		defer meta.close()
+		return markApplied(meta, c, sf, "Applied manually")
+	})
+}
@@ -0,0 +1,81 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTerminateStatement(t *testing.T) {
+	mysql := server{dialect: MySQLDialect{}}
+	if s := terminateStatement(mysql, "CREATE TABLE t (id INT)"); s != "CREATE TABLE t (id INT);" {
+		t.Fatalf("incorrect statement, %v", s)
+	}
+	if s := terminateStatement(mysql, "CREATE PROCEDURE p() BEGIN SELECT 1; END"); s != "DELIMITER $$\nCREATE PROCEDURE p() BEGIN SELECT 1; END$$\nDELIMITER ;" {
+		t.Fatalf("incorrect statement, %v", s)
+	}
+	oracle := server{dialect: OracleDialect{}}
+	if s := terminateStatement(oracle, "CREATE TABLE t (id NUMBER)"); s != "CREATE TABLE t (id NUMBER);" {
+		t.Fatalf("incorrect statement, %v", s)
+	}
+	if s := terminateStatement(oracle, "BEGIN\n  NULL;\nEND;"); s != "BEGIN\n  NULL;\nEND;\n/" {
+		t.Fatalf("incorrect statement, %v", s)
+	}
+}
+
+func TestMarkAppliedTransaction(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.on(`^INSERT INTO schema_version`, func(args []driver.Value) (fakeRows, error) { return fakeRows{}, errors.New("lock wait timeout") })
+	c := MigrateConfig{App: "test", BaseDir: "schema", Fs: fstest.MapFS{
+		"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);\nALTER TABLE t ADD COLUMN a INT;")},
+	}}
+	if err := MarkApplied(f.open(t), c, "v0.0.1"); err == nil {
+		t.Fatal("should fail")
+	}
+
+	// the replaced rows and the recorded statements are rolled back with the failed schema_version row
+	var seq []string
+	for _, q := range f.executed(`^(BEGIN|ROLLBACK|COMMIT|DELETE FROM schema_script_sql|INSERT INTO schema_script_sql)`) {
+		seq = append(seq, strings.Fields(q.SQL)[0])
+	}
+	if len(seq) != 5 || seq[0] != "BEGIN" || seq[1] != "DELETE" || seq[4] != "ROLLBACK" {
+		t.Fatalf("incorrect sequence, %v", seq)
+	}
+
+	if _, err := MarkPendingApplied(f.open(t), c); err == nil {
+		t.Fatal("should fail")
+	}
+	if n := len(f.executed(`^COMMIT`)); n != 0 {
+		t.Fatalf("should not commit, %v", n)
+	}
+}
+
+func TestMarkPendingAppliedEmpty(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	c := MigrateConfig{App: "test", BaseDir: "schema", Fs: fstest.MapFS{
+		"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+		"schema/v0.0.2.sql": {Data: []byte("-- ALTER TABLE t ADD COLUMN a INT;")},
+	}}
+	names, err := MarkPendingApplied(f.open(t), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "v0.0.1.sql,v0.0.2.sql" {
+		t.Fatalf("incorrect scripts, %v", names)
+	}
+	var remarks []string
+	for _, q := range f.executed(`^INSERT INTO schema_version`) {
+		for _, a := range q.Args {
+			if s, ok := a.(string); ok && (s == "Applied manually" || s == emptyScriptRemark) {
+				remarks = append(remarks, s)
+			}
+		}
+	}
+	if len(remarks) != 2 || remarks[0] != "Applied manually" || remarks[1] != emptyScriptRemark {
+		t.Fatalf("incorrect remarks, %v", remarks)
+	}
+}
@@ -0,0 +1,68 @@
+package svc
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Category of statement.
+type StmtCategory string
+
+const (
+	StmtDDLCreate StmtCategory = "ddl-create" // CREATE TABLE, CREATE INDEX, CREATE VIEW, etc.
+	StmtDDLAlter  StmtCategory = "ddl-alter"  // ALTER, RENAME
+	StmtDDLDrop   StmtCategory = "ddl-drop"   // DROP, TRUNCATE
+	StmtDML       StmtCategory = "dml"        // INSERT, UPDATE, DELETE, SELECT, etc.
+	StmtDCL       StmtCategory = "dcl"        // GRANT, REVOKE, CREATE USER, etc.
+	StmtOther     StmtCategory = "other"      // SET, PL/SQL blocks, etc.
+)
+
+var (
+	dclObjectRegex = regexp.MustCompile(`(?is)^(CREATE|ALTER|DROP|RENAME)\s+(USER|ROLE)\b`)
+)
+
+// Statement that would be executed in the migration.
+type PlannedStatement struct {
+	SQL      string
+	Category StmtCategory
+}
+
+func planStatement(sql string) PlannedStatement {
+	return PlannedStatement{SQL: sql, Category: ClassifyStatement(sql)}
+}
+
+// Classify statement by the leading keyword, it's a lightweight classification, the statement is not parsed.
+func ClassifyStatement(sql string) StmtCategory {
+	_, rest := splitLeadingComments(sql)
+	rest = strings.TrimSpace(rest)
+	if dclObjectRegex.MatchString(rest) {
+		return StmtDCL
+	}
+
+	kw, _, _ := strings.Cut(rest, " ")
+	if i := strings.IndexAny(kw, "\t\r\n(;"); i > -1 {
+		kw = kw[:i]
+	}
+	switch strings.ToUpper(kw) {
+	case "CREATE":
+		return StmtDDLCreate
+	case "ALTER", "RENAME":
+		return StmtDDLAlter
+	case "DROP", "TRUNCATE":
+		return StmtDDLDrop
+	case "INSERT", "UPDATE", "DELETE", "REPLACE", "SELECT", "WITH", "MERGE", "CALL", "LOAD":
+		return StmtDML
+	case "GRANT", "REVOKE":
+		return StmtDCL
+	}
+	return StmtOther
+}
+
+// Plan the migration, it's a dry run, the pending statements are classified and reported in ScriptResult.Planned,
+// tooling may apply different approval rules for each StmtCategory.
+func Plan(db *gorm.DB, log Logger, c MigrateConfig) (Result, error) {
+	c.DryRun = true
+	return Run(db, log, c)
+}
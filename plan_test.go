@@ -0,0 +1,27 @@
+package svc
+
+import "testing"
+
+func TestClassifyStatement(t *testing.T) {
+	cases := map[string]StmtCategory{
+		"CREATE TABLE t (id INT)":                      StmtDDLCreate,
+		"-- comment\ncreate index idx_a on t (a)":      StmtDDLCreate,
+		"ALTER TABLE t ADD COLUMN a INT":               StmtDDLAlter,
+		"RENAME TABLE t TO r":                          StmtDDLAlter,
+		"DROP TABLE t":                                 StmtDDLDrop,
+		"TRUNCATE t":                                   StmtDDLDrop,
+		"INSERT INTO t (id) VALUES (1)":                StmtDML,
+		"update t set a = 1":                           StmtDML,
+		"WITH x AS (SELECT 1) SELECT * FROM x":         StmtDML,
+		"GRANT SELECT ON tt.* TO 'reader'@'%'":         StmtDCL,
+		"CREATE USER 'reader'@'%' IDENTIFIED BY 'xxx'": StmtDCL,
+		"DROP ROLE reader":                             StmtDCL,
+		"SET @a = 1":                                   StmtOther,
+		"BEGIN\n\tNULL;\nEND;":                         StmtOther,
+	}
+	for sql, cat := range cases {
+		if c := ClassifyStatement(sql); c != cat {
+			t.Errorf("'%v' should be %v, %v", sql, cat, c)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package svc
+
+import (
+	"time"
+)
+
+// Publisher of the schema change events, e.g., the ones in publish/kafka and publish/nats.
+//
+// Events are published after the migration succeeded, so that downstream consumers (e.g., CDC pipelines, cache
+// layers) can react to the new tables and columns. Errors are logged, they don't fail the migration.
+type Publisher interface {
+	Publish(e SchemaChangeEvent) error
+}
+
+// Schema change event published after a successful migration.
+type SchemaChangeEvent struct {
+	App     string `json:"app"`
+	Env     string `json:"env,omitempty"`
+	Version string `json:"version,omitempty"` // latest version executed, empty if only repeatable scripts were executed
+
+	// Names of the executed scripts (including the repeatable ones).
+	Scripts []string `json:"scripts"`
+
+	// DDL executed, in the order of execution.
+	Changes []SchemaChange `json:"changes"`
+
+	MigratedAt time.Time `json:"migrated_at"`
+}
+
+// DDL executed in the migration.
+type SchemaChange struct {
+	Script   string       `json:"script"`
+	Category StmtCategory `json:"category"`
+
+	// Tables referenced by the statement, it's best-effort, e.g., tables are not extracted from the DDL of views.
+	Tables []string `json:"tables,omitempty"`
+
+	// Statement with the placeholders resolved, the values of the secret placeholders are masked, see
+	// MigrateConfig.SecretPlaceholders.
+	SQL string `json:"sql"`
+}
+
+// Build schema change of the executed statement, returns false if it's not DDL.
+func newSchemaChange(script string, stmt string) (SchemaChange, bool) {
+	ch := SchemaChange{Script: script, Category: ClassifyStatement(stmt), SQL: stmt}
+	switch ch.Category {
+	case StmtDDLCreate, StmtDDLAlter, StmtDDLDrop:
+	default:
+		return ch, false
+	}
+	ch.Tables, _ = simulatedTables(stmt)
+	return ch, true
+}
+
+func newSchemaChangeEvent(c MigrateConfig, res Result, at time.Time) SchemaChangeEvent {
+	e := SchemaChangeEvent{App: c.App, Env: c.Env, Scripts: []string{}, Changes: []SchemaChange{}, MigratedAt: at.UTC()}
+	for _, sr := range res.Scripts {
+		e.Scripts = append(e.Scripts, sr.Script)
+		e.Changes = append(e.Changes, sr.changes...)
+		if v, ok := FileVer(sr.Script); ok && (e.Version == "" || VerAfter(v, e.Version)) {
+			e.Version = v
+		}
+	}
+	return e
+}
+
+// Publish schema change event of the migration, nothing is published if no script is executed.
+func publishChanges(log Logger, c MigrateConfig, res Result, at time.Time) {
+	if c.Publisher == nil || res.DryRun || len(res.Scripts) < 1 {
+		return
+	}
+	e := newSchemaChangeEvent(c, res, at)
+	if err := c.Publisher.Publish(e); err != nil {
+		log.Errorf("failed to publish schema change event, %v", err)
+		return
+	}
+	log.Infof("Published schema change event, %d scripts, %d changes", len(e.Scripts), len(e.Changes))
+}
@@ -0,0 +1,54 @@
+// Package kafka publishes svc.SchemaChangeEvent to Kafka.
+//
+// The package doesn't depend on any Kafka client, the client is adapted using Producer, e.g., for
+// github.com/segmentio/kafka-go:
+//
+//	type producer struct{ w *kafkago.Writer }
+//
+//	func (p producer) Produce(topic string, key []byte, value []byte) error {
+//		return p.w.WriteMessages(context.Background(), kafkago.Message{Topic: topic, Key: key, Value: value})
+//	}
+//
+//	c.Publisher = kafka.NewPublisher(producer{w}, "schema-changes")
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/curtisnewbie/svc"
+)
+
+// Producer of Kafka messages.
+type Producer interface {
+	Produce(topic string, key []byte, value []byte) error
+}
+
+// Publisher of svc.SchemaChangeEvent, events are encoded as JSON, and keyed by the app, so that the events of the
+// same app are delivered to the same partition in order.
+type Publisher struct {
+	Producer Producer
+	Topic    string
+}
+
+func NewPublisher(p Producer, topic string) *Publisher {
+	return &Publisher{Producer: p, Topic: topic}
+}
+
+func (p *Publisher) Publish(e svc.SchemaChangeEvent) error {
+	if p.Producer == nil {
+		return errors.New("producer is nil")
+	}
+	if p.Topic == "" {
+		return errors.New("topic is empty")
+	}
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema change event, %w", err)
+	}
+	if err := p.Producer.Produce(p.Topic, []byte(e.App), buf); err != nil {
+		return fmt.Errorf("failed to produce to topic %v, %w", p.Topic, err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package kafka
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/curtisnewbie/svc"
+)
+
+type captureProducer struct {
+	topic string
+	key   []byte
+	value []byte
+}
+
+func (p *captureProducer) Produce(topic string, key []byte, value []byte) error {
+	p.topic, p.key, p.value = topic, key, value
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	cp := &captureProducer{}
+	p := NewPublisher(cp, "schema-changes")
+	if err := p.Publish(svc.SchemaChangeEvent{App: "myapp", Version: "v0.0.3"}); err != nil {
+		t.Fatal(err)
+	}
+	if cp.topic != "schema-changes" || string(cp.key) != "myapp" {
+		t.Fatalf("incorrect message, %v, %s", cp.topic, cp.key)
+	}
+	var e svc.SchemaChangeEvent
+	if err := json.Unmarshal(cp.value, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.App != "myapp" || e.Version != "v0.0.3" {
+		t.Fatalf("incorrect event, %+v", e)
+	}
+	if err := NewPublisher(cp, "").Publish(e); err == nil {
+		t.Fatal("should fail without topic")
+	}
+}
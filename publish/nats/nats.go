@@ -0,0 +1,54 @@
+// Package nats publishes svc.SchemaChangeEvent to NATS.
+//
+// The package doesn't depend on any NATS client, *nats.Conn of github.com/nats-io/nats.go satisfies Conn, e.g.,
+//
+//	nc, _ := nats.Connect(nats.DefaultURL)
+//	c.Publisher = svcnats.NewPublisher(nc, "")
+package nats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/curtisnewbie/svc"
+)
+
+const (
+	// prefix of the default subject, i.e., 'svc.schema.<app>'
+	DefaultSubjectPrefix = "svc.schema."
+)
+
+// Connection to NATS.
+type Conn interface {
+	Publish(subject string, data []byte) error
+}
+
+// Publisher of svc.SchemaChangeEvent, events are encoded as JSON, and published to Subject, or 'svc.schema.<app>'
+// if Subject is empty.
+type Publisher struct {
+	Conn    Conn
+	Subject string
+}
+
+func NewPublisher(conn Conn, subject string) *Publisher {
+	return &Publisher{Conn: conn, Subject: subject}
+}
+
+func (p *Publisher) Publish(e svc.SchemaChangeEvent) error {
+	if p.Conn == nil {
+		return errors.New("conn is nil")
+	}
+	subject := p.Subject
+	if subject == "" {
+		subject = DefaultSubjectPrefix + e.App
+	}
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema change event, %w", err)
+	}
+	if err := p.Conn.Publish(subject, buf); err != nil {
+		return fmt.Errorf("failed to publish to subject %v, %w", subject, err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package nats
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/curtisnewbie/svc"
+)
+
+type captureConn struct {
+	subject string
+	data    []byte
+}
+
+func (c *captureConn) Publish(subject string, data []byte) error {
+	c.subject, c.data = subject, data
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	cc := &captureConn{}
+	if err := NewPublisher(cc, "").Publish(svc.SchemaChangeEvent{App: "myapp", Version: "v0.0.3"}); err != nil {
+		t.Fatal(err)
+	}
+	if cc.subject != "svc.schema.myapp" {
+		t.Fatalf("incorrect subject, %v", cc.subject)
+	}
+	var e svc.SchemaChangeEvent
+	if err := json.Unmarshal(cc.data, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.App != "myapp" || e.Version != "v0.0.3" {
+		t.Fatalf("incorrect event, %+v", e)
+	}
+	if err := NewPublisher(cc, "schema.changes").Publish(e); err != nil || cc.subject != "schema.changes" {
+		t.Fatalf("incorrect subject, %v, %v", cc.subject, err)
+	}
+}
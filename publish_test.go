@@ -0,0 +1,92 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type capturePublisher struct {
+	events []SchemaChangeEvent
+	err    error
+}
+
+func (p *capturePublisher) Publish(e SchemaChangeEvent) error {
+	p.events = append(p.events, e)
+	return p.err
+}
+
+func TestNewSchemaChange(t *testing.T) {
+	ch, ok := newSchemaChange("v0.0.2.sql", "ALTER TABLE orders ADD COLUMN remark TEXT")
+	if !ok || ch.Category != StmtDDLAlter || len(ch.Tables) != 1 || ch.Tables[0] != "orders" {
+		t.Fatalf("incorrect schema change, %+v", ch)
+	}
+	if _, ok := newSchemaChange("v0.0.2.sql", "UPDATE orders SET remark = ''"); ok {
+		t.Fatal("DML is not schema change")
+	}
+}
+
+func TestPublishChanges(t *testing.T) {
+	p := &capturePublisher{}
+	c := MigrateConfig{App: "myapp", Env: "prod", Publisher: p}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ch, _ := newSchemaChange("v0.0.3.sql", "CREATE TABLE orders (id INT)")
+	res := Result{Scripts: []ScriptResult{
+		{Script: "v0.0.2.sql"},
+		{Script: "v0.0.3.sql", changes: []SchemaChange{ch}},
+		{Script: "views/v_orders.sql"},
+	}}
+
+	publishChanges(PrintLogger{}, c, res, at)
+	if len(p.events) != 1 {
+		t.Fatalf("should publish 1 event, %v", len(p.events))
+	}
+	e := p.events[0]
+	if e.App != "myapp" || e.Env != "prod" || e.Version != "v0.0.3" || len(e.Scripts) != 3 || !e.MigratedAt.Equal(at) {
+		t.Fatalf("incorrect event, %+v", e)
+	}
+	if len(e.Changes) != 1 || e.Changes[0].Script != "v0.0.3.sql" || e.Changes[0].Tables[0] != "orders" {
+		t.Fatalf("incorrect changes, %+v", e.Changes)
+	}
+
+	// nothing executed, or dry run
+	publishChanges(PrintLogger{}, c, Result{}, at)
+	publishChanges(PrintLogger{}, c, Result{DryRun: true, Scripts: res.Scripts}, at)
+	if len(p.events) != 1 {
+		t.Fatalf("should not publish, %v", len(p.events))
+	}
+
+	// errors are not propagated
+	p.err = errors.New("unavailable")
+	publishChanges(PrintLogger{}, c, res, at)
+	if len(p.events) != 2 {
+		t.Fatalf("should publish, %v", len(p.events))
+	}
+}
+
+func TestPublishedSecrets(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	p := &capturePublisher{}
+	_, err := Run(f.open(t), PrintLogger{}, MigrateConfig{
+		App:          "test",
+		Fs:           fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("ALTER TABLE orders COMMENT '${api_token} ${region}';")}},
+		BaseDir:      "schema",
+		Placeholders: map[string]string{"api_token": "s3cr3t", "region": "eu"},
+		Publisher:    p,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^ALTER TABLE orders`); len(q) != 1 || q[0].SQL != "ALTER TABLE orders COMMENT 's3cr3t eu'" {
+		t.Fatalf("incorrect statement executed, %+v", q)
+	}
+	if len(p.events) != 1 || len(p.events[0].Changes) != 1 {
+		t.Fatalf("incorrect events, %+v", p.events)
+	}
+	if s := p.events[0].Changes[0].SQL; s != "ALTER TABLE orders COMMENT '"+maskedPlaceholder+" eu'" {
+		t.Fatalf("secret should be masked, %v", s)
+	}
+}
@@ -0,0 +1,56 @@
+package svc
+
+// Reason of the remark recorded in schema_version, see RemarkFormatter.
+type RemarkReason string
+
+const (
+	RemarkExecuted    RemarkReason = "executed"    // the script is executed
+	RemarkEmpty       RemarkReason = "empty"       // the script contains no statement
+	RemarkInitialized RemarkReason = "initialized" // schema_version is initialized at the version of the script
+	RemarkApplied     RemarkReason = "applied"     // the script is marked as applied, e.g., MarkApplied
+)
+
+// Details of the remark to be recorded in schema_version.
+type RemarkContext struct {
+	App    string
+	Env    string
+	Script string
+	Kind   string // 'versioned' or 'run-always'
+	Reason RemarkReason
+
+	// Author and ticket of the script, declared with the '-- author:' and '-- ticket:' header comments.
+	Author string
+	Ticket string
+
+	// Remark recorded by default, e.g., 'Executed'.
+	Default string
+}
+
+// Formatter of the remarks recorded in schema_version for the scripts applied successfully, e.g., to embed the
+// release identifier or the change ticket in the history.
+//
+// The remarks of the failed and ignored scripts are not formatted, svc relies on their prefixes. The remark is
+// truncated to 255 characters, the default one is used if the formatted remark is empty.
+type RemarkFormatter interface {
+	FormatRemark(r RemarkContext) string
+}
+
+// Function as RemarkFormatter.
+type RemarkFormatterFunc func(r RemarkContext) string
+
+func (f RemarkFormatterFunc) FormatRemark(r RemarkContext) string {
+	return f(r)
+}
+
+// Remark of the script applied successfully.
+func (c MigrateConfig) remark(reason RemarkReason, sf schemaFile, def string) string {
+	if c.RemarkFormatter == nil {
+		return def
+	}
+	r := c.RemarkFormatter.FormatRemark(RemarkContext{App: c.App, Env: c.Env, Script: sf.Name, Kind: sf.kind(), Reason: reason,
+		Author: sf.Author, Ticket: sf.Ticket, Default: def})
+	if r == "" {
+		return def
+	}
+	return r
+}
@@ -0,0 +1,28 @@
+package svc
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRemarkFormatter(t *testing.T) {
+	sf := schemaFile{Name: "v0.0.2.sql", Ticket: "PROJ-123"}
+	var c MigrateConfig
+	if r := c.remark(RemarkExecuted, sf, "Executed"); r != "Executed" {
+		t.Fatalf("incorrect remark, %v", r)
+	}
+
+	c.App = "myapp"
+	c.RemarkFormatter = RemarkFormatterFunc(func(r RemarkContext) string {
+		if r.Reason == RemarkApplied {
+			return ""
+		}
+		return fmt.Sprintf("%v (release 2.1, %v)", r.Default, r.Ticket)
+	})
+	if r := c.remark(RemarkExecuted, sf, "Executed"); r != "Executed (release 2.1, PROJ-123)" {
+		t.Fatalf("incorrect remark, %v", r)
+	}
+	if r := c.remark(RemarkApplied, sf, "Applied manually"); r != "Applied manually" {
+		t.Fatalf("empty remark should fallback to the default one, %v", r)
+	}
+}
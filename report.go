@@ -0,0 +1,139 @@
+package svc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	ansiReset = "\033[0m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiCyan  = "\033[36m"
+	ansiBold  = "\033[1m"
+)
+
+// Reporter of the migration progress, it's notified when each script starts and finishes, and when the migration
+// finishes. Unlike Logger, it's meant for humans watching the migration, see PrettyReporter.
+type Reporter interface {
+	// The script is about to be executed.
+	ScriptStarted(script string)
+
+	// The script is executed, err is not nil if the script failed.
+	ScriptFinished(sr ScriptResult, elapsed time.Duration, err error)
+
+	// The migration is finished, err is not nil if the migration failed.
+	Finished(app string, res Result, elapsed time.Duration, err error)
+}
+
+type reportedScript struct {
+	ScriptResult
+	Elapsed time.Duration
+	Err     error
+}
+
+// Reporter writing colored, human-friendly output, e.g.,
+//
+//	▶ v0.0.2.sql
+//	✔ v0.0.2.sql (3 statements, 12 rows, 120ms)
+//
+// A summary table of the executed scripts is written when the migration is finished.
+type PrettyReporter struct {
+	w io.Writer
+
+	// Disable ANSI colors, e.g., when the output is not a terminal.
+	NoColor bool
+
+	// Elapsed times are not written, same as MigrateConfig.Deterministic.
+	NoElapsed bool
+
+	mu      sync.Mutex
+	scripts []reportedScript
+}
+
+func NewPrettyReporter(w io.Writer) *PrettyReporter {
+	return &PrettyReporter{w: w}
+}
+
+func (p *PrettyReporter) paint(color string, s string) string {
+	if p.NoColor {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+func (p *PrettyReporter) elapsed(d time.Duration) string {
+	if p.NoElapsed {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+func (p *PrettyReporter) ScriptStarted(script string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.w, "%s %s\n", p.paint(ansiCyan, "▶"), script)
+}
+
+func (p *PrettyReporter) ScriptFinished(sr ScriptResult, elapsed time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scripts = append(p.scripts, reportedScript{ScriptResult: sr, Elapsed: elapsed, Err: err})
+
+	detail := fmt.Sprintf("%d statements, %d rows", sr.Statements, sr.RowsAffected)
+	if !p.NoElapsed {
+		detail += ", " + p.elapsed(elapsed)
+	}
+	if err != nil {
+		fmt.Fprintf(p.w, "%s %s (%s)\n  %s\n", p.paint(ansiRed, "✘"), sr.Script, detail, p.paint(ansiRed, err.Error()))
+		return
+	}
+	fmt.Fprintf(p.w, "%s %s (%s)\n", p.paint(ansiGreen, "✔"), sr.Script, detail)
+}
+
+func (p *PrettyReporter) Finished(app string, res Result, elapsed time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.scripts) > 0 {
+		fmt.Fprintln(p.w)
+		tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SCRIPT\tSTATUS\tSTATEMENTS\tROWS\tTIME")
+		for _, s := range p.scripts {
+			status := "ok"
+			if s.Err != nil {
+				status = "failed"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%s\n", s.Script, status, s.Statements, s.RowsAffected, p.elapsed(s.Elapsed))
+		}
+		tw.Flush()
+		fmt.Fprintln(p.w)
+	}
+
+	statements := 0
+	for _, sr := range res.Scripts {
+		statements += sr.Statements
+	}
+	took := ""
+	if !p.NoElapsed {
+		took = " in " + p.elapsed(elapsed)
+	}
+	mode := ""
+	if res.DryRun {
+		mode = " (dry run)"
+	}
+	if err != nil {
+		fmt.Fprintf(p.w, "%s %s\n  %s\n", p.paint(ansiRed+ansiBold, "✘"),
+			p.paint(ansiBold, fmt.Sprintf("%s migration failed%s after %d scripts%s", app, mode, len(res.Scripts), took)),
+			p.paint(ansiRed, strings.TrimSpace(err.Error())))
+	} else {
+		fmt.Fprintf(p.w, "%s %s\n", p.paint(ansiGreen+ansiBold, "✔"),
+			p.paint(ansiBold, fmt.Sprintf("%s migrated%s, %d scripts, %d statements, %d rows affected%s", app, mode,
+				len(res.Scripts), statements, res.RowsAffected, took)))
+	}
+	p.scripts = nil
+}
@@ -0,0 +1,39 @@
+package svc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrettyReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewPrettyReporter(&buf)
+	r.NoColor = true
+	r.ScriptStarted("v0.0.2.sql")
+	r.ScriptFinished(ScriptResult{Script: "v0.0.2.sql", Statements: 2, RowsAffected: 3}, 1500*time.Millisecond, nil)
+	r.ScriptStarted("v0.0.3.sql")
+	r.ScriptFinished(ScriptResult{Script: "v0.0.3.sql"}, time.Millisecond, errors.New("table t exists"))
+	r.Finished("myapp", Result{Scripts: []ScriptResult{{Script: "v0.0.2.sql", Statements: 2, RowsAffected: 3}, {Script: "v0.0.3.sql"}},
+		RowsAffected: 3}, 2*time.Second, errors.New("table t exists"))
+
+	out := buf.String()
+	for _, s := range []string{
+		"▶ v0.0.2.sql\n",
+		"✔ v0.0.2.sql (2 statements, 3 rows, 1.5s)\n",
+		"✘ v0.0.3.sql (0 statements, 0 rows, 1ms)\n  table t exists\n",
+		"SCRIPT      STATUS  STATEMENTS  ROWS  TIME\n",
+		"v0.0.2.sql  ok      2           3     1.5s\n",
+		"v0.0.3.sql  failed  0           0     1ms\n",
+		"✘ myapp migration failed after 2 scripts in 2s\n",
+	} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("missing '%v' in output:\n%v", s, out)
+		}
+	}
+	if strings.Contains(out, "\033[") {
+		t.Fatalf("output should not be colored:\n%v", out)
+	}
+}
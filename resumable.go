@@ -0,0 +1,132 @@
+package svc
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// placeholders of the batch range in the statements of resumable scripts, i.e., key > from AND key <= to
+	batchFromPlaceholder = "svc.from"
+	batchToPlaceholder   = "svc.to"
+)
+
+// Batching declared in script using '-- svc:resumable <table>.<column> <batch size>'.
+//
+// The statements containing '${svc.from}' are executed repeatedly, one batch at a time, with '${svc.from}' and
+// '${svc.to}' replaced by the range of the numeric key column, e.g.,
+//
+//	-- svc:resumable orders.id 10000
+//	UPDATE orders SET status = 'closed' WHERE id > ${svc.from} AND id <= ${svc.to} AND status = 'expired';
+//
+// The key of the last processed batch is saved in schema_cursor, if the script fails and it's retried, or the process is killed in the middle of the
+// newest script, the batches continue from the saved key instead of starting over.
+type resumable struct {
+	Table     string
+	Column    string
+	BatchSize int64
+}
+
+func parseResumable(arg string) (*resumable, error) {
+	fields := strings.Fields(arg)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("malformed resumable '%v', expected '<table>.<column> <batch size>'", arg)
+	}
+	i := strings.LastIndex(fields[0], ".")
+	if i < 1 || i == len(fields[0])-1 {
+		return nil, fmt.Errorf("malformed resumable '%v', missing key column", arg)
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || size < 1 {
+		return nil, fmt.Errorf("malformed resumable '%v', invalid batch size '%v'", arg, fields[1])
+	}
+	return &resumable{Table: fields[0][:i], Column: fields[0][i+1:], BatchSize: size}, nil
+}
+
+// Check if the statement is executed in batches.
+func isBatched(sql string) bool {
+	return strings.Contains(sql, "${"+batchFromPlaceholder+"}")
+}
+
+// Resolve the batch range placeholders.
+func resolveBatch(sql string, from int64, to int64) string {
+	return resolvePlaceholders(sql, map[string]string{
+		batchFromPlaceholder: strconv.FormatInt(from, 10),
+		batchToPlaceholder:   strconv.FormatInt(to, 10),
+	})
+}
+
+// Execute the statement in batches, the cursor is saved after each batch, returns the total number of rows affected.
+func runBatches(conn *gorm.DB, meta *stmtCache, log Logger, c MigrateConfig, sf schemaFile, i int, stmt string) (int64, error) {
+	r := sf.Resumable
+	idx := sf.pos(i).Index
+	table, column := resolvePlaceholders(r.Table, c.Placeholders), r.Column
+
+	var from int64
+	saved, err := meta.queryRow(`SELECT last_key FROM schema_cursor WHERE app = ? AND script = ? AND stmt_index = ?`+meta.forUpdate(),
+		[]any{meta.app(c.App), sf.Name, idx}, &from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query schema_cursor, %w", err)
+	}
+	if saved {
+		log.Infof("'%v' - [%v] resuming batches after %v = %v", sf.Name, i+1, column, from)
+	} else {
+		var min sql.NullInt64
+		if err := conn.Raw(fmt.Sprintf("SELECT MIN(%s) FROM %s", column, table)).Row().Scan(&min); err != nil {
+			return 0, fmt.Errorf("failed to query MIN(%v) of %v, %w", column, table, err)
+		}
+		if !min.Valid {
+			return 0, nil
+		}
+		from = min.Int64 - 1
+	}
+
+	var max sql.NullInt64
+	if err := conn.Raw(fmt.Sprintf("SELECT MAX(%s) FROM %s", column, table)).Row().Scan(&max); err != nil {
+		return 0, fmt.Errorf("failed to query MAX(%v) of %v, %w", column, table, err)
+	}
+
+	var total int64
+	for batch := 0; max.Valid && from < max.Int64; batch++ {
+		to := from + r.BatchSize
+
+		// the first batch is throttled along with the statement
+		if batch > 0 {
+			if err := c.Throttle.wait(meta.ctx, log, sf.Name, i+1); err != nil {
+				return total, err
+			}
+		}
+		n, err := executorOrDefault(c.Executor).Exec(conn, Statement{Script: sf.Name, Index: idx, SQL: resolveBatch(stmt, from, to)})
+		if err != nil {
+			return total, fmt.Errorf("failed to exec batch (%v, %v], %w", from, to, err)
+		}
+		total += n
+
+		if saved {
+			_, err = meta.exec(`UPDATE schema_cursor SET last_key = ?, updated_at = ? WHERE app = ? AND script = ? AND stmt_index = ?`,
+				to, meta.timeArg(meta.now()), meta.app(c.App), sf.Name, idx)
+		} else {
+			_, err = meta.exec(`INSERT INTO schema_cursor (app, script, stmt_index, last_key, updated_at) VALUES (?,?,?,?,?)`,
+				meta.app(c.App), sf.Name, idx, to, meta.timeArg(meta.now()))
+		}
+		if err != nil {
+			return total, fmt.Errorf("failed to save schema_cursor, %w", err)
+		}
+		saved = true
+		log.Infof("'%v' - [%v] batch (%v, %v] executed, rows affected: %v", sf.Name, i+1, from, to, n)
+		from = to
+	}
+	return total, nil
+}
+
+// Remove the cursors of the completed script.
+func clearCursors(meta *stmtCache, app string, script string) error {
+	if _, err := meta.exec(`DELETE FROM schema_cursor WHERE app = ? AND script = ?`, meta.app(app), script); err != nil {
+		return fmt.Errorf("failed to delete schema_cursor, %w", err)
+	}
+	return nil
+}
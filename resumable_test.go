@@ -0,0 +1,60 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResumeInterruptedScript(t *testing.T) {
+	script := "-- svc:resumable orders.id 100\nALTER TABLE orders ADD COLUMN status INT;\n" +
+		"UPDATE orders SET status = 1 WHERE id > ${svc.from} AND id <= ${svc.to};"
+	newDB := func(cursor bool) *fakeDB {
+		f := &fakeDB{}
+		f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+		f.reply(`^SELECT id, script, success, remark FROM schema_version`, []string{"id", "script", "success", "remark"},
+			[]driver.Value{int64(1), "v0.0.1.sql", true, "Executed"})
+		f.reply(`^SELECT id, stmt, rows_affected FROM schema_script_sql`, []string{"id", "stmt", "rows_affected"},
+			[]driver.Value{int64(10), "ALTER TABLE orders ADD COLUMN status INT", int64(0)},
+			[]driver.Value{int64(11), "UPDATE orders SET status = 1 WHERE id > ${svc.from} AND id <= ${svc.to}", nil})
+		if cursor {
+			f.reply(`^SELECT stmt_index FROM schema_cursor`, []string{"stmt_index"}, []driver.Value{int64(2)})
+			f.reply(`^SELECT last_key FROM schema_cursor`, []string{"last_key"}, []driver.Value{int64(100)})
+		}
+		f.reply(`^SELECT MIN\(id\) FROM orders$`, []string{"min"}, []driver.Value{int64(1)})
+		f.reply(`^SELECT MAX\(id\) FROM orders$`, []string{"max"}, []driver.Value{int64(150)})
+		return f
+	}
+	c := MigrateConfig{
+		App: "test",
+		Fs: fstest.MapFS{
+			"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE orders (id INT);")},
+			"schema/v0.0.2.sql": {Data: []byte(script)},
+		},
+		BaseDir: "schema",
+	}
+
+	// killed during the backfill, it's resumed from the cursor
+	f := newDB(true)
+	if _, err := Run(f.open(t), PrintLogger{}, c); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^ALTER TABLE orders`); len(q) > 0 {
+		t.Fatalf("finished statement should not be executed again, %+v", q)
+	}
+	if q := f.executed(`^UPDATE orders SET status = 1`); len(q) != 1 || q[0].SQL != "UPDATE orders SET status = 1 WHERE id > 100 AND id <= 200" {
+		t.Fatalf("backfill should be resumed from the cursor, %+v", q)
+	}
+	if q := f.executed(`^DELETE FROM schema_script_sql WHERE id IN`); len(q) != 1 || q[0].Args[0] != int64(11) {
+		t.Fatalf("record of the unfinished statement should be removed, %+v", q)
+	}
+
+	// killed before the first batch is saved
+	f = newDB(false)
+	if _, err := Run(f.open(t), PrintLogger{}, c); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^UPDATE orders SET status = 1`); len(q) != 2 || q[0].SQL != "UPDATE orders SET status = 1 WHERE id > 0 AND id <= 100" {
+		t.Fatalf("backfill should be executed again, %+v", q)
+	}
+}
@@ -0,0 +1,109 @@
+package svc
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	createTableRegex = regexp.MustCompile(`(?i)^CREATE\s+(TEMPORARY\s+)?TABLE\s+`)
+	createIndexRegex = regexp.MustCompile(`(?i)^CREATE\s+(UNIQUE\s+|FULLTEXT\s+|SPATIAL\s+)?INDEX\s+`)
+	alterTableRegex  = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+`)
+	addColumnRegex   = regexp.MustCompile(`(?i)\bADD\s+COLUMN\s+`)
+	ifNotExistsRegex = regexp.MustCompile(`(?i)^IF\s+NOT\s+EXISTS\s+`)
+	placeholderRegex = regexp.MustCompile(`\$\{([\w.-]+)\}`)
+	alterClauseRegex = regexp.MustCompile(`(?i)\b(?:ALGORITHM|LOCK)\s*=`)
+)
+
+const (
+	// Default clauses appended to ALTER TABLE statements, see MigrateConfig.OnlineAlter.
+	DefaultOnlineAlter = "ALGORITHM=INPLACE, LOCK=NONE"
+)
+
+// Replace placeholders, e.g., '${schema}', with the provided values, unknown placeholders are left as is.
+func resolvePlaceholders(sql string, placeholders map[string]string) string {
+	if len(placeholders) < 1 {
+		return sql
+	}
+	return placeholderRegex.ReplaceAllStringFunc(sql, func(s string) string {
+		if v, ok := placeholders[s[2:len(s)-1]]; ok {
+			return v
+		}
+		return s
+	})
+}
+
+// Split the leading comments (and whitespaces) from the sql statement.
+func splitLeadingComments(sql string) (comments string, rest string) {
+	rest = sql
+	for {
+		trimmed := strings.TrimLeft(rest, " \t\r\n")
+		switch {
+		case strings.HasPrefix(trimmed, "--") || strings.HasPrefix(trimmed, "#"):
+			i := strings.Index(trimmed, "\n")
+			if i < 0 {
+				return sql, ""
+			}
+			rest = trimmed[i+1:]
+		case strings.HasPrefix(trimmed, "/*"):
+			i := strings.Index(trimmed, "*/")
+			if i < 0 {
+				return sql, ""
+			}
+			rest = trimmed[i+2:]
+		default:
+			return sql[:len(sql)-len(trimmed)], trimmed
+		}
+	}
+}
+
+// Rewrite CREATE TABLE statement to CREATE TABLE IF NOT EXISTS.
+//
+// MySQL doesn't support CREATE INDEX IF NOT EXISTS, so only CREATE TABLE statements are rewritten. For MariaDB,
+// CREATE INDEX and ALTER TABLE ... ADD COLUMN statements are rewritten as well.
+func rewriteIfNotExists(sql string, fl Flavor) string {
+	comments, rest := splitLeadingComments(sql)
+	loc := createTableRegex.FindStringIndex(rest)
+	if loc == nil && fl.SupportsIfNotExistsDDL() {
+		if loc = createIndexRegex.FindStringIndex(rest); loc == nil {
+			return comments + rewriteAddColumn(rest)
+		}
+	}
+	if loc == nil {
+		return sql
+	}
+	if ifNotExistsRegex.MatchString(rest[loc[1]:]) {
+		return sql
+	}
+	return comments + rest[:loc[1]] + "IF NOT EXISTS " + rest[loc[1]:]
+}
+
+// Rewrite ALTER TABLE ... ADD COLUMN to ADD COLUMN IF NOT EXISTS (MariaDB only).
+func rewriteAddColumn(sql string) string {
+	if !alterTableRegex.MatchString(sql) {
+		return sql
+	}
+	var b strings.Builder
+	for {
+		loc := addColumnRegex.FindStringIndex(sql)
+		if loc == nil {
+			b.WriteString(sql)
+			return b.String()
+		}
+		b.WriteString(sql[:loc[1]])
+		if !ifNotExistsRegex.MatchString(sql[loc[1]:]) {
+			b.WriteString("IF NOT EXISTS ")
+		}
+		sql = sql[loc[1]:]
+	}
+}
+
+// Append the clauses (e.g., 'ALGORITHM=INPLACE, LOCK=NONE') to ALTER TABLE statement, statements specifying
+// ALGORITHM or LOCK explicitly are not changed.
+func appendAlterClauses(sql string, clauses string) string {
+	comments, rest := splitLeadingComments(sql)
+	if clauses == "" || !alterTableRegex.MatchString(rest) || alterClauseRegex.MatchString(rest) {
+		return sql
+	}
+	return comments + strings.TrimRight(strings.TrimSpace(rest), ";") + ", " + clauses
+}
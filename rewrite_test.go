@@ -0,0 +1,145 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRewriteIfNotExists(t *testing.T) {
+	cases := [][2]string{
+		{"CREATE TABLE t (id INT)", "CREATE TABLE IF NOT EXISTS t (id INT)"},
+		{"create temporary table t (id INT)", "create temporary table IF NOT EXISTS t (id INT)"},
+		{"-- comment\nCREATE TABLE t (id INT)", "-- comment\nCREATE TABLE IF NOT EXISTS t (id INT)"},
+		{"CREATE TABLE IF NOT EXISTS t (id INT)", "CREATE TABLE IF NOT EXISTS t (id INT)"},
+		{"CREATE INDEX idx ON t (id)", "CREATE INDEX idx ON t (id)"},
+		{"ALTER TABLE t ADD COLUMN name VARCHAR(10)", "ALTER TABLE t ADD COLUMN name VARCHAR(10)"},
+	}
+	for _, c := range cases {
+		if v := rewriteIfNotExists(c[0], Flavor{Name: FlavorMySQL}); v != c[1] {
+			t.Fatalf("'%v' should be rewritten to '%v', but got '%v'", c[0], c[1], v)
+		}
+	}
+}
+
+func TestResolvePlaceholders(t *testing.T) {
+	v := resolvePlaceholders("GRANT SELECT ON ${schema}.* TO '${user}'@'%'", map[string]string{"schema": "myapp"})
+	if v != "GRANT SELECT ON myapp.* TO '${user}'@'%'" {
+		t.Fatalf("incorrect statement, %v", v)
+	}
+}
+
+func TestRewriteIfNotExistsMariaDB(t *testing.T) {
+	fl := Flavor{Name: FlavorMariaDB, Version: "10.11.6"}
+	cases := [][2]string{
+		{"CREATE TABLE t (id INT)", "CREATE TABLE IF NOT EXISTS t (id INT)"},
+		{"CREATE UNIQUE INDEX idx ON t (id)", "CREATE UNIQUE INDEX IF NOT EXISTS idx ON t (id)"},
+		{"ALTER TABLE t ADD COLUMN a INT, ADD COLUMN IF NOT EXISTS b INT, ADD COLUMN c INT",
+			"ALTER TABLE t ADD COLUMN IF NOT EXISTS a INT, ADD COLUMN IF NOT EXISTS b INT, ADD COLUMN IF NOT EXISTS c INT"},
+		{"UPDATE t SET name = 'ADD COLUMN x'", "UPDATE t SET name = 'ADD COLUMN x'"},
+	}
+	for _, c := range cases {
+		if v := rewriteIfNotExists(c[0], fl); v != c[1] {
+			t.Fatalf("'%v' should be rewritten to '%v', but got '%v'", c[0], c[1], v)
+		}
+	}
+}
+
+func TestRewriteIfNotExistsOracle(t *testing.T) {
+	f := &fakeDB{}
+	_, err := Run(f.open(t), PrintLogger{}, MigrateConfig{
+		App:                "test",
+		Fs:                 fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id NUMBER);")}},
+		BaseDir:            "schema",
+		Dialect:            OracleDialect{},
+		RewriteIfNotExists: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "RewriteIfNotExists") {
+		t.Fatalf("RewriteIfNotExists should be rejected by Oracle dialect, %v", err)
+	}
+	if q := f.executed(`^CREATE TABLE`); len(q) > 0 {
+		t.Fatalf("nothing should be executed, %+v", q)
+	}
+}
+
+func TestAppendAlterClauses(t *testing.T) {
+	cases := [][2]string{
+		{"ALTER TABLE t ADD COLUMN a INT", "ALTER TABLE t ADD COLUMN a INT, ALGORITHM=INPLACE, LOCK=NONE"},
+		{"-- add a\nALTER TABLE t ADD INDEX a_idx (a)", "-- add a\nALTER TABLE t ADD INDEX a_idx (a), ALGORITHM=INPLACE, LOCK=NONE"},
+		{"ALTER TABLE t ADD COLUMN a INT, ALGORITHM=INSTANT", "ALTER TABLE t ADD COLUMN a INT, ALGORITHM=INSTANT"},
+		{"ALTER TABLE t DROP COLUMN a, LOCK = SHARED", "ALTER TABLE t DROP COLUMN a, LOCK = SHARED"},
+		{"CREATE TABLE t (id INT)", "CREATE TABLE t (id INT)"},
+	}
+	for _, c := range cases {
+		if v := appendAlterClauses(c[0], DefaultOnlineAlter); v != c[1] {
+			t.Fatalf("'%v' should be rewritten to '%v', but got '%v'", c[0], c[1], v)
+		}
+	}
+
+	sf, err := parseScript("-- svc:online-alter\nALTER TABLE t ADD COLUMN a INT;", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.OnlineAlter != DefaultOnlineAlter {
+		t.Fatalf("incorrect online alter, %v", sf.OnlineAlter)
+	}
+	sf, err = parseScript("-- svc:online-alter ALGORITHM=INSTANT\nALTER TABLE t ADD COLUMN a INT;", server{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf.OnlineAlter != "ALGORITHM=INSTANT" {
+		t.Fatalf("incorrect online alter, %v", sf.OnlineAlter)
+	}
+}
+
+func TestPlaceholdersApplied(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT COUNT\(\*\) FROM tt.t$`, []string{"cnt"}, []driver.Value{int64(0)})
+	mfs := fstest.MapFS{
+		"schema/v0.0.1.sql": {Data: []byte(`-- svc:check SELECT id FROM ${schema}.legacy WHERE broken = 1
+-- svc:assert SELECT COUNT(*) FROM ${schema}.t = 0
+CREATE TABLE ${schema}.t (id INT);`)},
+		"schema/views/v_t.sql": {Data: []byte("CREATE VIEW v_t AS SELECT id FROM ${schema}.t;")},
+	}
+	c := MigrateConfig{App: "test", Fs: mfs, BaseDir: "schema", Placeholders: map[string]string{"schema": "tt"}}
+	if _, err := Run(f.open(t), PrintLogger{}, c); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`^SELECT id FROM tt.legacy WHERE broken = 1`,
+		`^CREATE TABLE tt.t \(id INT\)$`,
+		`^SELECT COUNT\(\*\) FROM tt.t$`,
+		`^CREATE VIEW v_t AS SELECT id FROM tt.t$`,
+	} {
+		if len(f.executed(q)) != 1 {
+			t.Errorf("'%v' should be executed", q)
+		}
+	}
+	if q := f.executed(`\$\{schema\}`); len(q) > 0 {
+		t.Fatalf("placeholders should be replaced, %+v", q)
+	}
+	if r := f.executed(`^INSERT INTO schema_script_sql`); len(r) != 1 || !strings.Contains(r[0].Args[2].(string), "${schema}") {
+		t.Fatalf("the original statement should be recorded, %+v", r)
+	}
+	object := f.executed(`^INSERT INTO schema_object`)
+	if len(object) != 1 || object[0].Args[3] != checksum([]byte("CREATE VIEW v_t AS SELECT id FROM tt.t;")) {
+		t.Fatalf("checksum should cover the placeholder values, %+v", object)
+	}
+
+	// undo statement
+	f = &fakeDB{}
+	f.reply(`^SELECT id, script, success, remark FROM schema_version`, []string{"id", "script", "success", "remark"},
+		[]driver.Value{int64(1), "v0.0.1.sql", true, "Executed"})
+	f.reply(`^SELECT id, script, stmt, undo_stmt, reversible, skipped, rows_affected FROM schema_script_sql`,
+		[]string{"id", "script", "stmt", "undo_stmt", "reversible", "skipped", "rows_affected"},
+		[]driver.Value{int64(1), "v0.0.1.sql", "CREATE TABLE ${schema}.t (id INT)", "DROP TABLE ${schema}.t", true, false, int64(0)})
+	c.Fs = nil
+	if err := Rollback(f.open(t), PrintLogger{}, c, "v0.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.executed(`^DROP TABLE tt.t$`)) != 1 {
+		t.Fatalf("placeholders of the undo statement should be replaced, %+v", f.executed(`^DROP`))
+	}
+}
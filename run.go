@@ -0,0 +1,145 @@
+package svc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Record of a migration run, saved in schema_run for each invocation (except dry run).
+type RunRecord struct {
+	Id           int64
+	App          string
+	StartedAt    time.Time
+	EndedAt      time.Time
+	Host         string
+	Scripts      string // names of the executed scripts, separated by comma
+	ScriptCount  int
+	RowsAffected int64
+	Success      bool
+	ErrorMsg     string
+	Env          string
+
+	// Identifier of the run, see MigrateConfig.IDGenerator, empty for the runs recorded by previous versions of svc.
+	Uid string
+
+	// Placeholder values used in the run, values of the secret placeholders are masked.
+	Placeholders map[string]string
+}
+
+const maskedPlaceholder = "******"
+
+var secretPlaceholderKeywords = []string{"password", "secret", "token", "credential"}
+
+// Check if the placeholder holds secret, see MigrateConfig.SecretPlaceholders.
+func (c MigrateConfig) isSecretPlaceholder(name string) bool {
+	for _, s := range c.SecretPlaceholders {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	lower := strings.ToLower(name)
+	for _, k := range secretPlaceholderKeywords {
+		if strings.Contains(lower, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// Placeholder values recorded in schema_run, secrets are masked.
+func (c MigrateConfig) recordedPlaceholders() map[string]string {
+	recorded := make(map[string]string, len(c.Placeholders))
+	for k, v := range c.Placeholders {
+		if c.isSecretPlaceholder(k) {
+			v = maskedPlaceholder
+		}
+		recorded[k] = v
+	}
+	return recorded
+}
+
+// Save the run record, errors are logged, the migration result is not affected.
+func recordRun(db *gorm.DB, log Logger, c MigrateConfig, start time.Time, end time.Time, res Result, err error) {
+	if c.DryRun {
+		return
+	}
+	host, _ := os.Hostname()
+	scripts := make([]string, 0, len(res.Scripts))
+	for _, s := range res.Scripts {
+		scripts = append(scripts, s.Script)
+	}
+	var msg string
+	if err != nil {
+		msg = err.Error()
+	}
+	var placeholders string
+	if len(c.Placeholders) > 0 {
+		buf, er := json.Marshal(c.recordedPlaceholders())
+		if er != nil {
+			log.Errorf("failed to marshal placeholders, %v", er)
+		}
+		placeholders = string(buf)
+	}
+	uid := idGeneratorOrDefault(c.IDGenerator).NewID(start)
+	d := dialectOf(db, c.Dialect)
+	if er := db.Exec(c.ns().rewrite(`INSERT INTO schema_run (app, started_at, ended_at, host, scripts, script_count, rows_affected, success, error_msg,
+		env, placeholders, record_uid) VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`), c.appArg(db), timeArg(d, start), timeArg(d, end), host, strings.Join(scripts, ","), len(scripts),
+		res.RowsAffected, err == nil, msg, c.Env, placeholders, uid).Error; er != nil {
+		log.Errorf("failed to save schema_run, %v", er)
+	}
+}
+
+// Query the last migration run of the app, returns false if svc has never run for the app.
+func LastRun(db *gorm.DB, app string) (RunRecord, bool, error) {
+	d := dialectOf(db, nil)
+	var r struct {
+		Id           int64
+		App          string
+		StartedAt    utcTime
+		EndedAt      utcTime
+		Host         string
+		Scripts      string
+		ScriptCount  int
+		RowsAffected int64
+		Success      bool
+		ErrorMsg     string
+		Env          string
+		Placeholders string
+		RecordUid    *string
+	}
+	t := db.Raw(namespace.rewrite(`SELECT id, app, started_at, ended_at, host, scripts, script_count, rows_affected, success, error_msg,
+		env, placeholders, record_uid FROM schema_run WHERE app = ? ORDER BY id DESC `+d.LimitOne()), appArg(d, app)).Scan(&r)
+	if t.Error != nil {
+		return RunRecord{}, false, fmt.Errorf("failed to query schema_run, %w", t.Error)
+	}
+	var placeholders map[string]string
+	if r.Placeholders != "" {
+		if err := json.Unmarshal([]byte(r.Placeholders), &placeholders); err != nil {
+			return RunRecord{}, false, fmt.Errorf("failed to unmarshal schema_run.placeholders, %w", err)
+		}
+	}
+	var uid string
+	if r.RecordUid != nil {
+		uid = *r.RecordUid
+	}
+	return RunRecord{
+		Id:           r.Id,
+		App:          app,
+		StartedAt:    r.StartedAt.Time,
+		EndedAt:      r.EndedAt.Time,
+		Host:         r.Host,
+		Scripts:      r.Scripts,
+		ScriptCount:  r.ScriptCount,
+		RowsAffected: r.RowsAffected,
+		Success:      r.Success,
+		ErrorMsg:     r.ErrorMsg,
+		Env:          r.Env,
+		Uid:          uid,
+		Placeholders: placeholders,
+	}, t.RowsAffected > 0, nil
+}
@@ -0,0 +1,28 @@
+package svc
+
+import (
+	"testing"
+)
+
+func TestRecordedPlaceholders(t *testing.T) {
+	c := MigrateConfig{
+		Placeholders: map[string]string{
+			"schema":      "myapp",
+			"db_password": "123456",
+			"api_key":     "abc",
+		},
+		SecretPlaceholders: []string{"API_KEY"},
+	}
+	p := c.recordedPlaceholders()
+	if p["schema"] != "myapp" {
+		t.Fatalf("incorrect schema, %v", p["schema"])
+	}
+	for _, k := range []string{"db_password", "api_key"} {
+		if p[k] != maskedPlaceholder {
+			t.Fatalf("%v should be masked, %v", k, p[k])
+		}
+	}
+	if c.Placeholders["db_password"] != "123456" {
+		t.Fatalf("placeholders should not be modified")
+	}
+}
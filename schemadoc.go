@@ -0,0 +1,172 @@
+package svc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"gorm.io/gorm"
+)
+
+// Description of the current schema, read from information_schema.
+type SchemaDesc struct {
+	Schema string      `json:"schema"`
+	Tables []TableDesc `json:"tables"`
+}
+
+type TableDesc struct {
+	Name      string       `json:"name"`
+	Comment   string       `json:"comment"`
+	Engine    string       `json:"engine"`
+	Collation string       `json:"collation"`
+	Columns   []ColumnDesc `json:"columns"`
+	Indexes   []IndexDesc  `json:"indexes"`
+}
+
+type ColumnDesc struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default"`
+	Key      string  `json:"key"`
+	Extra    string  `json:"extra"`
+	Comment  string  `json:"comment"`
+}
+
+type IndexDesc struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Columns []string `json:"columns"`
+}
+
+// Describe tables, columns, indexes and comments in current schema.
+//
+// svc's own tables are not included. It's only supported by MySQL and MariaDB.
+func DescribeSchema(db *gorm.DB) (SchemaDesc, error) {
+	var desc SchemaDesc
+	if db == nil {
+		return desc, errors.New("db is nil")
+	}
+
+	if err := db.Raw(`SELECT DATABASE()`).Scan(&desc.Schema).Error; err != nil {
+		return desc, fmt.Errorf("failed to query current schema, %w", err)
+	}
+
+	var tables []struct {
+		TableName string
+		Comment   string
+		Engine    string
+		Collation string
+	}
+	if err := db.Raw(`
+		SELECT TABLE_NAME table_name, TABLE_COMMENT comment, ENGINE engine, TABLE_COLLATION collation
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME`).Scan(&tables).Error; err != nil {
+		return desc, fmt.Errorf("failed to query information_schema.TABLES, %w", err)
+	}
+
+	var columns []struct {
+		TableName  string
+		ColumnName string
+		ColumnType string
+		IsNullable string
+		Dft        *string
+		ColumnKey  string
+		Extra      string
+		Comment    string
+	}
+	if err := db.Raw(`
+		SELECT TABLE_NAME table_name, COLUMN_NAME column_name, COLUMN_TYPE column_type, IS_NULLABLE is_nullable,
+			COLUMN_DEFAULT dft, COLUMN_KEY column_key, EXTRA extra, COLUMN_COMMENT comment
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE()
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`).Scan(&columns).Error; err != nil {
+		return desc, fmt.Errorf("failed to query information_schema.COLUMNS, %w", err)
+	}
+
+	var indexes []struct {
+		TableName  string
+		IndexName  string
+		NonUnique  int
+		ColumnName string
+	}
+	if err := db.Raw(`
+		SELECT TABLE_NAME table_name, INDEX_NAME index_name, NON_UNIQUE non_unique, COLUMN_NAME column_name
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE()
+		ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`).Scan(&indexes).Error; err != nil {
+		return desc, fmt.Errorf("failed to query information_schema.STATISTICS, %w", err)
+	}
+
+	tableIdx := map[string]int{}
+	for _, t := range tables {
+		if isMetaTable(t.TableName) {
+			continue
+		}
+		tableIdx[t.TableName] = len(desc.Tables)
+		desc.Tables = append(desc.Tables, TableDesc{
+			Name:      t.TableName,
+			Comment:   t.Comment,
+			Engine:    t.Engine,
+			Collation: t.Collation,
+			Columns:   []ColumnDesc{},
+			Indexes:   []IndexDesc{},
+		})
+	}
+
+	for _, c := range columns {
+		i, ok := tableIdx[c.TableName]
+		if !ok {
+			continue
+		}
+		desc.Tables[i].Columns = append(desc.Tables[i].Columns, ColumnDesc{
+			Name:     c.ColumnName,
+			Type:     c.ColumnType,
+			Nullable: c.IsNullable == "YES",
+			Default:  c.Dft,
+			Key:      c.ColumnKey,
+			Extra:    c.Extra,
+			Comment:  c.Comment,
+		})
+	}
+
+	for _, idx := range indexes {
+		i, ok := tableIdx[idx.TableName]
+		if !ok {
+			continue
+		}
+		t := &desc.Tables[i]
+		if n := len(t.Indexes); n > 0 && t.Indexes[n-1].Name == idx.IndexName {
+			t.Indexes[n-1].Columns = append(t.Indexes[n-1].Columns, idx.ColumnName)
+			continue
+		}
+		t.Indexes = append(t.Indexes, IndexDesc{
+			Name:    idx.IndexName,
+			Unique:  idx.NonUnique == 0,
+			Columns: []string{idx.ColumnName},
+		})
+	}
+	return desc, nil
+}
+
+// Describe current schema and write the description to w as JSON.
+//
+// e.g.,
+//
+//	if err := MigrateSchema(db, log, c); err != nil {
+//		// ...
+//	}
+//	f, _ := os.Create("schema.json")
+//	defer f.Close()
+//	err := WriteSchemaJSON(db, f)
+func WriteSchemaJSON(db *gorm.DB, w io.Writer) error {
+	desc, err := DescribeSchema(db)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(desc)
+}
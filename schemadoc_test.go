@@ -0,0 +1,51 @@
+package svc
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+)
+
+func TestDescribeSchema(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT DATABASE\(\)$`, []string{"database"}, []driver.Value{"tt"})
+	f.reply(`FROM information_schema.TABLES`, []string{"table_name", "comment", "engine", "collation"},
+		[]driver.Value{"schema_version", "svc schema version", "InnoDB", "utf8mb4_general_ci"},
+		[]driver.Value{"t_user", "users", "InnoDB", "utf8mb4_0900_ai_ci"})
+	f.reply(`FROM information_schema.COLUMNS`, []string{"table_name", "column_name", "column_type", "is_nullable", "dft",
+		"column_key", "extra", "comment"},
+		[]driver.Value{"schema_version", "id", "bigint unsigned", "NO", nil, "PRI", "auto_increment", ""},
+		[]driver.Value{"t_user", "id", "int", "NO", nil, "PRI", "auto_increment", "user id"},
+		[]driver.Value{"t_user", "name", "varchar(32)", "YES", "''", "MUL", "", "user name"})
+	f.reply(`FROM information_schema.STATISTICS`, []string{"table_name", "index_name", "non_unique", "column_name"},
+		[]driver.Value{"t_user", "PRIMARY", int64(0), "id"},
+		[]driver.Value{"t_user", "name_idx", int64(1), "name"},
+		[]driver.Value{"t_user", "name_idx", int64(1), "id"})
+
+	var buf bytes.Buffer
+	if err := WriteSchemaJSON(f.open(t), &buf); err != nil {
+		t.Fatal(err)
+	}
+	var desc SchemaDesc
+	if err := json.Unmarshal(buf.Bytes(), &desc); err != nil {
+		t.Fatal(err)
+	}
+	if desc.Schema != "tt" || len(desc.Tables) != 1 {
+		t.Fatalf("svc's own tables should be excluded, %+v", desc)
+	}
+	tb := desc.Tables[0]
+	if tb.Name != "t_user" || tb.Comment != "users" || tb.Collation != "utf8mb4_0900_ai_ci" || len(tb.Columns) != 2 {
+		t.Fatalf("incorrect table, %+v", tb)
+	}
+	if c := tb.Columns[1]; c.Name != "name" || !c.Nullable || c.Default == nil || *c.Default != "''" || c.Comment != "user name" {
+		t.Fatalf("incorrect column, %+v", c)
+	}
+	if tb.Columns[0].Nullable || tb.Columns[0].Default != nil {
+		t.Fatalf("incorrect column, %+v", tb.Columns[0])
+	}
+	if len(tb.Indexes) != 2 || !tb.Indexes[0].Unique || tb.Indexes[1].Unique || len(tb.Indexes[1].Columns) != 2 ||
+		tb.Indexes[1].Columns[1] != "id" {
+		t.Fatalf("incorrect indexes, %+v", tb.Indexes)
+	}
+}
@@ -0,0 +1,80 @@
+package svc
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// NO_ZERO_DATE, NO_ZERO_IN_DATE and ERROR_FOR_DIVISION_BY_ZERO are deprecated in MySQL 8.0, setting them
+	// explicitly produces warnings, they are kept if the session (e.g., the server default) already has them
+	strictSQLModes = []string{
+		"STRICT_TRANS_TABLES",
+		"STRICT_ALL_TABLES",
+	}
+)
+
+// Add strict modes to the session's sql_mode, returns func that restores the previous sql_mode.
+func setStrictSQLMode(db *gorm.DB) (restore func() error, err error) {
+	var prev string
+	if err := db.Raw(`SELECT @@SESSION.sql_mode`).Scan(&prev).Error; err != nil {
+		return nil, fmt.Errorf("failed to query sql_mode, %w", err)
+	}
+	if err := db.Exec(`SET SESSION sql_mode = ?`, mergeSQLModes(prev, strictSQLModes)).Error; err != nil {
+		return nil, fmt.Errorf("failed to set sql_mode, %w", err)
+	}
+	return func() error {
+		return db.Exec(`SET SESSION sql_mode = ?`, prev).Error
+	}, nil
+}
+
+func mergeSQLModes(mode string, add []string) string {
+	modes := []string{}
+	seen := map[string]struct{}{}
+	for _, m := range append(strings.Split(mode, ","), add...) {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m == "" {
+			continue
+		}
+		if _, ok := seen[m]; ok {
+			continue
+		}
+		seen[m] = struct{}{}
+		modes = append(modes, m)
+	}
+	return strings.Join(modes, ",")
+}
+
+type sqlWarning struct {
+	Level   string
+	Code    int
+	Message string
+}
+
+func (w sqlWarning) String() string {
+	return fmt.Sprintf("%v %v: %v", w.Level, w.Code, w.Message)
+}
+
+func queryWarnings(db *gorm.DB) ([]sqlWarning, error) {
+	var warnings []sqlWarning
+	if err := db.Raw(`SHOW WARNINGS`).Scan(&warnings).Error; err != nil {
+		return nil, fmt.Errorf("failed to show warnings, %w", err)
+	}
+	return warnings, nil
+}
+
+// Find warnings about data truncation.
+func findTruncation(warnings []sqlWarning) (sqlWarning, bool) {
+	for _, w := range warnings {
+		switch w.Code {
+		case 1264, // ER_WARN_DATA_OUT_OF_RANGE
+			1265, // WARN_DATA_TRUNCATED
+			1292, // ER_TRUNCATED_WRONG_VALUE
+			1366: // ER_TRUNCATED_WRONG_VALUE_FOR_FIELD
+			return w, true
+		}
+	}
+	return sqlWarning{}, false
+}
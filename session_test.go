@@ -0,0 +1,45 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestMergeSQLModes(t *testing.T) {
+	v := mergeSQLModes("ONLY_FULL_GROUP_BY,strict_trans_tables", []string{"STRICT_TRANS_TABLES", "NO_ZERO_DATE"})
+	if v != "ONLY_FULL_GROUP_BY,STRICT_TRANS_TABLES,NO_ZERO_DATE" {
+		t.Fatalf("incorrect sql_mode, %v", v)
+	}
+	if v := mergeSQLModes("", []string{"STRICT_ALL_TABLES"}); v != "STRICT_ALL_TABLES" {
+		t.Fatalf("incorrect sql_mode, %v", v)
+	}
+}
+
+func TestSetStrictSQLMode(t *testing.T) {
+	prev := "ONLY_FULL_GROUP_BY,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO"
+	f := &fakeDB{}
+	f.reply(`^SELECT @@SESSION.sql_mode$`, []string{"mode"}, []driver.Value{prev})
+	restore, err := setStrictSQLMode(f.open(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := f.executed(`^SET SESSION sql_mode`)
+	if len(set) != 1 || set[0].Args[0] != prev+",STRICT_TRANS_TABLES,STRICT_ALL_TABLES" {
+		t.Fatalf("incorrect sql_mode, %+v", set)
+	}
+	if err := restore(); err != nil {
+		t.Fatal(err)
+	}
+	if set := f.executed(`^SET SESSION sql_mode`); len(set) != 2 || set[1].Args[0] != prev {
+		t.Fatalf("sql_mode should be restored, %+v", set)
+	}
+
+	f = &fakeDB{}
+	f.reply(`^SELECT @@SESSION.sql_mode$`, []string{"mode"}, []driver.Value{""})
+	if _, err := setStrictSQLMode(f.open(t)); err != nil {
+		t.Fatal(err)
+	}
+	if set := f.executed(`^SET SESSION sql_mode`); len(set) != 1 || set[0].Args[0] != "STRICT_TRANS_TABLES,STRICT_ALL_TABLES" {
+		t.Fatalf("deprecated modes should not be added, %+v", set)
+	}
+}
@@ -0,0 +1,171 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	simAlterTableRegex  = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(` + identPat + `)`)
+	simDropTableRegex   = regexp.MustCompile(`(?is)^DROP\s+(?:TEMPORARY\s+)?TABLE\s+(?:IF\s+EXISTS\s+)?(.+?)(?:\s+(?:RESTRICT|CASCADE))?$`)
+	simDropIndexRegex   = regexp.MustCompile(`(?is)^DROP\s+INDEX\s+` + identPat + `\s+ON\s+(` + identPat + `)`)
+	simRenameTableRegex = regexp.MustCompile(`(?is)^RENAME\s+TABLE\s+(.+)$`)
+	simTruncateRegex    = regexp.MustCompile(`(?is)^TRUNCATE\s+(?:TABLE\s+)?(` + identPat + `)`)
+	simLikeRegex        = regexp.MustCompile(`(?is)\bLIKE\s+(` + identPat + `)`)
+	simToRegex          = regexp.MustCompile(`(?i)\s+TO\s+`)
+	simNameRegex        = regexp.MustCompile(`[^a-z0-9_]+`)
+)
+
+// Tables referenced by the table DDL (CREATE / ALTER / DROP / RENAME / TRUNCATE TABLE, CREATE / DROP INDEX) in their
+// original case, returns false if the statement can't be simulated, e.g., it's not a table DDL, or it references
+// tables in other databases.
+func simulatedTables(sql string) ([]string, bool) {
+	_, rest := splitLeadingComments(sql)
+	rest = strings.TrimSpace(rest)
+
+	var names []string
+	if m := undoCreateTableRegex.FindStringSubmatch(rest); m != nil {
+		names = append(names, m[1])
+		if l := simLikeRegex.FindStringSubmatch(rest); l != nil {
+			names = append(names, l[1])
+		}
+	} else if m := undoCreateIndexRegex.FindStringSubmatch(rest); m != nil {
+		names = append(names, m[2])
+	} else if m := simAlterTableRegex.FindStringSubmatch(rest); m != nil {
+		names = append(names, m[1])
+	} else if m := simDropIndexRegex.FindStringSubmatch(rest); m != nil {
+		names = append(names, m[1])
+	} else if m := simTruncateRegex.FindStringSubmatch(rest); m != nil {
+		names = append(names, m[1])
+	} else if m := simDropTableRegex.FindStringSubmatch(rest); m != nil {
+		for _, t := range strings.Split(m[1], ",") {
+			names = append(names, strings.TrimSpace(t))
+		}
+	} else if m := simRenameTableRegex.FindStringSubmatch(rest); m != nil {
+		for _, pair := range strings.Split(m[1], ",") {
+			for _, t := range simToRegex.Split(strings.TrimSpace(pair), -1) {
+				names = append(names, strings.TrimSpace(t))
+			}
+		}
+	} else {
+		return nil, false
+	}
+	for _, r := range referencesRegex.FindAllStringSubmatch(rest, -1) {
+		names = append(names, r[1])
+	}
+
+	tables := make([]string, 0, len(names))
+	for _, n := range names {
+		if strings.Contains(n, ".") {
+			return nil, false
+		}
+		tables = append(tables, unquoteTable(n))
+	}
+	return tables, true
+}
+
+// Name of the scratch schema used by Simulate, e.g., svc_sim_myapp.
+func simulationSchema(c MigrateConfig) string {
+	return simNameRegex.ReplaceAllString(c.ns().name()+"_sim_"+strings.ToLower(c.App), "_")
+}
+
+// Simulate the pending scripts in a scratch schema, without touching the actual tables or svc's history.
+//
+// The table DDLs (CREATE / ALTER / DROP / RENAME / TRUNCATE TABLE, CREATE / DROP INDEX) are executed against the
+// empty copies of the affected tables (CREATE TABLE ... LIKE) in the scratch schema (named '<namespace>_sim_<app>'),
+// which validates the syntax and the compatibility with the existing table definitions cheaply. Other statements
+// (e.g., DML, views) and the statements referencing tables in other databases are skipped. Foreign keys are not
+// copied by CREATE TABLE ... LIKE.
+//
+// The scratch schema is dropped afterwards, CREATE and DROP privileges on it are required. Only supported by MySQL.
+func Simulate(db *gorm.DB, log Logger, c MigrateConfig) (Result, error) {
+	res := Result{DryRun: true}
+	if log == nil {
+		return res, errors.New("log is nil")
+	}
+	db, closeConn, err := c.connect(db)
+	if err != nil {
+		return res, err
+	}
+	defer closeConn()
+	if db == nil {
+		return res, errors.New("db is nil")
+	}
+	c.Dialect = dialectOf(db, c.Dialect)
+	if c.Dialect.Name() != DialectMySQL {
+		return res, fmt.Errorf("simulation is not supported by %v dialect", c.Dialect.Name())
+	}
+
+	pending, _, err := pendingScripts(db, c)
+	if err != nil {
+		return res, err
+	}
+	current, err := c.Dialect.CurrentDatabase(db)
+	if err != nil {
+		return res, err
+	}
+
+	scratch := simulationSchema(c)
+	if err := db.Exec("DROP DATABASE IF EXISTS " + quoteIdent(scratch)).Error; err != nil {
+		return res, fmt.Errorf("failed to drop scratch schema %v, %w", scratch, err)
+	}
+	if err := db.Exec("CREATE DATABASE " + quoteIdent(scratch)).Error; err != nil {
+		return res, fmt.Errorf("failed to create scratch schema %v, %w", scratch, err)
+	}
+	defer func() {
+		if err := db.Exec("DROP DATABASE IF EXISTS " + quoteIdent(scratch)).Error; err != nil {
+			log.Errorf("failed to drop scratch schema %v, %v", scratch, err)
+		}
+	}()
+	log.Infof("Simulating %d pending scripts in scratch schema %v", len(pending), scratch)
+
+	cloned := map[string]struct{}{}
+	for _, sf := range pending {
+		src := current
+		if sf.Database != "" {
+			src = resolvePlaceholders(sf.Database, c.Placeholders)
+		}
+		sr := ScriptResult{Script: sf.Name}
+		for i, sql := range sf.SQLs {
+			stmt := resolvePlaceholders(sql, c.Placeholders)
+			tables, ok := simulatedTables(stmt)
+			if !ok {
+				log.Infof("'%v' - [%v] not simulated, skipped", sf.Name, i+1)
+				continue
+			}
+			for _, t := range tables {
+				key, seen := src+"."+t, src+"."+normalizeTable(t)
+				if _, ok := cloned[seen]; ok {
+					continue
+				}
+				cloned[seen] = struct{}{}
+				var cnt int
+				if err := db.Raw(`SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = ? AND table_name = ?`,
+					src, t).Scan(&cnt).Error; err != nil {
+					return res, fmt.Errorf("failed to check table %v, %w", key, err)
+				}
+				if cnt < 1 {
+					continue
+				}
+				if err := db.Exec(fmt.Sprintf("CREATE TABLE %s.%s LIKE %s.%s", quoteIdent(scratch), quoteIdent(t),
+					quoteIdent(src), quoteIdent(t))).Error; err != nil {
+					return res, fmt.Errorf("failed to copy table %v, %w", key, err)
+				}
+			}
+			if err := withDatabase(db, c.Dialect, scratch, func(conn *gorm.DB) error {
+				return conn.Exec(stmt).Error
+			}); err != nil {
+				res.add(sr)
+				return res, fmt.Errorf("simulation failed, %w", newScriptError(sf, i, stmt, err))
+			}
+			log.Infof("'%v' - [%v] simulated", sf.Name, i+1)
+			sr.Statements += 1
+		}
+		res.add(sr)
+	}
+	return res, nil
+}
@@ -0,0 +1,59 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSimulatedTables(t *testing.T) {
+	cases := []struct {
+		sql    string
+		tables []string
+		ok     bool
+	}{
+		{"CREATE TABLE t (id INT, p_id INT, FOREIGN KEY (p_id) REFERENCES `p` (id))", []string{"t", "p"}, true},
+		{"CREATE TABLE t2 LIKE t", []string{"t2", "t"}, true},
+		{"CREATE UNIQUE INDEX a_idx ON t (a)", []string{"t"}, true},
+		{"ALTER TABLE `Orders` ADD COLUMN a INT", []string{"Orders"}, true},
+		{"DROP INDEX a_idx ON t", []string{"t"}, true},
+		{"DROP TABLE IF EXISTS a, b", []string{"a", "b"}, true},
+		{"RENAME TABLE a TO b, c TO d", []string{"a", "b", "c", "d"}, true},
+		{"TRUNCATE TABLE t", []string{"t"}, true},
+		{"ALTER TABLE other.t ADD COLUMN a INT", nil, false},
+		{"UPDATE t SET a = 1", nil, false},
+		{"CREATE VIEW v AS SELECT * FROM t", nil, false},
+	}
+	for _, c := range cases {
+		tables, ok := simulatedTables(c.sql)
+		if ok != c.ok || !reflect.DeepEqual(tables, c.tables) {
+			t.Fatalf("'%v', expected %v %v, got %v %v", c.sql, c.tables, c.ok, tables, ok)
+		}
+	}
+	if s := simulationSchema(MigrateConfig{App: "my-app"}); s != "svc_sim_my_app" {
+		t.Fatalf("incorrect scratch schema, %v", s)
+	}
+}
+
+func TestSimulateTableCase(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT DATABASE\(\)$`, []string{"db"}, []driver.Value{"shop"})
+	f.reply(`^SELECT COUNT\(\*\) FROM information_schema.tables`, []string{"cnt"}, []driver.Value{int64(1)})
+	_, err := Simulate(f.open(t), PrintLogger{}, MigrateConfig{
+		App: "test",
+		Fs: fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("ALTER TABLE `Orders` ADD COLUMN a INT;\n" +
+			"ALTER TABLE orders ADD COLUMN b INT;")}},
+		BaseDir: "schema",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^SELECT COUNT\(\*\) FROM information_schema.tables`); len(q) != 1 || q[0].Args[1] != "Orders" {
+		t.Fatalf("table should be looked up in its original case, once, %+v", q)
+	}
+	if q := f.executed("^CREATE TABLE `svc_sim_test`.`Orders` LIKE `shop`.`Orders`$"); len(q) != 1 {
+		t.Fatalf("table should be cloned in its original case, %+v", f.executed(`^CREATE TABLE`))
+	}
+}
@@ -0,0 +1,112 @@
+package svc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	smartSkipTemporaryRegex = regexp.MustCompile(`(?is)^CREATE\s+TEMPORARY\s`)
+)
+
+// Object that the statement creates, see MigrateConfig.SmartSkip.
+type createdObject struct {
+	Kind   string // 'table', 'column' or 'index'
+	Schema string // empty for the current database
+	Table  string
+	Name   string // name of the column or the index
+}
+
+func (o createdObject) String() string {
+	table := o.Table
+	if o.Schema != "" {
+		table = o.Schema + "." + table
+	}
+	if o.Kind == "table" {
+		return "table " + table
+	}
+	return fmt.Sprintf("%s %s.%s", o.Kind, table, o.Name)
+}
+
+// Split the (possibly qualified and quoted) identifier into schema and name.
+func splitIdent(ident string) (schema string, name string) {
+	schema, name, ok := strings.Cut(ident, ".")
+	if !ok {
+		schema, name = "", ident
+	}
+	return strings.Trim(schema, "`"), strings.Trim(name, "`")
+}
+
+// Parse the object created by CREATE TABLE, ALTER TABLE ... ADD COLUMN / INDEX and CREATE INDEX, returns false if
+// the statement creates something else, or it can't be checked, e.g., ALTER TABLE with multiple clauses.
+func parseCreatedObject(sql string) (createdObject, bool) {
+	_, rest := splitLeadingComments(sql)
+	rest = strings.TrimSpace(rest)
+
+	if m := undoCreateTableRegex.FindStringSubmatch(rest); m != nil {
+		// temporary tables are not in information_schema.tables
+		if smartSkipTemporaryRegex.MatchString(rest) {
+			return createdObject{}, false
+		}
+		schema, table := splitIdent(m[1])
+		return createdObject{Kind: "table", Schema: schema, Table: table}, true
+	}
+	if m := undoCreateIndexRegex.FindStringSubmatch(rest); m != nil {
+		schema, table := splitIdent(m[2])
+		_, index := splitIdent(m[1])
+		return createdObject{Kind: "index", Schema: schema, Table: table, Name: index}, true
+	}
+	if m := undoAlterAddRegex.FindStringSubmatch(rest); m != nil {
+		schema, table := splitIdent(m[1])
+		clause := strings.TrimSpace(m[2])
+		if hasTopLevelComma(clause) {
+			return createdObject{}, false
+		}
+		if m := undoAddIndexRegex.FindStringSubmatch(clause); m != nil {
+			_, index := splitIdent(m[1])
+			return createdObject{Kind: "index", Schema: schema, Table: table, Name: index}, true
+		}
+		if m := undoAddColumnRegex.FindStringSubmatch(clause); m != nil {
+			if _, ok := undoAddKeywords[strings.ToLower(m[1])]; ok {
+				return createdObject{}, false
+			}
+			_, column := splitIdent(m[1])
+			return createdObject{Kind: "column", Schema: schema, Table: table, Name: column}, true
+		}
+	}
+	return createdObject{}, false
+}
+
+// Check if the object created by the statement already exists in information_schema, returns the object found.
+func alreadySatisfied(conn *gorm.DB, sql string) (createdObject, bool, error) {
+	o, ok := parseCreatedObject(sql)
+	if !ok {
+		return o, false, nil
+	}
+	schema := "DATABASE()"
+	args := []any{o.Table}
+	if o.Schema != "" {
+		schema = "?"
+		args = []any{o.Schema, o.Table}
+	}
+
+	var query string
+	switch o.Kind {
+	case "table":
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = ` + schema + ` AND table_name = ?`
+	case "column":
+		query = `SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = ` + schema + ` AND table_name = ? AND column_name = ?`
+		args = append(args, o.Name)
+	case "index":
+		query = `SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = ` + schema + ` AND table_name = ? AND index_name = ?`
+		args = append(args, o.Name)
+	}
+	var cnt int
+	if err := conn.Raw(query, args...).Scan(&cnt).Error; err != nil {
+		return o, false, fmt.Errorf("failed to check %v, %w", o, err)
+	}
+	return o, cnt > 0, nil
+}
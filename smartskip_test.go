@@ -0,0 +1,83 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseCreatedObject(t *testing.T) {
+	cases := []struct {
+		sql string
+		exp createdObject
+	}{
+		{"CREATE TABLE IF NOT EXISTS `t` (id INT)", createdObject{Kind: "table", Table: "t"}},
+		{"-- comment\ncreate table `db`.t (id INT)", createdObject{Kind: "table", Schema: "db", Table: "t"}},
+		{"CREATE UNIQUE INDEX name_idx ON t (name)", createdObject{Kind: "index", Table: "t", Name: "name_idx"}},
+		{"ALTER TABLE db.t ADD COLUMN `price` DECIMAL(10,2)", createdObject{Kind: "column", Schema: "db", Table: "t", Name: "price"}},
+		{"ALTER TABLE t ADD name VARCHAR(10)", createdObject{Kind: "column", Table: "t", Name: "name"}},
+		{"ALTER TABLE t ADD KEY name_idx (name)", createdObject{Kind: "index", Table: "t", Name: "name_idx"}},
+	}
+	for _, c := range cases {
+		o, ok := parseCreatedObject(c.sql)
+		if !ok {
+			t.Fatalf("'%v' should be checked", c.sql)
+		}
+		if o != c.exp {
+			t.Fatalf("object created by '%v' should be %+v, but got %+v", c.sql, c.exp, o)
+		}
+	}
+
+	unchecked := []string{
+		"CREATE TEMPORARY TABLE t (id INT)",
+		"ALTER TABLE t ADD COLUMN a INT, ADD COLUMN b INT",
+		"ALTER TABLE t ADD CONSTRAINT fk FOREIGN KEY (a) REFERENCES r (id)",
+		"ALTER TABLE t MODIFY COLUMN a BIGINT",
+		"INSERT INTO t (a) VALUES (1)",
+	}
+	for _, s := range unchecked {
+		if o, ok := parseCreatedObject(s); ok {
+			t.Fatalf("'%v' should not be checked, but got %+v", s, o)
+		}
+	}
+}
+
+func TestSmartSkipRollback(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT COUNT\(\*\) FROM information_schema.tables`, []string{"cnt"}, []driver.Value{int64(1)})
+	_, err := Run(f.open(t), PrintLogger{}, MigrateConfig{
+		App:       "test",
+		Fs:        fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);")}},
+		BaseDir:   "schema",
+		SmartSkip: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^CREATE TABLE t `); len(q) > 0 {
+		t.Fatalf("statement should be skipped, %+v", q)
+	}
+	q := f.executed(`^UPDATE schema_script_sql SET rows_affected = \?, skipped = \?, undo_stmt = NULL, reversible = \?`)
+	if len(q) != 1 || q[0].Args[1] != true || q[0].Args[2] != false {
+		t.Fatalf("skipped statement should be irreversible, %+v", q)
+	}
+
+	// the table existed before, it's not dropped
+	f = &fakeDB{}
+	f.reply(`^SELECT id, script, success, remark FROM schema_version`, []string{"id", "script", "success", "remark"},
+		[]driver.Value{int64(1), "v0.0.1.sql", true, "Executed"})
+	f.reply(`^SELECT id, script, stmt, undo_stmt, reversible, skipped, rows_affected FROM schema_script_sql`,
+		[]string{"id", "script", "stmt", "undo_stmt", "reversible", "skipped", "rows_affected"},
+		[]driver.Value{int64(4), "v0.0.1.sql", "CREATE TABLE t2 (id INT)", "DROP TABLE t2", true, false, int64(0)},
+		[]driver.Value{int64(3), "v0.0.1.sql", "CREATE TABLE t (id INT)", nil, false, true, int64(0)})
+	if err := Rollback(f.open(t), PrintLogger{}, MigrateConfig{App: "test"}, "v0.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^DROP TABLE`); len(q) != 1 || q[0].SQL != "DROP TABLE t2" {
+		t.Fatalf("only the executed statement should be undone, %+v", q)
+	}
+	if q := f.executed(`^DELETE FROM schema_script_sql WHERE id = \?`); len(q) != 2 {
+		t.Fatalf("records should be removed, %+v", q)
+	}
+}
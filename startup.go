@@ -0,0 +1,234 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Outcome of the migration on startup.
+type StartupOutcome string
+
+const (
+	StartupMigrated        StartupOutcome = "migrated"          // pending scripts were executed by this instance
+	StartupUpToDate        StartupOutcome = "up-to-date"        // nothing was pending
+	StartupMigratedByOther StartupOutcome = "migrated-by-other" // another instance held the lock, nothing was pending once it's released
+	StartupFailed          StartupOutcome = "failed"            // the migration failed
+	StartupTimeout         StartupOutcome = "timeout"           // the lock was not acquired, or the migration was not completed, before the deadline
+	StartupVerified        StartupOutcome = "verified"          // the migration was skipped, the schema is at or above RequiredVersion
+	StartupSchemaBehind    StartupOutcome = "schema-behind"     // the schema is behind RequiredVersion
+)
+
+var (
+	ErrSchemaBehind = errors.New("schema is behind the required version")
+
+	requiredVersion = ""
+)
+
+// Register the schema version that the service is built against (e.g., a constant generated at build time),
+// it's the default value of StartupOptions.RequiredVersion.
+func SetRequiredVersion(ver string) {
+	requiredVersion = ver
+}
+
+// Options of MigrateOnStartup.
+type StartupOptions struct {
+	// How long each attempt waits for the migration lock, defaults to 5 seconds. It's capped by the deadline of ctx.
+	LockTimeout time.Duration
+
+	// Interval between the attempts to acquire the migration lock, defaults to 1 second.
+	RetryInterval time.Duration
+
+	// Schema version that the service is built against, it's optional, defaults to the one set by
+	// SetRequiredVersion. If provided, the schema must be at or above the version after the migration, otherwise
+	// the outcome is StartupSchemaBehind, i.e., new code is not started against an old schema.
+	RequiredVersion string
+
+	// Skip the migration, only RequiredVersion is verified, e.g., the migrations are applied by the deployment
+	// pipeline, not by the service.
+	SkipMigration bool
+}
+
+// Result of MigrateOnStartup.
+type StartupResult struct {
+	Outcome StartupOutcome
+	Result  Result
+
+	// Number of attempts to acquire the migration lock.
+	Attempts int
+}
+
+// Whether the schema is up-to-date, and the service can start serving.
+func (r StartupResult) Ready() bool {
+	switch r.Outcome {
+	case StartupMigrated, StartupUpToDate, StartupMigratedByOther, StartupVerified:
+		return true
+	}
+	return false
+}
+
+func startupOutcome(waited bool, res Result, err error) StartupOutcome {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return StartupTimeout
+	case err != nil:
+		return StartupFailed
+	case versionedCount(res) > 0:
+		return StartupMigrated
+	case waited:
+		return StartupMigratedByOther
+	default:
+		return StartupUpToDate
+	}
+}
+
+// Number of versioned scripts executed, the run-always ones are executed by every instance.
+func versionedCount(res Result) int {
+	n := 0
+	for _, sr := range res.Scripts {
+		if len(sr.Version.Segments) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Migrate the schema on service boot, it's meant to be called by each instance of the service.
+//
+// The migration lock is always acquired, see MigrateConfig.Lock. If the lock is held by another instance, the
+// acquisition is retried until it succeeds or ctx is done (e.g., the startup deadline is exceeded), in which case
+// the outcome is StartupTimeout, so is the outcome if the deadline is exceeded during the migration. Only the
+// versioned scripts make the outcome StartupMigrated, the run-always ones are executed by every instance. The queries are bound to ctx (unless the connection is opened by svc, see
+// MigrateConfig.DSN).
+//
+// If StartupOptions.RequiredVersion is provided, the schema version is verified after the migration (or without
+// the migration if StartupOptions.SkipMigration is enabled), ErrSchemaBehind is returned if it's behind.
+//
+// The error is returned for StartupFailed, StartupTimeout and StartupSchemaBehind, use StartupResult.Ready to
+// decide whether to start serving.
+func MigrateOnStartup(ctx context.Context, db *gorm.DB, log Logger, c MigrateConfig, opts StartupOptions) (StartupResult, error) {
+	if opts.RequiredVersion == "" {
+		opts.RequiredVersion = requiredVersion
+	}
+	if db != nil {
+		db = db.WithContext(ctx)
+	}
+	if opts.SkipMigration {
+		if opts.RequiredVersion == "" {
+			return StartupResult{Outcome: StartupFailed}, errors.New("RequiredVersion is required if SkipMigration is enabled")
+		}
+		sr := StartupResult{Outcome: StartupVerified}
+		if err := verifyRequiredVersion(db, c, opts.RequiredVersion); err != nil {
+			sr.Outcome = startupVerifyOutcome(err)
+			return sr, err
+		}
+		log.Infof("Migration skipped, schema is at or above the required version %v", opts.RequiredVersion)
+		return sr, nil
+	}
+
+	sr, err := migrateOnStartup(ctx, db, log, c, opts)
+	if err != nil || opts.RequiredVersion == "" {
+		return sr, err
+	}
+	if err := verifyRequiredVersion(db, c, opts.RequiredVersion); err != nil {
+		sr.Outcome = startupVerifyOutcome(err)
+		return sr, err
+	}
+	return sr, nil
+}
+
+func startupVerifyOutcome(err error) StartupOutcome {
+	if errors.Is(err, ErrSchemaBehind) {
+		return StartupSchemaBehind
+	}
+	return StartupFailed
+}
+
+// Verify that the latest version applied is at or above the required version.
+func verifyRequiredVersion(db *gorm.DB, c MigrateConfig, required string) error {
+	db, closeConn, err := c.connect(db)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	current, err := appliedVersion(db, c)
+	if err != nil {
+		return err
+	}
+	if current == "" || !VerAfterEq(current, required) {
+		if current == "" {
+			current = "none"
+		}
+		return fmt.Errorf("schema version is %v, the service requires %v, %w", current, required, ErrSchemaBehind)
+	}
+	return nil
+}
+
+// Latest version applied successfully, empty if none.
+func appliedVersion(db *gorm.DB, c MigrateConfig) (string, error) {
+	var scripts []string
+	if err := db.Raw(c.ns().rewrite(`SELECT script FROM schema_version WHERE app = ? AND kind = ? AND success = ?`),
+		c.appArg(db), kindVersioned, true).Scan(&scripts).Error; err != nil {
+		return "", fmt.Errorf("failed to list schema_version, %w", err)
+	}
+	return latestVersion(scripts), nil
+}
+
+// Latest version of the scripts, empty if none.
+func latestVersion(scripts []string) string {
+	latest := ""
+	for _, s := range scripts {
+		if latest == "" || VerAfter(s, latest) {
+			latest = s
+		}
+	}
+	return latest
+}
+
+func migrateOnStartup(ctx context.Context, db *gorm.DB, log Logger, c MigrateConfig, opts StartupOptions) (StartupResult, error) {
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = 5 * time.Second
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = time.Second
+	}
+	c.Lock = true
+
+	var sr StartupResult
+	for {
+		if err := ctx.Err(); err != nil {
+			sr.Outcome = StartupTimeout
+			return sr, fmt.Errorf("migration lock not acquired after %d attempts, %w", sr.Attempts, err)
+		}
+
+		c.LockTimeout = opts.LockTimeout
+		if deadline, ok := ctx.Deadline(); ok {
+			if remaining := time.Until(deadline); remaining < c.LockTimeout {
+				c.LockTimeout = remaining
+			}
+		}
+		sr.Attempts++
+		res, err := Run(db, log, c)
+		if errors.Is(err, ErrLockNotAcquired) {
+			log.Infof("Migration lock is held by another instance, retrying in %v (attempt %d)", opts.RetryInterval, sr.Attempts)
+			select {
+			case <-ctx.Done():
+			case <-time.After(opts.RetryInterval):
+			}
+			continue
+		}
+		// the driver may not wrap the error of the cancelled query
+		if err != nil && ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+			err = fmt.Errorf("%w, %w", err, ctx.Err())
+		}
+		sr.Result = res
+		sr.Outcome = startupOutcome(sr.Attempts > 1, res, err)
+		return sr, err
+	}
+}
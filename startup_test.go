@@ -0,0 +1,65 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestStartupOutcome(t *testing.T) {
+	executed := Result{Scripts: []ScriptResult{{Script: "v0.0.2.sql", Version: ParseVer("v0.0.2.sql")}}}
+	repeatables := Result{Scripts: []ScriptResult{{Script: "views/v_orders.sql"}}}
+	cases := []struct {
+		waited bool
+		res    Result
+		err    error
+		want   StartupOutcome
+		ready  bool
+	}{
+		{false, executed, nil, StartupMigrated, true},
+		{true, executed, nil, StartupMigrated, true},
+		{false, Result{}, nil, StartupUpToDate, true},
+		{true, Result{}, nil, StartupMigratedByOther, true},
+		{false, executed, errors.New("table t exists"), StartupFailed, false},
+		{true, repeatables, nil, StartupMigratedByOther, true},
+		{false, repeatables, nil, StartupUpToDate, true},
+		{false, Result{}, fmt.Errorf("failed to exec, %w", context.DeadlineExceeded), StartupTimeout, false},
+	}
+	for i, c := range cases {
+		o := startupOutcome(c.waited, c.res, c.err)
+		if o != c.want {
+			t.Fatalf("case %d, expected %v, got %v", i, c.want, o)
+		}
+		if (StartupResult{Outcome: o}).Ready() != c.ready {
+			t.Fatalf("case %d, incorrect ready", i)
+		}
+	}
+	if (StartupResult{Outcome: StartupTimeout}).Ready() {
+		t.Fatal("timeout should not be ready")
+	}
+}
+
+func TestRequiredVersion(t *testing.T) {
+	if v := latestVersion([]string{"v0.0.9.sql", "v0.0.10.sql", "v0.0.2.sql"}); v != "v0.0.10.sql" {
+		t.Fatalf("incorrect latest version, %v", v)
+	}
+	if v := latestVersion(nil); v != "" {
+		t.Fatalf("incorrect latest version, %v", v)
+	}
+	behind := fmt.Errorf("schema version is v0.0.2.sql, the service requires v0.0.3, %w", ErrSchemaBehind)
+	if o := startupVerifyOutcome(behind); o != StartupSchemaBehind {
+		t.Fatalf("incorrect outcome, %v", o)
+	}
+	if o := startupVerifyOutcome(errors.New("connection refused")); o != StartupFailed {
+		t.Fatalf("incorrect outcome, %v", o)
+	}
+	if (StartupResult{Outcome: StartupSchemaBehind}).Ready() || !(StartupResult{Outcome: StartupVerified}).Ready() {
+		t.Fatal("incorrect ready")
+	}
+
+	sr, err := MigrateOnStartup(context.Background(), nil, PrintLogger{}, MigrateConfig{}, StartupOptions{SkipMigration: true})
+	if err == nil || sr.Outcome != StartupFailed {
+		t.Fatalf("should fail without RequiredVersion, %v, %v", sr.Outcome, err)
+	}
+}
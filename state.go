@@ -0,0 +1,293 @@
+package svc
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Snapshot of svc's own tables of the app, see ExportState and ImportState.
+//
+// It's JSON serializable, the rows are in the order of creation. The ids are not included, they are reassigned
+// when the rows are imported. Statements encrypted with MigrateConfig.StmtKey are exported as is.
+type State struct {
+	App        string         `json:"app"`
+	ExportedAt time.Time      `json:"exported_at"`
+	Versions   []VersionRow   `json:"versions"`
+	Statements []ScriptSQLRow `json:"statements"`
+	Objects    []ObjectRow    `json:"objects"`
+	Cursors    []CursorRow    `json:"cursors"`
+	Runs       []RunRow       `json:"runs"`
+}
+
+// Row of schema_version.
+type VersionRow struct {
+	Script      string    `json:"script"`
+	Kind        string    `json:"kind"`
+	Success     bool      `json:"success"`
+	Remark      string    `json:"remark"`
+	ErrorDetail string    `json:"error_detail,omitempty"`
+	FailedStmt  *int      `json:"failed_stmt,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	RecordUid   string    `json:"record_uid,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	Ticket      string    `json:"ticket,omitempty"`
+}
+
+// Row of schema_script_sql.
+type ScriptSQLRow struct {
+	Script       string    `json:"script"`
+	Stmt         string    `json:"stmt"`
+	UndoStmt     string    `json:"undo_stmt,omitempty"`
+	Reversible   bool      `json:"reversible"`
+	RowsAffected *int64    `json:"rows_affected"` // nil if the statement is not executed successfully
+	Warnings     string    `json:"warnings,omitempty"`
+	Skipped      bool      `json:"skipped,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Row of schema_object.
+type ObjectRow struct {
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	Checksum  string    `json:"checksum"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Row of schema_cursor.
+type CursorRow struct {
+	Script    string    `json:"script"`
+	StmtIndex int       `json:"stmt_index"`
+	LastKey   int64     `json:"last_key"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Row of schema_run.
+type RunRow struct {
+	StartedAt    time.Time `json:"started_at"`
+	EndedAt      time.Time `json:"ended_at"`
+	Host         string    `json:"host"`
+	Scripts      string    `json:"scripts"`
+	ScriptCount  int       `json:"script_count"`
+	RowsAffected int64     `json:"rows_affected"`
+	Success      bool      `json:"success"`
+	ErrorMsg     string    `json:"error_msg,omitempty"`
+	Env          string    `json:"env,omitempty"`
+	Placeholders string    `json:"placeholders,omitempty"`
+	RecordUid    string    `json:"record_uid,omitempty"`
+}
+
+// Export svc's own tables of the app, e.g., before the data of production is cloned into staging.
+//
+// The exported State can be restored using ImportState, so that the migration history of the clone is consistent
+// with the cloned schema. The tables are read in one read-only transaction, the State is a consistent snapshot even
+// if a migration is running.
+func ExportState(db *gorm.DB, app string) (State, error) {
+	if db == nil {
+		return State{}, errors.New("db is nil")
+	}
+	var s State
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		s, err = exportState(tx, app)
+		return err
+	}, snapshotTxOptions(dialectOf(db, nil)))
+	return s, err
+}
+
+// Options of the transaction reading a consistent snapshot, Oracle's read-only transactions are consistent by
+// themselves, REPEATABLE READ is not supported.
+func snapshotTxOptions(d Dialect) *sql.TxOptions {
+	if d.Name() == DialectOracle {
+		return &sql.TxOptions{ReadOnly: true}
+	}
+	return &sql.TxOptions{ReadOnly: true, Isolation: sql.LevelRepeatableRead}
+}
+
+func exportState(db *gorm.DB, app string) (State, error) {
+	s := State{App: app, ExportedAt: time.Now().UTC()}
+	query := func(table string, cols string, dest any) error {
+		if err := db.Raw(namespace.rewrite(`SELECT `+cols+` FROM `+table+` WHERE app = ? ORDER BY id ASC`), appArg(dialectOf(db, nil), app)).
+			Scan(dest).Error; err != nil {
+			return fmt.Errorf("failed to list %v, %w", table, err)
+		}
+		return nil
+	}
+
+	var versions []struct {
+		Script      string
+		Kind        string
+		Success     bool
+		Remark      string
+		ErrorDetail *string
+		FailedStmt  *int
+		CreatedAt   utcTime
+		RecordUid   *string
+		Author      *string
+		Ticket      *string
+	}
+	if err := query("schema_version", "script, kind, success, remark, error_detail, failed_stmt, created_at, record_uid, author, ticket",
+		&versions); err != nil {
+		return State{}, err
+	}
+	s.Versions = make([]VersionRow, 0, len(versions))
+	for _, r := range versions {
+		s.Versions = append(s.Versions, VersionRow{Script: r.Script, Kind: r.Kind, Success: r.Success, Remark: r.Remark,
+			ErrorDetail: deref(r.ErrorDetail), FailedStmt: r.FailedStmt, CreatedAt: r.CreatedAt.Time,
+			RecordUid: deref(r.RecordUid), Author: deref(r.Author), Ticket: deref(r.Ticket)})
+	}
+
+	var stmts []struct {
+		Script       string
+		Stmt         string
+		UndoStmt     *string
+		Reversible   bool
+		RowsAffected *int64
+		Warnings     *string
+		Skipped      bool
+		CreatedAt    utcTime
+	}
+	if err := query("schema_script_sql", "script, stmt, undo_stmt, reversible, rows_affected, warnings, skipped, created_at",
+		&stmts); err != nil {
+		return State{}, err
+	}
+	s.Statements = make([]ScriptSQLRow, 0, len(stmts))
+	for _, r := range stmts {
+		s.Statements = append(s.Statements, ScriptSQLRow{Script: r.Script, Stmt: r.Stmt, UndoStmt: deref(r.UndoStmt),
+			Reversible: r.Reversible, RowsAffected: r.RowsAffected, Warnings: deref(r.Warnings), Skipped: r.Skipped,
+			CreatedAt: r.CreatedAt.Time})
+	}
+
+	var objects []struct {
+		Type      string
+		Name      string
+		Checksum  string
+		CreatedAt utcTime
+		UpdatedAt utcTime
+	}
+	if err := query("schema_object", "type, name, checksum, created_at, updated_at", &objects); err != nil {
+		return State{}, err
+	}
+	s.Objects = make([]ObjectRow, 0, len(objects))
+	for _, r := range objects {
+		s.Objects = append(s.Objects, ObjectRow{Type: r.Type, Name: r.Name, Checksum: r.Checksum, CreatedAt: r.CreatedAt.Time,
+			UpdatedAt: r.UpdatedAt.Time})
+	}
+
+	var cursors []struct {
+		Script    string
+		StmtIndex int
+		LastKey   int64
+		UpdatedAt utcTime
+	}
+	if err := query("schema_cursor", "script, stmt_index, last_key, updated_at", &cursors); err != nil {
+		return State{}, err
+	}
+	s.Cursors = make([]CursorRow, 0, len(cursors))
+	for _, r := range cursors {
+		s.Cursors = append(s.Cursors, CursorRow{Script: r.Script, StmtIndex: r.StmtIndex, LastKey: r.LastKey, UpdatedAt: r.UpdatedAt.Time})
+	}
+
+	var runs []struct {
+		StartedAt    utcTime
+		EndedAt      utcTime
+		Host         string
+		Scripts      string
+		ScriptCount  int
+		RowsAffected int64
+		Success      bool
+		ErrorMsg     string
+		Env          string
+		Placeholders string
+		RecordUid    *string
+	}
+	if err := query("schema_run", "started_at, ended_at, host, scripts, script_count, rows_affected, success, error_msg, env, placeholders, record_uid",
+		&runs); err != nil {
+		return State{}, err
+	}
+	s.Runs = make([]RunRow, 0, len(runs))
+	for _, r := range runs {
+		s.Runs = append(s.Runs, RunRow{StartedAt: r.StartedAt.Time, EndedAt: r.EndedAt.Time, Host: r.Host, Scripts: r.Scripts,
+			ScriptCount: r.ScriptCount, RowsAffected: r.RowsAffected, Success: r.Success, ErrorMsg: r.ErrorMsg, Env: r.Env,
+			Placeholders: r.Placeholders, RecordUid: deref(r.RecordUid)})
+	}
+	return s, nil
+}
+
+// Restore svc's own tables of the app from the State exported by ExportState, e.g., after the data of production
+// is cloned into staging.
+//
+// The existing rows of the app are replaced in a transaction, the rows of the other apps are not changed. svc's
+// tables are created if necessary.
+func ImportState(db *gorm.DB, s State) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	d := dialectOf(db, nil)
+	if err := d.InitMetaTables(db, namespace); err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		exec := func(sql string, args ...any) error { return tx.Exec(namespace.rewrite(sql), args...).Error }
+		app := appArg(d, s.App)
+		for _, table := range metaTableNames {
+			if err := exec(`DELETE FROM `+table+` WHERE app = ?`, app); err != nil {
+				return fmt.Errorf("failed to delete %v, %w", table, err)
+			}
+		}
+		for _, r := range s.Versions {
+			if err := exec(`INSERT INTO schema_version (app, script, kind, success, remark, error_detail, failed_stmt, created_at,
+				record_uid, author, ticket) VALUES (?,?,?,?,?,?,?,?,?,?,?)`, app, r.Script, r.Kind, r.Success, r.Remark,
+				nullable(r.ErrorDetail), r.FailedStmt, timeArg(d, r.CreatedAt), r.RecordUid, r.Author, r.Ticket); err != nil {
+				return fmt.Errorf("failed to save schema_version, %w", err)
+			}
+		}
+		for _, r := range s.Statements {
+			if err := exec(`INSERT INTO schema_script_sql (app, script, stmt, undo_stmt, reversible, rows_affected, warnings, skipped,
+				created_at) VALUES (?,?,?,?,?,?,?,?,?)`, app, r.Script, r.Stmt, nullable(r.UndoStmt), r.Reversible, r.RowsAffected,
+				nullable(r.Warnings), r.Skipped, timeArg(d, r.CreatedAt)); err != nil {
+				return fmt.Errorf("failed to save schema_script_sql, %w", err)
+			}
+		}
+		for _, r := range s.Objects {
+			if err := exec(`INSERT INTO schema_object (app, type, name, checksum, created_at, updated_at) VALUES (?,?,?,?,?,?)`,
+				app, r.Type, r.Name, r.Checksum, timeArg(d, r.CreatedAt), timeArg(d, r.UpdatedAt)); err != nil {
+				return fmt.Errorf("failed to save schema_object, %w", err)
+			}
+		}
+		for _, r := range s.Cursors {
+			if err := exec(`INSERT INTO schema_cursor (app, script, stmt_index, last_key, updated_at) VALUES (?,?,?,?,?)`,
+				app, r.Script, r.StmtIndex, r.LastKey, timeArg(d, r.UpdatedAt)); err != nil {
+				return fmt.Errorf("failed to save schema_cursor, %w", err)
+			}
+		}
+		for _, r := range s.Runs {
+			if err := exec(`INSERT INTO schema_run (app, started_at, ended_at, host, scripts, script_count, rows_affected, success,
+				error_msg, env, placeholders, record_uid) VALUES (?,?,?,?,?,?,?,?,?,?,?,?)`, app, timeArg(d, r.StartedAt), timeArg(d, r.EndedAt),
+				r.Host, r.Scripts, r.ScriptCount, r.RowsAffected, r.Success, nullable(r.ErrorMsg), r.Env, nullable(r.Placeholders),
+				r.RecordUid); err != nil {
+				return fmt.Errorf("failed to save schema_run, %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// NULL for the empty string.
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStateJSON(t *testing.T) {
+	var zero int64
+	at := time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	s := State{
+		App:        "myapp",
+		ExportedAt: at,
+		Versions:   []VersionRow{{Script: "v0.0.1.sql", Kind: kindVersioned, Success: true, Remark: "Executed", CreatedAt: at}},
+		Statements: []ScriptSQLRow{
+			{Script: "v0.0.1.sql", Stmt: "CREATE TABLE t (id INT)", RowsAffected: &zero, CreatedAt: at},
+			{Script: "v0.0.1.sql", Stmt: "ALTER TABLE t ADD COLUMN a INT", CreatedAt: at},
+		},
+	}
+	buf, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r State
+	if err := json.Unmarshal(buf, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.App != "myapp" || len(r.Versions) != 1 || !r.Versions[0].CreatedAt.Equal(at) {
+		t.Fatalf("incorrect state, %+v", r)
+	}
+
+	// statements not executed successfully are retried from the checkpoint, nil must be kept
+	if len(r.Statements) != 2 || r.Statements[0].RowsAffected == nil || r.Statements[1].RowsAffected != nil {
+		t.Fatalf("incorrect statements, %+v", r.Statements)
+	}
+
+	if err := ImportState(nil, r); err == nil {
+		t.Fatal("should fail")
+	}
+}
+
+func TestExportImportState(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	f := &fakeDB{}
+	f.reply(`^SELECT script, kind, success, remark, error_detail, failed_stmt, created_at, record_uid, author, ticket FROM schema_version WHERE app = \?`,
+		[]string{"script", "kind", "success", "remark", "error_detail", "failed_stmt", "created_at", "record_uid", "author", "ticket"},
+		[]driver.Value{"v0.0.1.sql", kindVersioned, true, "Executed", nil, nil, at, "uid-1", "alice", nil},
+		[]driver.Value{"v0.0.2.sql", kindVersioned, false, "table t exists", "table t exists", int64(2), at, nil, nil, "T-1"})
+	f.reply(`^SELECT script, stmt, undo_stmt, reversible, rows_affected, warnings, skipped, created_at FROM schema_script_sql WHERE app = \?`,
+		[]string{"script", "stmt", "undo_stmt", "reversible", "rows_affected", "warnings", "skipped", "created_at"},
+		[]driver.Value{"v0.0.1.sql", "CREATE TABLE t (id INT)", "DROP TABLE t", true, int64(0), nil, false, at},
+		[]driver.Value{"v0.0.2.sql", "CREATE TABLE t (id INT)", nil, false, nil, nil, false, at})
+	f.reply(`^SELECT type, name, checksum, created_at, updated_at FROM schema_object WHERE app = \?`,
+		[]string{"type", "name", "checksum", "created_at", "updated_at"}, []driver.Value{"view", "v_t", "abc", at, at})
+	f.reply(`^SELECT script, stmt_index, last_key, updated_at FROM schema_cursor WHERE app = \?`,
+		[]string{"script", "stmt_index", "last_key", "updated_at"}, []driver.Value{"v0.0.2.sql", int64(1), int64(100), at})
+	f.reply(`^SELECT started_at, ended_at, host, scripts, script_count, rows_affected, success, error_msg, env, placeholders, record_uid FROM schema_run WHERE app = \?`,
+		[]string{"started_at", "ended_at", "host", "scripts", "script_count", "rows_affected", "success", "error_msg", "env", "placeholders", "record_uid"},
+		[]driver.Value{at, at, "host-1", "v0.0.1.sql", int64(1), int64(0), true, "", "prod", "", nil})
+
+	s, err := ExportState(f.open(t), "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// read in one snapshot
+	var seq []string
+	for _, q := range f.executed(`^(BEGIN|COMMIT|ROLLBACK|SELECT)`) {
+		seq = append(seq, q.SQL)
+	}
+	if len(seq) != 7 || seq[0] != "BEGIN READ ONLY" || seq[6] != "COMMIT" {
+		t.Fatalf("tables should be read in one read-only transaction, %v", seq)
+	}
+
+	if s.App != "myapp" || len(s.Versions) != 2 || len(s.Statements) != 2 || len(s.Objects) != 1 || len(s.Cursors) != 1 || len(s.Runs) != 1 {
+		t.Fatalf("incorrect state, %+v", s)
+	}
+	v := s.Versions[1]
+	if s.Versions[0].RecordUid != "uid-1" || s.Versions[0].Author != "alice" || s.Versions[0].FailedStmt != nil ||
+		v.Success || v.ErrorDetail != "table t exists" || v.FailedStmt == nil || *v.FailedStmt != 2 || v.Ticket != "T-1" ||
+		!v.CreatedAt.Equal(at) {
+		t.Fatalf("incorrect versions, %+v", s.Versions)
+	}
+	if st := s.Statements; st[0].UndoStmt != "DROP TABLE t" || st[0].RowsAffected == nil || st[1].RowsAffected != nil || st[1].UndoStmt != "" {
+		t.Fatalf("incorrect statements, %+v", st)
+	}
+	if c := s.Cursors[0]; c.Script != "v0.0.2.sql" || c.StmtIndex != 1 || c.LastKey != 100 || !c.UpdatedAt.Equal(at) {
+		t.Fatalf("incorrect cursors, %+v", s.Cursors)
+	}
+	if r := s.Runs[0]; r.Host != "host-1" || r.Env != "prod" || r.RecordUid != "" || !r.StartedAt.Equal(at) {
+		t.Fatalf("incorrect runs, %+v", s.Runs)
+	}
+
+	f = &fakeDB{}
+	if err := ImportState(f.open(t), s); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range metaTableNames {
+		if q := f.executed(`^DELETE FROM ` + table + ` WHERE app = \?`); len(q) != 1 || q[0].Args[0] != "myapp" {
+			t.Fatalf("rows of %v should be replaced, %+v", table, q)
+		}
+	}
+	iv := f.executed(`^INSERT INTO schema_version`)
+	if len(iv) != 2 || iv[0].Args[0] != "myapp" || iv[0].Args[5] != nil || iv[0].Args[6] != nil || iv[0].Args[8] != "uid-1" ||
+		iv[1].Args[5] != "table t exists" || iv[1].Args[6] != int64(2) || iv[1].Args[10] != "T-1" {
+		t.Fatalf("incorrect schema_version, %+v", iv)
+	}
+	is := f.executed(`^INSERT INTO schema_script_sql`)
+	if len(is) != 2 || is[0].Args[3] != "DROP TABLE t" || is[0].Args[5] != int64(0) || is[1].Args[3] != nil || is[1].Args[5] != nil {
+		t.Fatalf("incorrect schema_script_sql, %+v", is)
+	}
+	if ic := f.executed(`^INSERT INTO schema_cursor`); len(ic) != 1 || ic[0].Args[2] != int64(1) || ic[0].Args[3] != int64(100) {
+		t.Fatalf("incorrect schema_cursor, %+v", ic)
+	}
+	if ir := f.executed(`^INSERT INTO schema_run`); len(ir) != 1 || ir[0].Args[8] != nil || ir[0].Args[3] != "host-1" {
+		t.Fatalf("incorrect schema_run, %+v", ir)
+	}
+	if io := f.executed(`^INSERT INTO schema_object`); len(io) != 1 || io[0].Args[3] != "abc" {
+		t.Fatalf("incorrect schema_object, %+v", io)
+	}
+
+	// the default app
+	f = &fakeDB{}
+	s.App = ""
+	if err := ImportState(f.open(t), s); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^DELETE FROM schema_version WHERE app = \?`); len(q) != 1 || q[0].Args[0] != "" {
+		t.Fatalf("rows of the default app should be replaced, %+v", q)
+	}
+	if iv := f.executed(`^INSERT INTO schema_version`); len(iv) != 2 || iv[0].Args[0] != "" {
+		t.Fatalf("incorrect schema_version, %+v", iv)
+	}
+}
@@ -0,0 +1,166 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StatusApplied = "applied" // executed successfully
+	StatusFailed  = "failed"  // executed, but failed
+	StatusIgnored = "ignored" // marked as ignored using IgnoreScript
+	StatusPending = "pending" // not executed yet, will be executed in next migration
+	StatusSkipped = "skipped" // not recorded, but before the current version, e.g., before the version initialized on first run
+)
+
+// Status of a versioned script.
+type ScriptStatus struct {
+	Script string
+	Status string
+
+	// Remark recorded in schema_version, e.g., the reason of being ignored.
+	Remark string
+
+	// Author and ticket recorded in schema_version, declared with the '-- author:' and '-- ticket:' header comments.
+	Author string
+	Ticket string
+}
+
+// Mark the versioned script as ignored, the script is never executed for the app and is not reported as pending in Status.
+//
+// It's mainly used when a script is intentionally skipped in one environment, e.g., a data fix that only applies to production.
+func IgnoreScript(db *gorm.DB, app string, script string, reason string) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	if err := dialectOf(db, nil).InitMetaTables(db, namespace); err != nil {
+		return err
+	}
+	meta := newStmtCache(db, nil, nil, nil, namespace)
+	defer meta.close()
+	return saveSchemaVer(meta, app, scriptName(script), kindIgnored, true, reason)
+}
+
+// Remove the ignored mark of the versioned script, the script is executed in next migration if it's still pending.
+func UnignoreScript(db *gorm.DB, app string, script string) error {
+	if db == nil {
+		return errors.New("db is nil")
+	}
+	if err := db.Exec(namespace.rewrite(`DELETE FROM schema_version WHERE app = ? AND script = ? AND kind = ?`),
+		appArg(dialectOf(db, nil), app), scriptName(script), kindIgnored).Error; err != nil {
+		return fmt.Errorf("failed to delete schema_version, %w", err)
+	}
+	return nil
+}
+
+// Normalize script name, e.g., 'V0.0.3' => 'v0.0.3.sql'.
+func scriptName(script string) string {
+	script = strings.ToLower(strings.TrimSpace(script))
+	if !strings.HasSuffix(script, ".sql") {
+		script += ".sql"
+	}
+	return script
+}
+
+func listIgnored(db *gorm.DB, ns Namespace, app string, suffix string) ([]string, error) {
+	var names []string
+	if err := db.Raw(ns.rewrite(`SELECT script FROM schema_version WHERE app = ? AND kind = ?`+suffix), app, kindIgnored).
+		Scan(&names).Error; err != nil {
+		return nil, fmt.Errorf("failed to list ignored scripts, %w", err)
+	}
+	return names, nil
+}
+
+// Report status of the versioned scripts in c.Fs, nothing is executed. The scripts marked with '-- svc:run-always'
+// are not reported.
+func Status(db *gorm.DB, c MigrateConfig) ([]ScriptStatus, error) {
+	if c.Fs == nil {
+		return nil, errors.New("fs is nil")
+	}
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	c.Dialect = dialectOf(db, c.Dialect)
+
+	var recorded []struct {
+		Script  string
+		Kind    string
+		Success bool
+		Remark  string
+		Author  *string
+		Ticket  *string
+	}
+	if err := db.Raw(c.ns().rewrite(`SELECT script, kind, success, remark, author, ticket FROM schema_version WHERE app = ? AND kind IN (?,?) ORDER BY id ASC`),
+		c.appArg(db), kindVersioned, kindIgnored).Scan(&recorded).Error; err != nil {
+		return nil, fmt.Errorf("failed to list schema_version, %w", err)
+	}
+
+	last := c.StartingVersion
+	srv := server{dialect: c.Dialect, ns: c.ns()}
+	baseline, ok, err := readBaseline(c.Fs, c.BaseDir, srv)
+	if err != nil {
+		return nil, err
+	}
+	if ok && last == "" {
+		last = baseline.Name
+	}
+	byName := map[string]ScriptStatus{}
+	for _, r := range recorded {
+		st := ScriptStatus{Script: r.Script, Remark: r.Remark}
+		if r.Author != nil {
+			st.Author = *r.Author
+		}
+		if r.Ticket != nil {
+			st.Ticket = *r.Ticket
+		}
+		switch {
+		case r.Kind == kindIgnored:
+			st.Status = StatusIgnored
+		case r.Success:
+			st.Status = StatusApplied
+		default:
+			st.Status = StatusFailed
+		}
+		if r.Kind == kindVersioned && (last == "" || VerAfter(r.Script, last)) {
+			last = r.Script
+		}
+		if prev, ok := byName[r.Script]; ok && prev.Status == StatusIgnored && r.Kind == kindVersioned {
+			continue
+		}
+		byName[r.Script] = st
+	}
+
+	files, err := discoverFiles(c.Fs, c.BaseDir, c.Recursive, c.isExcluded)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open %v folders, %w", c.BaseDir, err)
+	}
+	statuses := make([]ScriptStatus, 0, len(files))
+	for _, f := range files {
+		st, ok := byName[f.Name]
+		if !ok {
+			// run-always scripts are not versioned, they are never pending
+			sf, err := readSchemaFile(c.Fs, f, srv)
+			if err != nil {
+				return nil, err
+			}
+			if sf.RunAlways {
+				continue
+			}
+			st = ScriptStatus{Script: f.Name, Status: StatusPending}
+			if last != "" && !VerAfter(f.Name, last) {
+				st.Status = StatusSkipped
+			}
+		}
+		statuses = append(statuses, st)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return VerAfter(statuses[j].Script, statuses[i].Script) })
+	return statuses, nil
+}
@@ -0,0 +1,41 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScriptName(t *testing.T) {
+	for in, out := range map[string]string{
+		"v0.0.3":      "v0.0.3.sql",
+		" V0.0.3.SQL": "v0.0.3.sql",
+		"v0.0.3.sql":  "v0.0.3.sql",
+	} {
+		if n := scriptName(in); n != out {
+			t.Errorf("'%v' should be %v, %v", in, out, n)
+		}
+	}
+}
+
+func TestStatusRunAlways(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT script, kind, success, remark, author, ticket FROM schema_version`,
+		[]string{"script", "kind", "success", "remark", "author", "ticket"},
+		[]driver.Value{"v0.0.1.sql", kindVersioned, true, "Executed", nil, nil})
+	statuses, err := Status(f.open(t), MigrateConfig{
+		App: "test",
+		Fs: fstest.MapFS{
+			"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);")},
+			"schema/v0.0.2.sql": {Data: []byte("-- svc:run-always\nANALYZE TABLE t;")},
+			"schema/v0.0.3.sql": {Data: []byte("ALTER TABLE t ADD COLUMN a INT;")},
+		},
+		BaseDir: "schema",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].Status != StatusApplied || statuses[1].Script != "v0.0.3.sql" || statuses[1].Status != StatusPending {
+		t.Fatalf("run-always scripts should not be reported, %+v", statuses)
+	}
+}
@@ -0,0 +1,73 @@
+package svc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Summary of the migration, it's machine-parsable, e.g., for cron wrappers alerting on failures.
+type Summary struct {
+	App          string   `json:"app"`
+	Success      bool     `json:"success"`
+	Error        string   `json:"error,omitempty"`
+	DryRun       bool     `json:"dry_run"`
+	Scripts      []string `json:"scripts"`
+	Failed       []string `json:"failed,omitempty"` // scripts failed without stopping the migration
+	Empty        []string `json:"empty,omitempty"`  // scripts without statements, recorded as applied
+	Statements   int      `json:"statements"`
+	RowsAffected int64    `json:"rows_affected"`
+}
+
+// Summarize the migration.
+func NewSummary(app string, res Result, err error) Summary {
+	s := Summary{
+		App:          app,
+		Success:      err == nil,
+		DryRun:       res.DryRun,
+		Scripts:      []string{},
+		RowsAffected: res.RowsAffected,
+	}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	for _, sr := range res.Scripts {
+		s.Scripts = append(s.Scripts, sr.Script)
+		s.Statements += sr.Statements
+	}
+	for _, f := range res.Failures {
+		s.Failed = append(s.Failed, f.Script)
+	}
+	s.Empty = append(s.Empty, res.Empty...)
+	return s
+}
+
+// Single line summary in key=value format, values containing spaces are quoted, e.g.,
+//
+//	svc_summary app=myapp success=true dry_run=false scripts=v0.0.2.sql,v0.0.3.sql statements=5 rows_affected=12
+func (s Summary) String() string {
+	b := strings.Builder{}
+	b.WriteString("svc_summary")
+	kv := func(k string, v any) {
+		str := fmt.Sprint(v)
+		if str == "" || strings.ContainsAny(str, " \t\n\"=") {
+			str = fmt.Sprintf("%q", str)
+		}
+		b.WriteString(" " + k + "=" + str)
+	}
+	kv("app", s.App)
+	kv("success", s.Success)
+	kv("dry_run", s.DryRun)
+	kv("scripts", strings.Join(s.Scripts, ","))
+	if len(s.Failed) > 0 {
+		kv("failed", strings.Join(s.Failed, ","))
+	}
+	if len(s.Empty) > 0 {
+		kv("empty", strings.Join(s.Empty, ","))
+	}
+	kv("statements", s.Statements)
+	kv("rows_affected", s.RowsAffected)
+	if s.Error != "" {
+		kv("error", s.Error)
+	}
+	return b.String()
+}
@@ -0,0 +1,57 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSummary(t *testing.T) {
+	res := Result{Scripts: []ScriptResult{{Script: "v0.0.2.sql", Statements: 2, RowsAffected: 3}, {Script: "v0.0.3.sql", Statements: 1}},
+		RowsAffected: 3}
+	s := NewSummary("myapp", res, nil).String()
+	if s != "svc_summary app=myapp success=true dry_run=false scripts=v0.0.2.sql,v0.0.3.sql statements=3 rows_affected=3" {
+		t.Fatalf("incorrect summary, %v", s)
+	}
+
+	s = NewSummary("myapp", Result{}, errors.New("table t exists")).String()
+	if s != `svc_summary app=myapp success=false dry_run=false scripts="" statements=0 rows_affected=0 error="table t exists"` {
+		t.Fatalf("incorrect summary, %v", s)
+	}
+
+	res.Failures = []ScriptFailure{{Script: "v0.0.3.sql", Err: errors.New("table t exists")}}
+	s = NewSummary("myapp", res, nil).String()
+	if s != "svc_summary app=myapp success=true dry_run=false scripts=v0.0.2.sql,v0.0.3.sql failed=v0.0.3.sql statements=3 rows_affected=3" {
+		t.Fatalf("incorrect summary, %v", s)
+	}
+
+	var r Result
+	r.add(ScriptResult{Script: "v0.0.4.sql", Empty: true})
+	s = NewSummary("myapp", r, nil).String()
+	if s != "svc_summary app=myapp success=true dry_run=false scripts=v0.0.4.sql empty=v0.0.4.sql statements=0 rows_affected=0" {
+		t.Fatalf("incorrect summary, %v", s)
+	}
+}
+
+func TestSummaryLastLine(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	log := &bufLogger{}
+	_, err := Run(f.open(t), log, MigrateConfig{
+		App:       "myapp",
+		Fs:        fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);")}},
+		BaseDir:   "schema",
+		Publisher: &capturePublisher{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !log.contains("Published schema change event") {
+		t.Fatalf("event should be published, %v", log.lines)
+	}
+	if last := log.lines[len(log.lines)-1]; !strings.HasPrefix(last, "svc_summary app=myapp success=true") {
+		t.Fatalf("summary should be the last line, %v", last)
+	}
+}
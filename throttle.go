@@ -0,0 +1,215 @@
+package svc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// Returned (wrapped) when the replication lag doesn't drop below Throttle.MaxLag within Throttle.MaxWait.
+	ErrReplicationLag = errors.New("replication lag exceeds the threshold")
+)
+
+const (
+	// prefix of schema_version.remark of the scripts interrupted by the throttle
+	throttledRemarkPrefix = "Throttled, not executed: "
+)
+
+// Failure of the throttle, the statement is not executed, or the batches remain to be resumed from the cursor.
+type throttleError struct {
+	err error
+}
+
+func (e *throttleError) Error() string {
+	return e.err.Error()
+}
+
+func (e *throttleError) Unwrap() error {
+	return e.err
+}
+
+func isThrottleFailure(err error) bool {
+	var te *throttleError
+	return errors.As(err, &te)
+}
+
+func isThrottled(remark string) bool {
+	return strings.HasPrefix(remark, throttledRemarkPrefix)
+}
+
+// Save schema_version of the script interrupted by the throttle, no statement is recorded as failed, the script is
+// resumed from its checkpoint in the next migration regardless of OnPreviousFailure.
+func saveSchemaVerThrottled(meta *stmtCache, app string, script string, kind string, err error) error {
+	return saveSchemaVerDetail(meta, app, script, kind, false, throttledRemarkPrefix+err.Error(), err.Error(), 0)
+}
+
+// Function reporting the current replication lag, e.g., the max Seconds_Behind_Master of the replicas.
+type LagFunc func() (time.Duration, error)
+
+// Throttling of the statements, so that large migrations don't overwhelm the replication on busy clusters.
+//
+// svc waits before each statement (and each batch of the '-- svc:resumable' statements) is executed, first for the
+// fixed Delay, then, if ReplicationLag is provided, until the reported lag drops below MaxLag.
+//
+// The migration stops if the throttle fails, e.g., MaxWait is exceeded, even with ContinueOnError. The statement is
+// not recorded as failed, the script is resumed from its checkpoint in the next migration, whatever the
+// OnPreviousFailure is.
+type Throttle struct {
+	// Fixed delay before each statement, it's optional.
+	Delay time.Duration
+
+	// Replication lag reported by the user, e.g., ReplicaLag, it's optional. Errors fail the migration, the
+	// statement is not executed.
+	ReplicationLag LagFunc
+
+	// Max replication lag tolerated, required if ReplicationLag is provided.
+	MaxLag time.Duration
+
+	// Interval between the checks of the replication lag while waiting, defaults to 1 second.
+	CheckInterval time.Duration
+
+	// Max time waiting for the replication lag of a statement, the migration fails with ErrReplicationLag when
+	// it's exceeded, 0 means waiting indefinitely.
+	MaxWait time.Duration
+}
+
+func (t *Throttle) validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.Delay < 0 || t.MaxLag < 0 || t.CheckInterval < 0 || t.MaxWait < 0 {
+		return errors.New("invalid Throttle, durations must not be negative")
+	}
+	if t.ReplicationLag != nil && t.MaxLag <= 0 {
+		return errors.New("invalid Throttle, MaxLag is required if ReplicationLag is provided")
+	}
+	return nil
+}
+
+// Wait before the statement is executed, it's a no-op if the throttle is nil. The errors are *throttleError.
+func (t *Throttle) wait(ctx context.Context, log Logger, script string, stmt int) error {
+	if t == nil {
+		return nil
+	}
+	if err := t.waitLag(ctx, log, script, stmt); err != nil {
+		return &throttleError{err: err}
+	}
+	return nil
+}
+
+func (t *Throttle) waitLag(ctx context.Context, log Logger, script string, stmt int) error {
+	if err := sleepCtx(ctx, t.Delay); err != nil {
+		return err
+	}
+	if t.ReplicationLag == nil {
+		return nil
+	}
+	interval := t.CheckInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	var waited time.Duration
+	for {
+		lag, err := t.ReplicationLag()
+		if err != nil {
+			return fmt.Errorf("failed to check replication lag, %w", err)
+		}
+		if lag < t.MaxLag {
+			return nil
+		}
+		if t.MaxWait > 0 && waited >= t.MaxWait {
+			return fmt.Errorf("%w, lag: %v, max lag: %v, waited: %v", ErrReplicationLag, lag, t.MaxLag, waited)
+		}
+		log.Infof("'%v' - [%v] replication lag %v exceeds %v, waiting", script, stmt, lag, t.MaxLag)
+		if err := sleepCtx(ctx, interval); err != nil {
+			return err
+		}
+		waited += interval
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// LagFunc reporting Seconds_Behind_Master (or Seconds_Behind_Source) of the MySQL replica, replica is the
+// connection to the replica, not the primary that the migration runs on. The max lag of the replication channels is
+// reported if the replica has more than one, e.g., multi-source replication.
+//
+// An error is returned if the replication is not running, i.e., the lag of any channel is NULL or the server is
+// not a replica.
+func ReplicaLag(replica *gorm.DB) LagFunc {
+	return func() (time.Duration, error) {
+		rows, err := replica.Raw("SHOW REPLICA STATUS").Rows()
+		if err != nil {
+			// before MySQL 8.0.22
+			if rows, err = replica.Raw("SHOW SLAVE STATUS").Rows(); err != nil {
+				return 0, fmt.Errorf("failed to show replica status, %w", err)
+			}
+		}
+		defer rows.Close()
+		cols, err := rows.Columns()
+		if err != nil {
+			return 0, err
+		}
+		lagCol := -1
+		for i, c := range cols {
+			if strings.EqualFold(c, "Seconds_Behind_Master") || strings.EqualFold(c, "Seconds_Behind_Source") {
+				lagCol = i
+				break
+			}
+		}
+		if lagCol < 0 {
+			return 0, errors.New("Seconds_Behind_Master not found in replica status")
+		}
+		vals := make([]sql.RawBytes, len(cols))
+		dest := make([]any, len(cols))
+		for i := range vals {
+			dest[i] = &vals[i]
+		}
+
+		// one row for each channel
+		var max time.Duration
+		channels := 0
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				return 0, err
+			}
+			channels++
+			if vals[lagCol] == nil {
+				return 0, errors.New("replication is not running")
+			}
+			sec, err := strconv.ParseInt(string(vals[lagCol]), 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("malformed %v '%s', %w", cols[lagCol], vals[lagCol], err)
+			}
+			if lag := time.Duration(sec) * time.Second; lag > max {
+				max = lag
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		if channels == 0 {
+			return 0, errors.New("server is not a replica")
+		}
+		return max, nil
+	}
+}
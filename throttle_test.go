@@ -0,0 +1,139 @@
+package svc
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	var tt *Throttle
+	if err := tt.wait(context.Background(), PrintLogger{}, "v0.0.1.sql", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	lags := []time.Duration{5 * time.Second, 3 * time.Second, 0}
+	checks := 0
+	tt = &Throttle{
+		Delay:         time.Millisecond,
+		MaxLag:        time.Second,
+		CheckInterval: time.Millisecond,
+		ReplicationLag: func() (time.Duration, error) {
+			lag := lags[checks]
+			checks++
+			return lag, nil
+		},
+	}
+	if err := tt.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tt.wait(context.Background(), PrintLogger{}, "v0.0.1.sql", 1); err != nil {
+		t.Fatal(err)
+	}
+	if checks != 3 {
+		t.Fatalf("should check lag 3 times, %v", checks)
+	}
+
+	tt.MaxWait = 2 * time.Millisecond
+	tt.ReplicationLag = func() (time.Duration, error) { return time.Minute, nil }
+	if err := tt.wait(context.Background(), PrintLogger{}, "v0.0.1.sql", 1); !errors.Is(err, ErrReplicationLag) {
+		t.Fatalf("should be ErrReplicationLag, %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tt = &Throttle{Delay: time.Hour}
+	if err := tt.wait(ctx, PrintLogger{}, "v0.0.1.sql", 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("should be canceled, %v", err)
+	}
+
+	invalid := []Throttle{
+		{Delay: -time.Second},
+		{ReplicationLag: func() (time.Duration, error) { return 0, nil }},
+	}
+	for _, it := range invalid {
+		if err := it.validate(); err == nil {
+			t.Fatalf("%+v should be invalid", it)
+		}
+	}
+}
+
+func TestThrottledResume(t *testing.T) {
+	c := MigrateConfig{
+		App: "test",
+		Fs: fstest.MapFS{
+			"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+			"schema/v0.0.2.sql": {Data: []byte("CREATE TABLE b (id INT);\nCREATE TABLE c (id INT);")},
+		},
+		BaseDir: "schema",
+	}
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT id, script, success, remark FROM schema_version`, []string{"id", "script", "success", "remark"},
+		[]driver.Value{int64(1), "v0.0.1.sql", true, "Executed"})
+	checks := 0
+	c.Throttle = &Throttle{MaxLag: time.Second, ReplicationLag: func() (time.Duration, error) {
+		if checks++; checks > 1 {
+			return 0, errors.New("replica is down")
+		}
+		return 0, nil
+	}}
+	c.ContinueOnError = true
+	if _, err := Run(f.open(t), PrintLogger{}, c); err == nil || !isThrottleFailure(err) {
+		t.Fatalf("throttle failure should stop the migration, %v", err)
+	}
+	if q := f.executed(`^CREATE TABLE c `); len(q) > 0 {
+		t.Fatalf("statement should not be executed, %+v", q)
+	}
+	v := f.executed(`^INSERT INTO schema_version`)
+	if len(v) != 1 || !strings.HasPrefix(v[0].Args[4].(string), throttledRemarkPrefix) || v[0].Args[6] != nil {
+		t.Fatalf("script should be recorded as throttled, %+v", v)
+	}
+
+	// resumed from the checkpoint, even with FailHard
+	f = &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT id, script, success, remark FROM schema_version`, []string{"id", "script", "success", "remark"},
+		[]driver.Value{int64(2), "v0.0.2.sql", false, throttledRemarkPrefix + "failed to check replication lag, replica is down"})
+	f.reply(`^SELECT stmt FROM schema_script_sql WHERE app = \? AND script = \? AND rows_affected IS NOT NULL`, []string{"stmt"},
+		[]driver.Value{"CREATE TABLE b (id INT)"})
+	c.Throttle, c.ContinueOnError = nil, false
+	if _, err := Run(f.open(t), PrintLogger{}, c); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^CREATE TABLE b `); len(q) > 0 {
+		t.Fatalf("executed statement should be skipped, %+v", q)
+	}
+	if q := f.executed(`^CREATE TABLE c `); len(q) != 1 {
+		t.Fatalf("rest of the script should be executed, %+v", q)
+	}
+}
+
+func TestReplicaLag(t *testing.T) {
+	f := &fakeDB{}
+	cols := []string{"Channel_Name", "Seconds_Behind_Source"}
+	f.reply(`^SHOW REPLICA STATUS$`, cols, []driver.Value{"a", "3"}, []driver.Value{"b", "7"}, []driver.Value{"c", "1"})
+	lag, err := ReplicaLag(f.open(t))()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lag != 7*time.Second {
+		t.Fatalf("should report the max lag of the channels, %v", lag)
+	}
+
+	f = &fakeDB{}
+	f.reply(`^SHOW REPLICA STATUS$`, cols, []driver.Value{"a", "3"}, []driver.Value{"b", nil})
+	if _, err := ReplicaLag(f.open(t))(); err == nil {
+		t.Fatal("stopped channel should be reported")
+	}
+
+	f = &fakeDB{}
+	f.reply(`^SHOW REPLICA STATUS$`, cols)
+	if _, err := ReplicaLag(f.open(t))(); err == nil {
+		t.Fatal("server is not a replica")
+	}
+}
@@ -0,0 +1,296 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	identPat = "(?:`[^`]+`|[\\w$]+)(?:\\.(?:`[^`]+`|[\\w$]+))?"
+)
+
+var (
+	undoCreateTableRegex    = regexp.MustCompile(`(?is)^CREATE\s+(?:TEMPORARY\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + identPat + `)`)
+	undoCreateSequenceRegex = regexp.MustCompile(`(?is)^CREATE\s+SEQUENCE\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + identPat + `)`)
+	undoCreateIndexRegex    = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+|FULLTEXT\s+|SPATIAL\s+)?INDEX\s+(` + identPat + `)\s+(?:USING\s+\w+\s+)?ON\s+(` + identPat + `)`)
+	undoAlterAddRegex       = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(` + identPat + `)\s+ADD\s+(.*)$`)
+	undoAddIndexRegex       = regexp.MustCompile(`(?is)^(?:UNIQUE\s+)?(?:INDEX|KEY)\s+(` + identPat + `)`)
+	undoAddColumnRegex      = regexp.MustCompile(`(?is)^(?:COLUMN\s+)?(` + identPat + `)\s`)
+	undoIfNotExistsRegex    = regexp.MustCompile(`(?is)\bIF\s+NOT\s+EXISTS\b`)
+
+	// keywords following ADD that are not column names
+	undoAddKeywords = map[string]struct{}{
+		"constraint": {}, "primary": {}, "foreign": {}, "unique": {}, "fulltext": {}, "spatial": {},
+		"partition": {}, "check": {}, "index": {}, "key": {},
+	}
+)
+
+// Derive the inverse of simple DDL, i.e., CREATE TABLE, CREATE SEQUENCE, CREATE INDEX, ALTER TABLE ... ADD COLUMN / INDEX.
+//
+// Returns false if the statement is irreversible (or svc doesn't know how to reverse it). Statements with IF NOT EXISTS
+// are irreversible, the object may have existed before the statement is executed. The undo statements are written
+// for the dialect, e.g., Oracle's DROP INDEX has no ON clause, and ADD INDEX is MySQL only.
+func deriveUndo(d Dialect, sql string) (string, bool) {
+	oracle := d != nil && d.Name() == DialectOracle
+	_, rest := splitLeadingComments(sql)
+	rest = strings.TrimSpace(rest)
+	if undoIfNotExistsRegex.MatchString(rest) {
+		return "", false
+	}
+
+	if m := undoCreateTableRegex.FindStringSubmatch(rest); m != nil {
+		return fmt.Sprintf("DROP TABLE %s", m[1]), true
+	}
+	if m := undoCreateSequenceRegex.FindStringSubmatch(rest); m != nil {
+		return fmt.Sprintf("DROP SEQUENCE %s", m[1]), true
+	}
+	if m := undoCreateIndexRegex.FindStringSubmatch(rest); m != nil {
+		if oracle {
+			return fmt.Sprintf("DROP INDEX %s", m[1]), true
+		}
+		return fmt.Sprintf("DROP INDEX %s ON %s", m[1], m[2]), true
+	}
+	if m := undoAlterAddRegex.FindStringSubmatch(rest); m != nil {
+		table, clause := m[1], strings.TrimSpace(m[2])
+
+		// multiple clauses in one ALTER, e.g., ADD COLUMN a INT, ADD COLUMN b INT
+		if hasTopLevelComma(clause) {
+			return "", false
+		}
+		if m := undoAddIndexRegex.FindStringSubmatch(clause); m != nil {
+			if oracle {
+				return "", false
+			}
+			return fmt.Sprintf("ALTER TABLE %s DROP INDEX %s", table, m[1]), true
+		}
+		if m := undoAddColumnRegex.FindStringSubmatch(clause); m != nil {
+			if _, ok := undoAddKeywords[strings.ToLower(m[1])]; ok {
+				return "", false
+			}
+			return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", table, m[1]), true
+		}
+	}
+	return "", false
+}
+
+// Check if there is comma that is not enclosed by parentheses or quotes.
+func hasTopLevelComma(s string) bool {
+	depth := 0
+	var quote rune
+	for _, r := range s {
+		if quote != 0 {
+			if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '\'', '"', '`':
+			quote = r
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Split s at the commas that are not enclosed by parentheses or quotes, e.g., the clauses of ALTER TABLE.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote rune
+	for i, r := range s {
+		if quote != 0 {
+			if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '\'', '"', '`':
+			quote = r
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+type executedStmt struct {
+	Id         int64
+	Script     string
+	Stmt       string
+	UndoStmt   string
+	Reversible bool
+	Skipped    bool
+
+	// nil if the statement is not executed successfully, e.g., it failed, or the migration was killed before it
+	RowsAffected *int64
+}
+
+// Rollback scripts that are after the target version using the undo statements recorded in schema_script_sql.
+//
+// The scripts are rolled back in the reverse order in which they were recorded in schema_version (i.e., the order of
+// execution, not the order of versions), and the statements are undone in the reverse order of their execution. The
+// records of the rolled back scripts are removed from schema_version and schema_script_sql. If any of the statements
+// is irreversible, nothing is rolled back and an error is returned. The statements skipped by MigrateConfig.SmartSkip
+// are not undone, the objects existed before, neither are the statements not executed successfully (e.g., the failed
+// ones, or the ones of the scripts skipped by SkipFailed), only their records are removed.
+//
+// Rollback is not transactional, DDL can't be rolled back (it commits implicitly in MySQL). If a statement fails,
+// Rollback stops, the statements undone so far remain undone and their records are removed, so Rollback can be
+// called again once the failure is fixed.
+//
+// If MigrateConfig.Fs is provided, and the script contains the '-- migrate:down' section, the statements in
+// the down section are executed instead of the recorded undo statements. The statements of the scripts declaring
+// '-- svc:database' are only undone in the declared database if MigrateConfig.Fs is provided.
+func Rollback(db *gorm.DB, log Logger, c MigrateConfig, targetVer string) error {
+	if log == nil {
+		return errors.New("log is nil")
+	}
+	db, closeConn, err := c.connect(db)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+	if db == nil {
+		return errors.New("db is nil")
+	}
+
+	var applied []schemaVersion
+	if err := db.Raw(c.ns().rewrite(`SELECT id, script, success, remark FROM schema_version WHERE app = ? AND kind = ? ORDER BY id DESC`),
+		c.appArg(db), kindVersioned).
+		Scan(&applied).Error; err != nil {
+		return fmt.Errorf("failed to list schema_version, %w", err)
+	}
+
+	down := map[string][]string{}
+	databases := map[string]string{}
+	if c.Fs != nil {
+		srv, err := detectServer(db, c.Dialect)
+		if err != nil {
+			return err
+		}
+		srv.ns = c.ns()
+		files, err := discoverFiles(c.Fs, c.BaseDir, c.Recursive, c.isExcluded)
+		if err != nil {
+			return fmt.Errorf("failed to discover scripts, %w", err)
+		}
+		for _, f := range files {
+			if !VerAfter(f.Name, targetVer) {
+				continue
+			}
+			sf, err := readSchemaFile(c.Fs, f, srv)
+			if err != nil {
+				return err
+			}
+			if len(sf.Down) > 0 {
+				down[sf.Name] = sf.Down
+			}
+			if sf.Database != "" {
+				databases[sf.Name] = resolvePlaceholders(sf.Database, c.Placeholders)
+			}
+		}
+	}
+
+	type rollbackScript struct {
+		ver   schemaVersion
+		down  []string
+		stmts []executedStmt
+	}
+	cph, err := c.stmtCipher()
+	if err != nil {
+		return err
+	}
+	scripts := []rollbackScript{}
+	irreversible := []string{}
+	for _, v := range applied {
+		if !VerAfter(v.Script, targetVer) {
+			continue
+		}
+		var stmts []executedStmt
+		if err := db.Raw(c.ns().rewrite(`SELECT id, script, stmt, undo_stmt, reversible, skipped, rows_affected FROM schema_script_sql
+			WHERE app = ? AND script = ? ORDER BY id DESC`), c.appArg(db), v.Script).Scan(&stmts).Error; err != nil {
+			return fmt.Errorf("failed to list schema_script_sql, %w", err)
+		}
+		for i := range stmts {
+			if stmts[i].Stmt, err = cph.decrypt(c.App, v.Script, stmts[i].Stmt); err != nil {
+				return err
+			}
+		}
+		if d, ok := down[v.Script]; ok {
+			scripts = append(scripts, rollbackScript{ver: v, down: d, stmts: stmts})
+			continue
+		}
+		if len(stmts) < 1 {
+			irreversible = append(irreversible, fmt.Sprintf("%v: no statement recorded", v.Script))
+		}
+		for _, s := range stmts {
+			if !s.Reversible && !s.Skipped && s.RowsAffected != nil {
+				irreversible = append(irreversible, fmt.Sprintf("%v: '%v'", v.Script, s.Stmt))
+			}
+		}
+		scripts = append(scripts, rollbackScript{ver: v, stmts: stmts})
+	}
+	if len(irreversible) > 0 {
+		return fmt.Errorf("unable to rollback to %v, found irreversible statements:\n%v", targetVer, strings.Join(irreversible, "\n"))
+	}
+
+	for _, s := range scripts {
+		database := databases[s.ver.Script]
+		for _, sql := range s.down {
+			if err := withDatabase(db, c.Dialect, database, func(conn *gorm.DB) error {
+				return conn.Exec(resolvePlaceholders(sql, c.Placeholders)).Error
+			}); err != nil {
+				return fmt.Errorf("failed to rollback %v, '%v', %w", s.ver.Script, sql, err)
+			}
+			log.Infof("'%v' - down: \n\n%v\n", s.ver.Script, sql)
+		}
+		if len(s.down) > 0 {
+			if err := db.Exec(c.ns().rewrite(`DELETE FROM schema_script_sql WHERE app = ? AND script = ?`), c.appArg(db), s.ver.Script).Error; err != nil {
+				return fmt.Errorf("failed to delete schema_script_sql, %w", err)
+			}
+			s.stmts = nil
+		}
+		for _, st := range s.stmts {
+			if st.Skipped {
+				log.Infof("'%v' - skipped by smart-skip, not undone: \n\n%v\n", s.ver.Script, st.Stmt)
+			} else if st.RowsAffected == nil {
+				log.Infof("'%v' - not executed, not undone: \n\n%v\n", s.ver.Script, st.Stmt)
+			} else {
+				undo := resolvePlaceholders(st.UndoStmt, c.Placeholders)
+				if err := withDatabase(db, c.Dialect, database, func(conn *gorm.DB) error {
+					return conn.Exec(undo).Error
+				}); err != nil {
+					return fmt.Errorf("failed to rollback %v, '%v', %w", s.ver.Script, undo, err)
+				}
+				log.Infof("'%v' - undone: \n\n%v\n", s.ver.Script, undo)
+			}
+			if err := db.Exec(c.ns().rewrite(`DELETE FROM schema_script_sql WHERE id = ?`), st.Id).Error; err != nil {
+				return fmt.Errorf("failed to delete schema_script_sql, %w", err)
+			}
+		}
+		if err := db.Exec(c.ns().rewrite(`DELETE FROM schema_version WHERE id = ?`), s.ver.Id).Error; err != nil {
+			return fmt.Errorf("failed to delete schema_version, %w", err)
+		}
+		log.Infof("Script %v rolled back", s.ver.Script)
+	}
+	return nil
+}
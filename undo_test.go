@@ -0,0 +1,129 @@
+package svc
+
+import (
+	"database/sql/driver"
+	"testing"
+	"testing/fstest"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+func TestDeriveUndo(t *testing.T) {
+	cases := [][2]string{
+		{"CREATE TABLE `t` (id INT, name VARCHAR(10))", "DROP TABLE `t`"},
+		{"-- comment\ncreate table db.t (id INT)", "DROP TABLE db.t"},
+		{"CREATE UNIQUE INDEX name_idx ON t (name)", "DROP INDEX name_idx ON t"},
+		{"ALTER TABLE t ADD COLUMN price DECIMAL(10,2) NOT NULL DEFAULT 0", "ALTER TABLE t DROP COLUMN price"},
+		{"ALTER TABLE t ADD name VARCHAR(10)", "ALTER TABLE t DROP COLUMN name"},
+		{"ALTER TABLE t ADD UNIQUE KEY name_idx (name)", "ALTER TABLE t DROP INDEX name_idx"},
+	}
+	for _, c := range cases {
+		v, ok := deriveUndo(MySQLDialect{}, c[0])
+		if !ok {
+			t.Fatalf("'%v' should be reversible", c[0])
+		}
+		if v != c[1] {
+			t.Fatalf("undo of '%v' should be '%v', but got '%v'", c[0], c[1], v)
+		}
+	}
+
+	// Oracle
+	if v, ok := deriveUndo(OracleDialect{}, "CREATE UNIQUE INDEX name_idx ON t (name)"); !ok || v != "DROP INDEX name_idx" {
+		t.Fatalf("incorrect undo of Oracle index, %v", v)
+	}
+	if v, ok := deriveUndo(OracleDialect{}, "ALTER TABLE t ADD UNIQUE KEY name_idx (name)"); ok {
+		t.Fatalf("ADD INDEX should be irreversible on Oracle, %v", v)
+	}
+	if v, ok := deriveUndo(OracleDialect{}, "ALTER TABLE t ADD name VARCHAR2(10)"); !ok || v != "ALTER TABLE t DROP COLUMN name" {
+		t.Fatalf("incorrect undo of Oracle column, %v", v)
+	}
+
+	irreversible := []string{
+		"ALTER TABLE t ADD COLUMN a INT, ADD COLUMN b INT",
+		"ALTER TABLE t ADD CONSTRAINT fk FOREIGN KEY (a) REFERENCES r (id)",
+		"ALTER TABLE t DROP COLUMN a",
+		"UPDATE t SET a = 1",
+		"DROP TABLE t",
+		"CREATE TABLE IF NOT EXISTS `t` (id INT, name VARCHAR(10))",
+		"CREATE SEQUENCE IF NOT EXISTS seq",
+		"ALTER TABLE t ADD COLUMN IF NOT EXISTS a INT",
+	}
+	for _, s := range irreversible {
+		if v, ok := deriveUndo(MySQLDialect{}, s); ok {
+			t.Fatalf("'%v' should be irreversible, but got '%v'", s, v)
+		}
+	}
+}
+
+func TestAdoptIrreversible(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.on(`^CREATE TABLE t `, func(args []driver.Value) (fakeRows, error) {
+		return fakeRows{}, &mysqldriver.MySQLError{Number: 1050, Message: "Table 't' already exists"}
+	})
+	_, err := Run(f.open(t), PrintLogger{}, MigrateConfig{
+		App:     "test",
+		Fs:      fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);\nCREATE TABLE t2 (id INT);")}},
+		BaseDir: "schema",
+		Adopt:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := f.executed(`^INSERT INTO schema_script_sql`)
+	if len(recorded) != 2 || recorded[0].Args[3] != "DROP TABLE t" || recorded[0].Args[4] != true {
+		t.Fatalf("incorrect schema_script_sql, %+v", recorded)
+	}
+	tolerated := f.executed(`^UPDATE schema_script_sql SET rows_affected = \?, undo_stmt = NULL, reversible = \?`)
+	if len(tolerated) != 1 || tolerated[0].Args[1] != false {
+		t.Fatalf("statement tolerated in adopt mode should be irreversible, %+v", tolerated)
+	}
+
+	f = &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	_, err = Run(f.open(t), PrintLogger{}, MigrateConfig{
+		App:                "test",
+		Fs:                 fstest.MapFS{"schema/v0.0.1.sql": {Data: []byte("CREATE TABLE t (id INT);")}},
+		BaseDir:            "schema",
+		RewriteIfNotExists: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded = f.executed(`^INSERT INTO schema_script_sql`)
+	if len(recorded) != 1 || recorded[0].Args[3] != nil || recorded[0].Args[4] != false {
+		t.Fatalf("statement rewritten to IF NOT EXISTS should be irreversible, %+v", recorded)
+	}
+	if q := f.executed(`^CREATE TABLE IF NOT EXISTS t `); len(q) != 1 {
+		t.Fatalf("statement should be rewritten, %+v", q)
+	}
+}
+
+func TestRollbackNotExecuted(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT id, script, success, remark FROM schema_version`, []string{"id", "script", "success", "remark"},
+		[]driver.Value{int64(2), "v0.0.2.sql", true, "Skipped after failure: table t3 exists"},
+		[]driver.Value{int64(1), "v0.0.1.sql", true, "Executed"})
+	cols := []string{"id", "script", "stmt", "undo_stmt", "reversible", "skipped", "rows_affected"}
+	f.on(`^SELECT id, script, stmt, undo_stmt, reversible, skipped, rows_affected FROM schema_script_sql`, func(args []driver.Value) (fakeRows, error) {
+		if args[1] == "v0.0.2.sql" {
+			// failed, and the statement after it is never reached
+			return fakeRows{cols: cols, rows: [][]driver.Value{
+				{int64(3), "v0.0.2.sql", "CREATE INDEX a_idx ON t3 (a)", "DROP INDEX a_idx ON t3", true, false, nil},
+				{int64(2), "v0.0.2.sql", "CREATE TABLE t3 (id INT)", "DROP TABLE t3", true, false, nil},
+			}}, nil
+		}
+		return fakeRows{cols: cols, rows: [][]driver.Value{
+			{int64(1), "v0.0.1.sql", "CREATE TABLE t (id INT)", "DROP TABLE t", true, false, int64(0)},
+		}}, nil
+	})
+	if err := Rollback(f.open(t), PrintLogger{}, MigrateConfig{App: "test"}, "v0.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if q := f.executed(`^DROP`); len(q) != 1 || q[0].SQL != "DROP TABLE t" {
+		t.Fatalf("only the executed statements should be undone, %+v", q)
+	}
+	if q := f.executed(`^DELETE FROM schema_script_sql WHERE id = \?`); len(q) != 3 {
+		t.Fatalf("records should be removed, %+v", q)
+	}
+}
@@ -1,6 +1,11 @@
 package svc
 
 import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cast"
@@ -10,55 +15,106 @@ const (
 	VerSep = "."
 )
 
-// Check if ver1 is eq to ver2.
-func VerEq(ver1 string, ver2 string) bool {
-	ver1Sp := SplitVer(ver1)
-	ver2Sp := SplitVer(ver2)
-	ver1Sp, ver2Sp = PadVers(ver1Sp, ver2Sp)
-	for i := 0; i < len(ver1Sp); i++ {
-		l := cast.ToInt(ver1Sp[i])
-		r := cast.ToInt(ver2Sp[i])
-		if l != r {
-			return false
-		}
+var (
+	verFileRegex = regexp.MustCompile(`^v?\d+(?:\.\d+)*$`)
+)
+
+// Version helpers
+//
+// Versions are compared segment by segment, e.g., 'v1.2.3.4.5' is split into [1 2 3 4 5], there is no limit on
+// the number of segments. Before comparison:
+//
+//   - the version is lower-cased, the leading 'v' (or 'V') is optional and removed,
+//   - the '.sql' suffix (case-insensitive) is removed, so 'V1.2.sql' and 'v1.2' are the same version,
+//   - the shorter version is padded with '0' segments, i.e., 'v1' == 'v1.0' == 'v1.0.0.0',
+//   - each segment is parsed as a decimal integer, leading zeros are ignored ('v1.08' == 'v1.8'), and segments
+//     that are not integers are treated as 0.
+//
+// These are the rules svc uses to order and compare scripts, tools working with svc's scripts should use these
+// helpers (and FileVer) instead of re-implementing them.
+//
+// Compatibility: the earlier versions of svc parsed the segments with base prefixes, a segment with a leading zero
+// was octal ('010' => 8, '08' and '09' => 0), and the shorter version was only padded when it's the second one
+// ('v1' == 'v1.0.1', but 'v1.0.1' is after 'v1'). The migration fails if the scripts recorded in schema_version are
+// ordered differently by the two rules, see verRuleConflict.
+
+// Version parsed for comparison, see ParseVer.
+//
+// Parsing is done once, it's cheaper to compare (and sort) the parsed versions than the version strings, e.g., when
+// the scripts of thousands of tenants are sorted.
+type Version struct {
+	// Segments of the version, e.g., 'v1.2.3' => [1 2 3], the trailing '0' segments are kept as is.
+	Segments []uint64
+}
+
+// Parse version using the rules of CompareVer, e.g., 'V1.02.3.sql' => [1 2 3].
+func ParseVer(ver string) Version {
+	sp := SplitVer(ver)
+	segs := make([]uint64, len(sp))
+	for i, s := range sp {
+		segs[i] = verSegment(s)
 	}
-	return true
+	return Version{Segments: segs}
 }
 
-// Check if ver1 is after or eq to ver2.
-func VerAfterEq(ver1 string, ver2 string) bool {
-	ver1Sp := SplitVer(ver1)
-	ver2Sp := SplitVer(ver2)
-	ver1Sp, ver2Sp = PadVers(ver1Sp, ver2Sp)
-	for i := 0; i < len(ver1Sp); i++ {
-		l := cast.ToInt(ver1Sp[i])
-		r := cast.ToInt(ver2Sp[i])
+// Compare v with o, returns -1 if v is before o, 0 if they are equal, and 1 if v is after o.
+//
+// The shorter version is treated as if it's padded with '0' segments.
+func (v Version) Compare(o Version) int {
+	n := len(v.Segments)
+	if len(o.Segments) > n {
+		n = len(o.Segments)
+	}
+	for i := 0; i < n; i++ {
+		var l, r uint64
+		if i < len(v.Segments) {
+			l = v.Segments[i]
+		}
+		if i < len(o.Segments) {
+			r = o.Segments[i]
+		}
 		if l > r {
-			return true
+			return 1
 		} else if l < r {
-			return false
+			return -1
 		}
 	}
-	return true
+	return 0
+}
+
+// Format the version, e.g., [1 2 3] => 'v1.2.3', empty if there is no segment.
+func (v Version) String() string {
+	if len(v.Segments) < 1 {
+		return ""
+	}
+	segs := make([]string, len(v.Segments))
+	for i, s := range v.Segments {
+		segs[i] = strconv.FormatUint(s, 10)
+	}
+	return "v" + strings.Join(segs, VerSep)
+}
+
+// Compare ver1 with ver2, returns -1 if ver1 is before ver2, 0 if they are equal, and 1 if ver1 is after ver2.
+func CompareVer(ver1 string, ver2 string) int {
+	return ParseVer(ver1).Compare(ParseVer(ver2))
+}
+
+// Check if ver1 is eq to ver2.
+func VerEq(ver1 string, ver2 string) bool {
+	return CompareVer(ver1, ver2) == 0
+}
+
+// Check if ver1 is after or eq to ver2.
+func VerAfterEq(ver1 string, ver2 string) bool {
+	return CompareVer(ver1, ver2) >= 0
 }
 
 // Check if ver1 is after ver2.
 func VerAfter(ver1 string, ver2 string) bool {
-	ver1Sp := SplitVer(ver1)
-	ver2Sp := SplitVer(ver2)
-	ver1Sp, ver2Sp = PadVers(ver1Sp, ver2Sp)
-	for i := 0; i < len(ver1Sp); i++ {
-		l := cast.ToInt(ver1Sp[i])
-		r := cast.ToInt(ver2Sp[i])
-		if l > r {
-			return true
-		} else if l < r {
-			return false
-		}
-	}
-	return false
+	return CompareVer(ver1, ver2) > 0
 }
 
+// Split version into segments, e.g., 'V1.2.3.sql' => [1 2 3].
 func SplitVer(ver string) []string {
 	ver = strings.ToLower(ver)
 	ver = strings.TrimPrefix(ver, "v")
@@ -70,12 +126,13 @@ func SplitVer(ver string) []string {
 func PadVers(ver1 []string, ver2 []string) ([]string, []string) {
 	if len(ver1) > len(ver2) {
 		return ver1, PadVer(ver2, len(ver1))
-	} else if len(ver1) > len(ver2) {
+	} else if len(ver1) < len(ver2) {
 		return PadVer(ver1, len(ver2)), ver2
 	}
 	return ver1, ver2
 }
 
+// Pad version segments with '0' to length s.
 func PadVer(ver1 []string, s int) []string {
 	cp := make([]string, s)
 	for i := 0; i < s; i++ {
@@ -87,3 +144,96 @@ func PadVer(ver1 []string, s int) []string {
 	}
 	return cp
 }
+
+// Extract version from the path or name of a script, e.g., 'schema/svc/V1.2.3.4.sql' => 'v1.2.3.4'.
+//
+// Returns false if the file name is not a version, e.g., 'baseline.sql', 'v1.2-fix.sql' or 'v1.2' (missing
+// the '.sql' suffix).
+func FileVer(fname string) (string, bool) {
+	name := strings.ToLower(path.Base(strings.ReplaceAll(fname, "\\", "/")))
+	if !strings.HasSuffix(name, ".sql") {
+		return "", false
+	}
+	name = strings.TrimSuffix(name, ".sql")
+	if !verFileRegex.MatchString(name) {
+		return "", false
+	}
+	return "v" + strings.TrimPrefix(name, "v"), true
+}
+
+func verSegment(s string) uint64 {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Check if ver1 is after ver2 using the rules of the earlier versions of svc, see the compatibility note of the
+// version helpers.
+func legacyVerAfter(ver1 string, ver2 string) bool {
+	v1, v2 := SplitVer(ver1), SplitVer(ver2)
+	if len(v1) > len(v2) {
+		v2 = PadVer(v2, len(v1))
+	}
+	for i := 0; i < len(v1); i++ {
+		l, r := cast.ToInt(v1[i]), cast.ToInt(v2[i])
+		if l > r {
+			return true
+		} else if l < r {
+			return false
+		}
+	}
+	return false
+}
+
+// Compare the versions in the order the earlier versions of svc sorted the scripts, i.e., ver2 is before ver1 if
+// legacyVerAfter(ver1, ver2), the check is done in both directions as only the second version was padded.
+func legacyCompareVer(ver1 string, ver2 string) int {
+	if legacyVerAfter(ver1, ver2) {
+		return 1
+	} else if legacyVerAfter(ver2, ver1) {
+		return -1
+	}
+	return 0
+}
+
+// Check if the recorded scripts are ordered differently by the current version rules and the rules of the earlier
+// versions of svc, e.g., 'v1.08' and 'v1.1' ('v1.08' was 'v1.0').
+//
+// The scripts are sorted once by each rule and the orders are compared, the versions that are equal by one rule
+// must be equal by the other as well.
+func verRuleConflict(scripts []string) error {
+	parsed := make(map[string]Version, len(scripts))
+	for _, s := range scripts {
+		parsed[s] = ParseVer(s)
+	}
+	now := func(a, b string) int { return parsed[a].Compare(parsed[b]) }
+
+	// the equal versions are ordered by name in both
+	byNow := append([]string(nil), scripts...)
+	sort.Strings(byNow)
+	before := append([]string(nil), byNow...)
+	sort.SliceStable(byNow, func(i, j int) bool { return now(byNow[i], byNow[j]) < 0 })
+	sort.SliceStable(before, func(i, j int) bool { return legacyCompareVer(before[i], before[j]) < 0 })
+
+	conflict := func(a, b string) error {
+		return fmt.Errorf("scripts %v and %v recorded in schema_version are ordered differently by the version rules"+
+			" of svc (compared as %d, previously %d), segments are now compared as decimal integers and the shorter"+
+			" version is always padded with '0', rename the scripts and their schema_version records so that they"+
+			" are ordered the same by both rules, e.g., 'v1.08.sql' => 'v1.8.sql'", a, b, now(a, b), legacyCompareVer(a, b))
+	}
+	for i := range byNow {
+		if byNow[i] != before[i] {
+			return conflict(byNow[i], before[i])
+		}
+		if i == 0 {
+			continue
+		}
+		a, b := byNow[i-1], byNow[i]
+		if (now(a, b) == 0) != (legacyCompareVer(a, b) == 0) {
+			return conflict(a, b)
+		}
+	}
+	return nil
+}
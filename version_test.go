@@ -1,7 +1,10 @@
 package svc
 
 import (
+	"database/sql/driver"
+	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestPadVer(t *testing.T) {
@@ -49,3 +52,135 @@ func TestVerAfterEq(t *testing.T) {
 		t.Fatal("should return true")
 	}
 }
+
+func TestVerEq(t *testing.T) {
+	if !VerEq("v1", "v1.0.0.0.0") {
+		t.Fatal("should return true")
+	}
+	if !VerEq("V1.2.3.4.5.sql", "v1.2.3.4.5") {
+		t.Fatal("should return true")
+	}
+	if VerEq("v1", "v1.0.1") {
+		t.Fatal("should return false")
+	}
+	if VerEq("v1.0.0.0.1", "v1") {
+		t.Fatal("should return false")
+	}
+	if !VerEq("v1.08", "v1.8") {
+		t.Fatal("should return true")
+	}
+}
+
+func TestCompareVer(t *testing.T) {
+	cases := []struct {
+		ver1 string
+		ver2 string
+		want int
+	}{
+		{"v1.2.3.4.5", "v1.2.3.4.6", -1},
+		{"v1.2.3.4.10", "v1.2.3.4.9", 1},
+		{"v1.2.3.4", "v1.2.3.4.0.0", 0},
+		{"v1", "v1.0.0.0.1", -1},
+		{"v1.0.0.0.1", "v1", 1},
+		{"v0.0.10.sql", "V0.0.9.SQL", 1},
+		{"1.010", "v1.9", 1},
+	}
+	for _, c := range cases {
+		if got := CompareVer(c.ver1, c.ver2); got != c.want {
+			t.Fatalf("CompareVer(%v, %v) = %v, want %v", c.ver1, c.ver2, got, c.want)
+		}
+	}
+	if !VerAfter("v1.0.0.0.1", "v1") {
+		t.Fatal("should return true")
+	}
+	if VerAfterEq("v1", "v1.0.0.0.1") {
+		t.Fatal("should return false")
+	}
+}
+
+func TestFileVer(t *testing.T) {
+	cases := []struct {
+		fname string
+		want  string
+		ok    bool
+	}{
+		{"v1.2.3.sql", "v1.2.3", true},
+		{"schema/svc/V1.2.3.4.5.SQL", "v1.2.3.4.5", true},
+		{"schema/svc/1.2.sql", "v1.2", true},
+		{"baseline.sql", "", false},
+		{"v1.2-fix.sql", "", false},
+		{"v1.2", "", false},
+		{"v1..2.sql", "", false},
+	}
+	for _, c := range cases {
+		got, ok := FileVer(c.fname)
+		if got != c.want || ok != c.ok {
+			t.Fatalf("FileVer(%v) = %v, %v, want %v, %v", c.fname, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestParseVer(t *testing.T) {
+	v := ParseVer("V1.02.3.sql")
+	if len(v.Segments) != 3 || v.Segments[1] != 2 || v.String() != "v1.2.3" {
+		t.Fatalf("incorrect version, %+v", v)
+	}
+	if v.Compare(ParseVer("v1.2.3.0")) != 0 || v.Compare(ParseVer("v1.10")) != -1 || ParseVer("v2").Compare(v) != 1 {
+		t.Fatal("incorrect comparison")
+	}
+	if s := (Version{}).String(); s != "" {
+		t.Fatalf("zero version should be empty, %v", s)
+	}
+
+	files := []schemaFile{{Name: "v0.0.10.sql"}, {Name: "v0.0.2.sql"}, {Name: "v0.1.sql"}, {Name: "v0.0.9.sql"}}
+	sortSchemaFile(files)
+	for i, n := range []string{"v0.0.2.sql", "v0.0.9.sql", "v0.0.10.sql", "v0.1.sql"} {
+		if files[i].Name != n {
+			t.Fatalf("incorrect order, %+v", files)
+		}
+	}
+}
+
+func TestVerRuleConflict(t *testing.T) {
+	for _, scripts := range [][]string{
+		{"v0.0.1.sql", "v0.0.2.sql", "v0.0.10.sql", "v1.0.0.sql", "v1.1.sql"},
+		{"v1.sql", "v1.1.sql"},
+		{"v1.1.sql", "v1.sql"},
+		{"v0.0.1.sql", "v0.0.1.1.sql", "v0.0.2.sql"},
+		{"v1.sql", "v1.0.1.sql"},
+	} {
+		if err := verRuleConflict(scripts); err != nil {
+			t.Fatalf("%v should not conflict, %v", scripts, err)
+		}
+	}
+	if legacyCompareVer("v1.08", "v1.1") != -1 || legacyCompareVer("v1.010", "v1.9") != -1 || legacyCompareVer("v1", "v1.0.1") != -1 ||
+		legacyCompareVer("v1.0.1", "v1") != 1 || legacyCompareVer("v1", "v1.0") != 0 || legacyVerAfter("v1", "v1.0.1") {
+		t.Fatal("incorrect legacy comparison")
+	}
+	for _, scripts := range [][]string{
+		{"v1.08.sql", "v1.1.sql"},
+		{"v1.010.sql", "v1.9.sql"},
+		{"v1.08.sql", "v1.8.sql"},
+	} {
+		if err := verRuleConflict(scripts); err == nil || !strings.Contains(err.Error(), scripts[0]) {
+			t.Fatalf("%v should conflict, %v", scripts, err)
+		}
+	}
+}
+
+func TestMigrateVerRuleConflict(t *testing.T) {
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	f.reply(`^SELECT DISTINCT script FROM schema_version`, []string{"script"}, []driver.Value{"v1.08.sql"}, []driver.Value{"v1.1.sql"})
+	_, err := Run(f.open(t), PrintLogger{}, MigrateConfig{
+		App:     "test",
+		Fs:      fstest.MapFS{"schema/v1.1.sql": {Data: []byte("CREATE TABLE t (id INT);")}},
+		BaseDir: "schema",
+	})
+	if err == nil || !strings.Contains(err.Error(), "ordered differently") {
+		t.Fatalf("should fail with the conflict, %v", err)
+	}
+	if q := f.executed(`^CREATE TABLE t `); len(q) > 0 {
+		t.Fatalf("nothing should be executed, %+v", q)
+	}
+}
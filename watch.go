@@ -0,0 +1,111 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gorm.io/gorm"
+)
+
+const (
+	// Changes within the window are applied in one migration, editors usually write files in several steps.
+	watchDebounce = 300 * time.Millisecond
+)
+
+// Watch the schema directory for development, new scripts or new statements appended to the last script are applied
+// automatically as they are saved.
+//
+// The directory is watched on disk, MigrateConfig.Fs should be created using DirFS, if Fs is nil, DirFS(".") is used,
+// i.e., BaseDir is relative to the current working directory. Failed migrations are logged, and Watch keeps watching
+// until ctx is cancelled.
+func Watch(ctx context.Context, db *gorm.DB, log Logger, c MigrateConfig) error {
+	if log == nil {
+		return errors.New("log is nil")
+	}
+	if c.Fs == nil {
+		c.Fs = DirFS(".")
+	}
+	dfs, ok := c.Fs.(dirFS)
+	if !ok {
+		return errors.New("fs is not created by DirFS, unable to watch the directory")
+	}
+	dir := filepath.Join(dfs.root, filepath.FromSlash(c.BaseDir))
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher, %w", err)
+	}
+	defer w.Close()
+
+	// subdirectories are watched as well, e.g., views/, routines/ and nested directories in recursive mode
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return w.Add(p)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch %v, %w", dir, err)
+	}
+	log.Infof("Watching schema directory '%v'", dir)
+
+	// db is nil if MigrateConfig.DSN (or Connect) is provided
+	if db != nil {
+		db = db.WithContext(ctx)
+	}
+	migrate := func() {
+		if _, err := Run(db, log, c); err != nil {
+			log.Errorf("Migration failed, %v", err)
+		}
+	}
+	migrate()
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			log.Errorf("Watcher error, %v", err)
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Has(fsnotify.Create) {
+				if isDir(ev.Name) {
+					if err := w.Add(ev.Name); err != nil {
+						log.Errorf("Failed to watch %v, %v", ev.Name, err)
+					}
+					continue
+				}
+			}
+			if !strings.HasSuffix(strings.ToLower(ev.Name), ".sql") {
+				continue
+			}
+			if ev.Has(fsnotify.Write) || ev.Has(fsnotify.Create) || ev.Has(fsnotify.Rename) {
+				debounce = time.After(watchDebounce)
+			}
+		case <-debounce:
+			debounce = nil
+			migrate()
+		}
+	}
+}
+
+func isDir(p string) bool {
+	st, err := os.Stat(p)
+	return err == nil && st.IsDir()
+}
@@ -0,0 +1,99 @@
+package svc
+
+import (
+	"context"
+	"database/sql/driver"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema")
+	if err := os.MkdirAll(schema, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(schema, "v0.0.1.sql"), []byte("CREATE TABLE a (id INT);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+	db := f.open(t)
+
+	if err := Watch(context.Background(), db, PrintLogger{}, MigrateConfig{Fs: fstest.MapFS{}}); err == nil {
+		t.Fatal("should fail, fs is not created by DirFS")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, db, PrintLogger{}, MigrateConfig{App: "test", Fs: DirFS(dir), BaseDir: "schema"})
+	}()
+	waitFor := func(pattern string) {
+		deadline := time.Now().Add(5 * time.Second)
+		for len(f.executed(pattern)) < 1 {
+			if time.Now().After(deadline) {
+				t.Fatalf("'%v' should be executed", pattern)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitFor(`^CREATE TABLE a `)
+
+	if err := os.WriteFile(filepath.Join(schema, "v0.0.2.sql"), []byte("CREATE TABLE b (id INT);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(`^CREATE TABLE b `)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch should return once ctx is cancelled")
+	}
+}
+
+func TestWatchConnect(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema")
+	if err := os.MkdirAll(schema, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(schema, "v0.0.1.sql"), []byte("CREATE TABLE a (id INT);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeDB{}
+	f.reply(`^SELECT VERSION\(\)$`, []string{"version"}, []driver.Value{"8.0.36"})
+
+	// db is nil, the connection is opened by Connect
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Watch(ctx, nil, PrintLogger{}, MigrateConfig{App: "test", Fs: DirFS(dir), BaseDir: "schema",
+			Connect: func() (*gorm.DB, error) { return f.open(t), nil }})
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(f.executed(`^CREATE TABLE a `)) < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("script should be executed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Watch should return once ctx is cancelled")
+	}
+}